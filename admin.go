@@ -0,0 +1,752 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/michgboxy2/carzone/config"
+	loginHandler "github.com/michgboxy2/carzone/handler/login"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/money"
+	rateService "github.com/michgboxy2/carzone/service/rate"
+	"github.com/michgboxy2/carzone/store"
+	rateStore "github.com/michgboxy2/carzone/store/rate"
+)
+
+// exportFile is the document written by `export` and read by `import`.
+type exportFile struct {
+	ExportedAt time.Time       `json:"exported_at"`
+	Engines    []models.Engine `json:"engines"`
+	Cars       []models.Car    `json:"cars"`
+
+	// References holds the values of each reference kind, which the cars'
+	// specs refer to. Older exports have none.
+	References map[string][]models.ReferenceValue `json:"references,omitempty"`
+
+	// Brands is the brand and model catalogue, each brand with its models.
+	// Cars of older exports are filed under their brands once imported.
+	Brands []models.Brand `json:"brands,omitempty"`
+
+	// FuelTypes is the fuel type registry. Older exports have none, and
+	// import keeps the registry's defaults.
+	FuelTypes []models.FuelType `json:"fuel_types,omitempty"`
+
+	// Prices holds each car's price history, oldest first. Cars of older
+	// exports start theirs at their current price once imported.
+	Prices map[uuid.UUID][]models.PricePoint `json:"prices,omitempty"`
+}
+
+func runInitSchema(args []string) error {
+	fs := flag.NewFlagSet("init-schema", flag.ExitOnError)
+
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := executeSchema(db.DB, store.Schema); err != nil {
+		return err
+	}
+
+	log.Println("schema is up to date")
+	return nil
+}
+
+func runSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	force := fs.Bool("force", false, "seed even if the database already has cars")
+
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := executeSchema(db.DB, store.Schema); err != nil {
+		return err
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM cars").Scan(&count); err != nil {
+		return err
+	}
+
+	if count > 0 && !*force {
+		log.Printf("database already has %d cars, skipping seed (use -force to seed anyway)", count)
+		return nil
+	}
+
+	if err := executeSchema(db.DB, store.Seed+"\n"+store.FoldBrands+"\n"+store.StartPrices); err != nil {
+		return err
+	}
+
+	log.Println("sample data inserted")
+	return nil
+}
+
+func runCheckDB(args []string) error {
+	fs := flag.NewFlagSet("check-db", flag.ExitOnError)
+
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+	fmt.Printf("ping: ok (%s)\n", time.Since(start).Round(time.Millisecond))
+
+	var missing []string
+	for _, table := range []string{"engines", "cars"} {
+		var count int
+		err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count)
+		if err != nil {
+			fmt.Printf("table %s: missing (%v)\n", table, err)
+			missing = append(missing, table)
+			continue
+		}
+		fmt.Printf("table %s: ok (%d rows)\n", table, count)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing tables %v, run init-schema", missing)
+	}
+
+	return nil
+}
+
+func runToken(args []string) error {
+	if len(args) == 0 || args[0] != "issue" {
+		return errors.New("usage: token issue -user <name> [-role admin] [-ttl 24h]")
+	}
+
+	fs := flag.NewFlagSet("token issue", flag.ExitOnError)
+	user := fs.String("user", "admin", "user name to put in the token")
+	role := fs.String("role", models.RoleAdmin, "role to put in the token: "+strings.Join(models.Roles, ", "))
+	ttl := fs.Duration("ttl", 0, "how long the token is valid (defaults to auth.token_ttl)")
+
+	cfg, err := config.Load(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	if *ttl == 0 {
+		*ttl = cfg.Auth.TokenTTL
+	}
+
+	if *ttl < 0 {
+		return errors.New("ttl must be positive")
+	}
+
+	if err := models.ValidateRole(*role); err != nil {
+		return err
+	}
+
+	token, err := loginHandler.NewLoginHandler(cfg.Auth).GenerateToken(*user, *role, *ttl)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(token)
+	return nil
+}
+
+// runRates loads exchange rates from a JSON file such as
+// {"base": "USD", "rates": {"EUR": "0.92"}}, replacing the current ones.
+func runRates(args []string) error {
+	if len(args) == 0 || args[0] != "import" {
+		return errors.New("usage: rates import [-f rates.json]")
+	}
+
+	fs := flag.NewFlagSet("rates import", flag.ExitOnError)
+	in := fs.String("f", "-", "rates file to load ('-' for stdin)")
+
+	cfg, err := config.Load(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var rates money.Rates
+	if err := json.NewDecoder(r).Decode(&rates); err != nil {
+		return fmt.Errorf("reading rates file: %w", err)
+	}
+
+	db, err := openDB(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := executeSchema(db.DB, store.Schema); err != nil {
+		return err
+	}
+
+	loaded, err := rateService.NewExchangeRateService(rateStore.New(db), cfg.Currency.Base).ReplaceRates(context.Background(), &rates)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("loaded %d exchange rates against %s", len(loaded.Rates), loaded.Base)
+	return nil
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("o", "-", "output file ('-' for stdout)")
+
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	data := exportFile{
+		ExportedAt: time.Now().UTC(),
+		Engines:    []models.Engine{},
+		Cars:       []models.Car{},
+		References: map[string][]models.ReferenceValue{},
+	}
+
+	if data.Brands, err = exportBrands(ctx, db.DB); err != nil {
+		return fmt.Errorf("brands: %w", err)
+	}
+
+	if data.FuelTypes, err = exportFuelTypes(ctx, db.DB); err != nil {
+		return fmt.Errorf("fuel types: %w", err)
+	}
+
+	for _, kind := range models.ReferenceKinds {
+		values, err := exportReferences(ctx, db.DB, kind)
+		if err != nil {
+			return fmt.Errorf("%s: %w", kind, err)
+		}
+		data.References[kind] = values
+	}
+
+	engineRows, err := db.QueryContext(ctx,
+		"SELECT "+store.EngineColumns("")+" FROM engines ORDER BY created_at, engine_id")
+	if err != nil {
+		return err
+	}
+	defer engineRows.Close()
+
+	for engineRows.Next() {
+		var row store.EngineRow
+		if err := engineRows.Scan(row.Dest()...); err != nil {
+			return err
+		}
+		data.Engines = append(data.Engines, row.Engine())
+	}
+
+	if err := engineRows.Err(); err != nil {
+		return err
+	}
+
+	carRows, err := db.QueryContext(ctx, `
+		SELECT id, name, year, brand, COALESCE(model, ''), fuel_type, engine_id, price, currency, created_at, updated_at, `+store.CarSpecColumns("")+`,
+			`+store.CarHistoryColumns("")+`
+		FROM cars ORDER BY created_at, id`)
+	if err != nil {
+		return err
+	}
+	defer carRows.Close()
+
+	for carRows.Next() {
+		var car models.Car
+		var price, currency string
+		var specs store.CarSpecsRow
+		var history store.CarHistoryRow
+		if err := carRows.Scan(append(append([]interface{}{&car.ID, &car.Name, &car.Year, &car.Brand, &car.Model, &car.FuelType,
+			&car.Engine.EngineID, &price, &currency, &car.CreatedAt, &car.UpdatedAt}, specs.Dest()...), history.Dest()...)...); err != nil {
+			return err
+		}
+		car.CarSpecs = specs.Specs()
+		car.CarHistory = history.History()
+
+		if car.Price, err = money.Parse(price, strings.TrimSpace(currency)); err != nil {
+			return fmt.Errorf("car %s: %w", car.ID, err)
+		}
+		data.Cars = append(data.Cars, car)
+	}
+
+	if err := carRows.Err(); err != nil {
+		return err
+	}
+
+	if data.Prices, err = exportPrices(ctx, db.DB); err != nil {
+		return fmt.Errorf("prices: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		return err
+	}
+
+	log.Printf("exported %d engines and %d cars", len(data.Engines), len(data.Cars))
+	return nil
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	in := fs.String("f", "-", "export file to load ('-' for stdin)")
+
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var data exportFile
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return fmt.Errorf("reading export file: %w", err)
+	}
+
+	db, err := openDB(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := executeSchema(db.DB, store.Schema); err != nil {
+		return err
+	}
+
+	if err := importData(context.Background(), db.DB, &data); err != nil {
+		return err
+	}
+
+	log.Printf("imported %d engines and %d cars", len(data.Engines), len(data.Cars))
+	return nil
+}
+
+func exportReferences(ctx context.Context, db *sql.DB, kind string) ([]models.ReferenceValue, error) {
+	rows, err := db.QueryContext(ctx, "SELECT code, name, created_at, updated_at FROM "+kind+" ORDER BY code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []models.ReferenceValue{}
+
+	for rows.Next() {
+		var value models.ReferenceValue
+		if err := rows.Scan(&value.Code, &value.Name, &value.CreatedAt, &value.UpdatedAt); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
+
+func exportFuelTypes(ctx context.Context, db *sql.DB) ([]models.FuelType, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT code, name, synonyms, powertrains, deprecated, emission_factor, emission_unit, created_at, updated_at
+		FROM fuel_types ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fuelTypes := []models.FuelType{}
+
+	for rows.Next() {
+		var f models.FuelType
+		if err := rows.Scan(&f.Code, &f.Name, pq.Array(&f.Synonyms), pq.Array(&f.Powertrains),
+			&f.Deprecated, &f.EmissionFactor, &f.EmissionUnit, &f.CreatedAt, &f.UpdatedAt); err != nil {
+			return nil, err
+		}
+		fuelTypes = append(fuelTypes, f)
+	}
+
+	return fuelTypes, rows.Err()
+}
+
+// exportPrices returns the price history of every car.
+func exportPrices(ctx context.Context, db *sql.DB) (map[uuid.UUID][]models.PricePoint, error) {
+	rows, err := db.QueryContext(ctx, "SELECT car_id, price, currency, changed_at FROM car_prices ORDER BY car_id, changed_at, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := map[uuid.UUID][]models.PricePoint{}
+
+	for rows.Next() {
+		var carID uuid.UUID
+		var amount, currency string
+		var point models.PricePoint
+		if err := rows.Scan(&carID, &amount, &currency, &point.ChangedAt); err != nil {
+			return nil, err
+		}
+
+		if point.Price, err = money.Parse(amount, strings.TrimSpace(currency)); err != nil {
+			return nil, fmt.Errorf("car %s: %w", carID, err)
+		}
+		prices[carID] = append(prices[carID], point)
+	}
+
+	return prices, rows.Err()
+}
+
+// exportBrands returns the brand catalogue, each brand with its models.
+func exportBrands(ctx context.Context, db *sql.DB) ([]models.Brand, error) {
+	brands := []models.Brand{}
+	index := make(map[uuid.UUID]int)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT b.id, b.name, ARRAY(SELECT alias FROM brand_aliases a WHERE a.brand_id = b.id ORDER BY alias),
+			b.created_at, b.updated_at
+		FROM brands b ORDER BY b.created_at, b.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var brand models.Brand
+		if err := rows.Scan(&brand.ID, &brand.Name, pq.Array(&brand.Aliases), &brand.CreatedAt, &brand.UpdatedAt); err != nil {
+			return nil, err
+		}
+		index[brand.ID] = len(brands)
+		brands = append(brands, brand)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	modelRows, err := db.QueryContext(ctx, `
+		SELECT m.id, m.brand_id, m.name, ARRAY(SELECT alias FROM model_aliases a WHERE a.model_id = m.id ORDER BY alias),
+			m.created_at, m.updated_at
+		FROM models m ORDER BY m.created_at, m.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer modelRows.Close()
+
+	for modelRows.Next() {
+		var model models.CarModel
+		if err := modelRows.Scan(&model.ID, &model.BrandID, &model.Name, pq.Array(&model.Aliases), &model.CreatedAt, &model.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if i, ok := index[model.BrandID]; ok {
+			brands[i].Models = append(brands[i].Models, model)
+		}
+	}
+
+	return brands, modelRows.Err()
+}
+
+// importBrand upserts a brand and its models, replacing their aliases.
+func importBrand(ctx context.Context, tx *sql.Tx, brand models.Brand) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO brands (id, name, created_at, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE
+		SET name = EXCLUDED.name,
+			updated_at = EXCLUDED.updated_at`,
+		brand.ID, brand.Name, brand.CreatedAt, brand.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM brand_aliases WHERE brand_id = $1", brand.ID); err != nil {
+		return err
+	}
+
+	for _, alias := range brand.Aliases {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO brand_aliases (brand_id, alias) VALUES ($1, $2)", brand.ID, alias); err != nil {
+			return fmt.Errorf("alias %s: %w", alias, err)
+		}
+	}
+
+	for _, model := range brand.Models {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO models (id, brand_id, name, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (id) DO UPDATE
+			SET name = EXCLUDED.name,
+				updated_at = EXCLUDED.updated_at`,
+			model.ID, brand.ID, model.Name, model.CreatedAt, model.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("model %s: %w", model.Name, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM model_aliases WHERE model_id = $1", model.ID); err != nil {
+			return err
+		}
+
+		for _, alias := range model.Aliases {
+			_, err := tx.ExecContext(ctx,
+				"INSERT INTO model_aliases (model_id, brand_id, alias) VALUES ($1, $2, $3)", model.ID, brand.ID, alias)
+			if err != nil {
+				return fmt.Errorf("model %s alias %s: %w", model.Name, alias, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkImportedCar validates car as the API validates a new one and returns
+// the code of its fuel type, which must suit the powertrain of its engine.
+func checkImportedCar(ctx context.Context, tx *sql.Tx, car models.Car) (string, error) {
+	err := models.ValidateRequest(models.CarRequest{
+		Name:       car.Name,
+		Year:       car.Year,
+		Brand:      car.Brand,
+		Model:      car.Model,
+		FuelType:   car.FuelType,
+		Engine:     car.Engine,
+		Price:      car.Price,
+		CarSpecs:   car.CarSpecs,
+		CarHistory: car.CarHistory,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if car.Engine.EngineID == uuid.Nil {
+		return "", errors.New("engine.engine_id is required")
+	}
+
+	var fuelType models.FuelType
+	err = tx.QueryRowContext(ctx, `
+		SELECT code, powertrains FROM fuel_types
+		WHERE lower(code) = lower($1) OR lower($1) IN (SELECT lower(s) FROM unnest(synonyms) s)`,
+		car.FuelType).Scan(&fuelType.Code, pq.Array(&fuelType.Powertrains))
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%w: %s", models.ErrFuelTypeNotFound, car.FuelType)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var powertrain string
+	err = tx.QueryRowContext(ctx, "SELECT powertrain FROM engines WHERE engine_id = $1", car.Engine.EngineID).Scan(&powertrain)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%w: %s", models.ErrEngineNotFound, car.Engine.EngineID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := models.ValidatePowertrainFuelType(fuelType, powertrain); err != nil {
+		return "", err
+	}
+
+	return fuelType.Code, nil
+}
+
+// importData upserts everything in a single transaction, reference values,
+// fuel types, brands and engines first so the cars can reference them.
+// Engines and cars are checked as the API would check them, so a bad
+// export fails the import rather than leaving rows the API would reject.
+// Rows are matched on their IDs, which makes re-running an import safe.
+func importData(ctx context.Context, db *sql.DB, data *exportFile) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	for kind, values := range data.References {
+		if err = models.ValidateReferenceKind(kind); err != nil {
+			return err
+		}
+
+		for _, value := range values {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO `+kind+` (code, name, created_at, updated_at)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (code) DO UPDATE
+				SET name = EXCLUDED.name,
+					updated_at = EXCLUDED.updated_at`,
+				value.Code, value.Name, value.CreatedAt, value.UpdatedAt)
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", kind, value.Code, err)
+			}
+		}
+	}
+
+	for _, f := range data.FuelTypes {
+		if f.Synonyms == nil {
+			f.Synonyms = []string{}
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO fuel_types (code, name, synonyms, powertrains, deprecated, emission_factor, emission_unit, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (code) DO UPDATE
+			SET (name, synonyms, powertrains, deprecated, emission_factor, emission_unit, updated_at) =
+				(EXCLUDED.name, EXCLUDED.synonyms, EXCLUDED.powertrains, EXCLUDED.deprecated,
+				EXCLUDED.emission_factor, EXCLUDED.emission_unit, EXCLUDED.updated_at)`,
+			f.Code, f.Name, pq.Array(f.Synonyms), pq.Array(f.Powertrains), f.Deprecated, f.EmissionFactor, f.EmissionUnit, f.CreatedAt, f.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("fuel type %s: %w", f.Code, err)
+		}
+	}
+
+	for _, brand := range data.Brands {
+		if err = importBrand(ctx, tx, brand); err != nil {
+			return fmt.Errorf("brand %s: %w", brand.Name, err)
+		}
+	}
+
+	for _, engine := range data.Engines {
+		engineReq := engine.Request()
+		if err = models.ValidateEngineRequest(engineReq); err != nil {
+			return fmt.Errorf("engine %s: %w", engine.EngineID, err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO engines (engine_id, `+store.EngineSpecColumns()+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			ON CONFLICT (engine_id) DO UPDATE
+			SET (`+store.EngineSpecColumns()+`, updated_at) =
+				($2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, CURRENT_TIMESTAMP)`,
+			append([]interface{}{engine.EngineID}, store.EngineSpecValues(&engineReq)...)...)
+		if err != nil {
+			return fmt.Errorf("engine %s: %w", engine.EngineID, err)
+		}
+	}
+
+	for _, car := range data.Cars {
+		if car.FuelType, err = checkImportedCar(ctx, tx, car); err != nil {
+			return fmt.Errorf("car %s: %w", car.ID, err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO cars (id, name, year, brand, fuel_type, engine_id, price, currency, created_at, updated_at, `+store.CarSpecColumns("")+`,
+				`+store.CarCatalogColumns+`, `+store.CarHistoryColumns("")+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
+				`+store.CarCatalogValues("$4", "$18")+`, $19, $20, $21, $22, $23, $24)
+			ON CONFLICT (id) DO UPDATE
+			SET name = EXCLUDED.name,
+				year = EXCLUDED.year,
+				brand = EXCLUDED.brand,
+				fuel_type = EXCLUDED.fuel_type,
+				engine_id = EXCLUDED.engine_id,
+				price = EXCLUDED.price,
+				currency = EXCLUDED.currency,
+				updated_at = EXCLUDED.updated_at,
+				(`+store.CarSpecColumns("")+`) = ($11, $12, $13, $14, $15, $16, $17),
+				(`+store.CarCatalogColumns+`) = (EXCLUDED.model, EXCLUDED.brand_id, EXCLUDED.model_id),
+				(`+store.CarHistoryColumns("")+`) = ($19, $20, $21, $22, $23, $24)`,
+			append(append(append([]interface{}{car.ID, car.Name, car.Year, car.Brand, car.FuelType, car.Engine.EngineID,
+				car.Price.Amount(), car.Price.Currency, car.CreatedAt, car.UpdatedAt}, store.CarSpecValues(car.CarSpecs)...),
+				store.CarModelValue(car.Model)), store.CarHistoryValues(car.CarHistory)...)...)
+		if err != nil {
+			return fmt.Errorf("car %s: %w", car.ID, err)
+		}
+	}
+
+	// Cars whose brand is not in the catalogue by that exact name, such as
+	// those of older exports, are filed under the brand it resolves to.
+	if _, err = tx.ExecContext(ctx, store.FoldBrands); err != nil {
+		return fmt.Errorf("folding brands: %w", err)
+	}
+
+	// An exported history replaces the car's own.
+	for carID, points := range data.Prices {
+		if _, err = tx.ExecContext(ctx, "DELETE FROM car_prices WHERE car_id = $1", carID); err != nil {
+			return fmt.Errorf("prices of car %s: %w", carID, err)
+		}
+
+		for _, point := range points {
+			if err = point.Price.Validate(); err != nil {
+				return fmt.Errorf("prices of car %s: %w", carID, err)
+			}
+
+			_, err = tx.ExecContext(ctx, "INSERT INTO car_prices (car_id, price, currency, changed_at) VALUES ($1, $2, $3, $4)",
+				carID, point.Price.Amount(), point.Price.Currency, point.ChangedAt)
+			if err != nil {
+				return fmt.Errorf("prices of car %s: %w", carID, err)
+			}
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, store.StartPrices); err != nil {
+		return fmt.Errorf("starting price histories: %w", err)
+	}
+
+	return nil
+}
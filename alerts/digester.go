@@ -0,0 +1,116 @@
+package alerts
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/m3db/prometheus_client_golang/prometheus"
+	"github.com/michgboxy2/carzone/config"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+)
+
+// digestLease is how long a claimed digest is hidden from other digesters;
+// it comfortably outlasts one send.
+const digestLease = 5 * time.Minute
+
+var digestCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "search_alert_digests_total",
+		Help: "Total number of saved search digests sent by result (sent or failed)",
+	},
+	[]string{"result"},
+)
+
+func init() {
+	prometheus.MustRegister(digestCounter)
+}
+
+// Digester sends the alerts waiting on saved searches, one digest per
+// search, at most once per the search's frequency. A digest that fails is
+// tried again after the retry interval. Several instances can run at once,
+// each claiming different searches.
+type Digester struct {
+	store    store.SearchStoreInterface
+	notifier Notifier
+	cfg      config.Alerts
+}
+
+func NewDigester(store store.SearchStoreInterface, notifier Notifier, cfg config.Alerts) *Digester {
+	return &Digester{store: store, notifier: notifier, cfg: cfg}
+}
+
+func (d *Digester) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		claimed, err := d.SendDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("search alert digester: %v", err)
+		}
+
+		if err == nil && claimed == d.cfg.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue claims the digests that are due, sends them concurrently and
+// records the outcomes. It returns how many it claimed.
+func (d *Digester) SendDue(ctx context.Context) (int, error) {
+	due, err := d.store.ClaimDueDigests(ctx, d.cfg.BatchSize, digestLease)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, digest := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.send(ctx, digest)
+		}()
+	}
+	wg.Wait()
+
+	return len(due), nil
+}
+
+func (d *Digester) send(ctx context.Context, digest models.SearchDigest) {
+	search := digest.Search
+
+	err := d.notifier.Notify(ctx, digest)
+	if err == nil {
+		digestCounter.WithLabelValues("sent").Inc()
+
+		last := digest.Alerts[len(digest.Alerts)-1].ID
+		next := time.Now().Add(models.DigestInterval(search.Frequency))
+
+		if err := d.store.MarkDigestSent(ctx, search.ID, last, next); err != nil {
+			log.Printf("saved search %s: recording digest: %v", search.ID, err)
+		}
+		return
+	}
+
+	// The worker is stopping; the lease expires and the digest is sent
+	// later.
+	if ctx.Err() != nil {
+		return
+	}
+
+	digestCounter.WithLabelValues("failed").Inc()
+	log.Printf("saved search %s: sending digest of %d alerts: %v", search.ID, len(digest.Alerts), err)
+
+	if err := d.store.PostponeDigest(ctx, search.ID, time.Now().Add(d.cfg.RetryInterval)); err != nil {
+		log.Printf("saved search %s: postponing digest: %v", search.ID, err)
+	}
+}
@@ -0,0 +1,121 @@
+// Package alerts tells users about cars that match their saved searches.
+// The Matcher raises alerts as cars are created and updated, and the
+// Digester sends them through a Notifier at each search's frequency.
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/money"
+	"github.com/michgboxy2/carzone/service"
+	"github.com/michgboxy2/carzone/store"
+)
+
+// Matcher is an events.Sink that checks every created and updated car
+// against the saved searches. A car that starts to match a search raises a
+// new_match alert, and one that matched and got cheaper a price_drop alert.
+// Running inside the relay's transaction, the alerts are raised exactly
+// when the event is marked published.
+type Matcher struct {
+	store store.SearchStoreInterface
+	rates service.ExchangeRateServiceInterface
+}
+
+func NewMatcher(store store.SearchStoreInterface, rates service.ExchangeRateServiceInterface) *Matcher {
+	return &Matcher{store: store, rates: rates}
+}
+
+func (m *Matcher) Publish(ctx context.Context, event models.Event) error {
+	if event.Type != models.EventCarCreated && event.Type != models.EventCarUpdated {
+		return nil
+	}
+
+	var car models.Car
+	if err := json.Unmarshal(event.Payload, &car); err != nil {
+		// Retrying would not make the payload readable.
+		log.Printf("search matcher: event %s: %v", event.ID, err)
+		return nil
+	}
+
+	return m.Match(ctx, car)
+}
+
+func (m *Matcher) Close() error {
+	return nil
+}
+
+// Match checks car against the saved searches and raises its alerts.
+func (m *Matcher) Match(ctx context.Context, car models.Car) error {
+	// Searches the car has left by changing brand or fuel type are not
+	// looked at below, so their matches are dropped here.
+	if err := m.store.DeleteStaleMatches(ctx, car); err != nil {
+		return err
+	}
+
+	searches, err := m.store.MatchingSearches(ctx, car)
+	if err != nil {
+		return err
+	}
+
+	var rates *money.Rates
+
+	for _, search := range searches {
+		price := car.Price
+
+		// Cars listed in another currency are compared at today's rate.
+		if currency := search.Currency(); currency != "" && currency != price.Currency {
+			if rates == nil {
+				if rates, err = m.rates.GetRates(ctx); err != nil {
+					return err
+				}
+			}
+
+			if price, err = rates.Convert(car.Price, currency); err != nil {
+				log.Printf("search matcher: saved search %s skipped for car %s: %v", search.ID, car.ID, err)
+				continue
+			}
+		}
+
+		last, err := m.store.GetMatch(ctx, search.ID, car.ID)
+		if err != nil {
+			return err
+		}
+
+		if !search.MatchesYear(car.Year) || !search.MatchesPrice(price) {
+			if last != nil {
+				// Should the car match again, it is news again.
+				if err := m.store.DeleteMatch(ctx, search.ID, car.ID); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		alert := models.SearchAlert{SearchID: search.ID, Car: car}
+
+		switch {
+		case last == nil:
+			alert.Kind = models.AlertNewMatch
+		case last.Currency == car.Price.Currency && car.Price.Minor < last.Minor:
+			alert.Kind = models.AlertPriceDrop
+			alert.OldPrice = last
+		case *last == car.Price:
+			continue
+		}
+
+		if alert.Kind != "" {
+			if err := m.store.AddAlert(ctx, alert); err != nil {
+				return err
+			}
+		}
+
+		if err := m.store.SaveMatch(ctx, search.ID, car.ID, car.Price); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/michgboxy2/carzone/config"
+	"github.com/michgboxy2/carzone/models"
+)
+
+// Notifier sends a saved search's digest to its owner. Notify must only
+// return nil once the digest has been accepted; it is sent again after the
+// retry interval otherwise.
+type Notifier interface {
+	Notify(ctx context.Context, digest models.SearchDigest) error
+}
+
+// NewNotifier builds the notifier selected by cfg.Notifier.
+func NewNotifier(cfg config.Alerts) (Notifier, error) {
+	switch cfg.Notifier {
+	case "log":
+		return LogNotifier{}, nil
+	case "smtp":
+		return NewSMTPNotifier(cfg.SMTPAddr, cfg.SMTPFrom, cfg.SMTPUsername, cfg.SMTPPassword.Value()), nil
+	case "webhook":
+		return NewWebhookNotifier(cfg.WebhookURL, &http.Client{Timeout: cfg.WebhookTimeout}), nil
+	default:
+		return nil, fmt.Errorf("unknown alert notifier %q", cfg.Notifier)
+	}
+}
+
+// LogNotifier writes digests to the service log. It is the default and is
+// useful in development.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, digest models.SearchDigest) error {
+	log.Printf("saved search %s of %s: %s", digest.Search.ID, digest.Search.Owner, subject(digest))
+	for _, alert := range digest.Alerts {
+		log.Printf("  %s", describe(alert))
+	}
+	return nil
+}
+
+// SMTPNotifier emails digests to the address on the search. It speaks plain
+// SMTP, which suits a local stand-in such as MailHog; credentials are only
+// sent when a username is configured.
+type SMTPNotifier struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPNotifier(addr, from, username, password string) *SMTPNotifier {
+	n := &SMTPNotifier{addr: addr, from: from}
+
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		n.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return n
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, digest models.SearchDigest) error {
+	to := digest.Search.Email
+	if to == "" {
+		// Searches saved while another notifier was configured need not
+		// have an address; there is nowhere to send theirs.
+		log.Printf("saved search %s has no email; dropping %d alerts", digest.Search.ID, len(digest.Alerts))
+		return nil
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject(digest))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+
+	for _, alert := range digest.Alerts {
+		msg.WriteString(describe(alert) + "\r\n")
+	}
+
+	fmt.Fprintf(&msg, "\r\nYou are receiving this because of your %s saved search %q.\r\n", digest.Search.Frequency, digest.Search.Name)
+
+	return smtp.SendMail(n.addr, n.auth, n.from, []string{to}, msg.Bytes())
+}
+
+// WebhookNotifier POSTs each digest as JSON to a fixed URL, for a service
+// that delivers them. Any 2xx response counts as sent.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string, client *http.Client) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: client}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, digest models.SearchDigest) error {
+	body, err := json.Marshal(digest)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "carzone-alerts")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("alert webhook returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+func subject(digest models.SearchDigest) string {
+	var matches, drops int
+	for _, alert := range digest.Alerts {
+		if alert.Kind == models.AlertPriceDrop {
+			drops++
+		} else {
+			matches++
+		}
+	}
+
+	var parts []string
+	if matches > 0 {
+		parts = append(parts, plural(matches, "new match", "new matches"))
+	}
+	if drops > 0 {
+		parts = append(parts, plural(drops, "price drop", "price drops"))
+	}
+
+	return fmt.Sprintf("%s for %q", strings.Join(parts, " and "), digest.Search.Name)
+}
+
+func describe(alert models.SearchAlert) string {
+	car := alert.Car
+	name := strings.Join(strings.Fields(fmt.Sprintf("%s %s %s", car.Year, car.Brand, car.Model)), " ")
+	if car.Name != "" {
+		name += " (" + car.Name + ")"
+	}
+
+	if alert.Kind == models.AlertPriceDrop && alert.OldPrice != nil {
+		return fmt.Sprintf("Price drop: %s, now %s, was %s [car %s]", name, car.Price, alert.OldPrice, car.ID)
+	}
+
+	return fmt.Sprintf("New match: %s at %s [car %s]", name, car.Price, car.ID)
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
@@ -0,0 +1,124 @@
+// Package blob stores uploaded files such as car images. Keys are
+// slash-separated paths chosen by the caller, e.g. "cars/<id>/<image>/original".
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/michgboxy2/carzone/config"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs by key. Put replaces any existing blob atomically, and
+// Delete of a missing key is not an error.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewStore builds the store selected by cfg.Backend.
+func NewStore(cfg config.Blob) (Store, error) {
+	switch cfg.Backend {
+	case "filesystem":
+		return NewFileSystem(cfg.Dir)
+	default:
+		return nil, fmt.Errorf("unknown blob backend %q", cfg.Backend)
+	}
+}
+
+// FileSystem keeps each blob in a file under a root directory.
+type FileSystem struct {
+	root string
+}
+
+func NewFileSystem(root string) (*FileSystem, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("blob directory: %w", err)
+	}
+	return &FileSystem{root: root}, nil
+}
+
+// Put writes to a temporary file next to the destination and renames it
+// into place, so readers never see a partial blob.
+func (fs *FileSystem) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := fs.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Open returns the blob as an *os.File, which callers may use to serve
+// range requests.
+func (fs *FileSystem) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := fs.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (fs *FileSystem) Delete(ctx context.Context, key string) error {
+	path, err := fs.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps a key to a file under root, rejecting keys that would escape it.
+func (fs *FileSystem) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsRune(segment, '\\') {
+			return "", fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+
+	return filepath.Join(fs.root, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,69 @@
+package blob
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrURLExpired   = errors.New("url has expired")
+	ErrURLSignature = errors.New("url signature is invalid")
+)
+
+// URLSigner issues links that anyone may follow until they expire, so files
+// can be embedded in pages without a bearer token. The signature covers the
+// path and the expiry time.
+type URLSigner struct {
+	key []byte
+	ttl time.Duration
+}
+
+func NewURLSigner(key string, ttl time.Duration) *URLSigner {
+	return &URLSigner{key: []byte(key), ttl: ttl}
+}
+
+// Sign returns path with expires and sig query parameters appended.
+func (s *URLSigner) Sign(path string, now time.Time) string {
+	expires := strconv.FormatInt(now.Add(s.ttl).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("sig", s.signature(path, expires))
+
+	return path + "?" + query.Encode()
+}
+
+// Verify checks the expires and sig parameters of a request for path and
+// returns when the link expires.
+func (s *URLSigner) Verify(path string, query url.Values, now time.Time) (time.Time, error) {
+	expires := query.Get("expires")
+
+	sec, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrURLSignature
+	}
+
+	if !hmac.Equal([]byte(query.Get("sig")), []byte(s.signature(path, expires))) {
+		return time.Time{}, ErrURLSignature
+	}
+
+	expiry := time.Unix(sec, 0)
+	if !now.Before(expiry) {
+		return time.Time{}, ErrURLExpired
+	}
+
+	return expiry, nil
+}
+
+func (s *URLSigner) signature(path, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(path))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
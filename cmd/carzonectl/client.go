@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/michgboxy2/carzone/models"
+)
+
+// APIError is returned for any non-2xx response from the carzone API.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server returned %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
+
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+func NewClient(baseURL, token string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *Client) Login(ctx context.Context, creds models.Credentials) (string, error) {
+	var resp struct {
+		Token string `json:"token"`
+	}
+
+	if err := c.do(ctx, http.MethodPost, "/login", creds, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.Token, nil
+}
+
+func (c *Client) GetCar(ctx context.Context, id string) (*models.Car, error) {
+	var car models.Car
+
+	if err := c.do(ctx, http.MethodGet, "/car/"+url.PathEscape(id), nil, &car); err != nil {
+		return nil, err
+	}
+
+	return &car, nil
+}
+
+func (c *Client) ListCarsByBrand(ctx context.Context, brand string, query url.Values) ([]models.Car, error) {
+	var cars []models.Car
+
+	path := "/cars/" + url.PathEscape(brand)
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	if err := c.do(ctx, http.MethodGet, path, nil, &cars); err != nil {
+		return nil, err
+	}
+
+	return cars, nil
+}
+
+func (c *Client) GetPriceHistory(ctx context.Context, id string) (*models.PriceHistory, error) {
+	var history models.PriceHistory
+
+	if err := c.do(ctx, http.MethodGet, "/cars/"+url.PathEscape(id)+"/prices", nil, &history); err != nil {
+		return nil, err
+	}
+
+	return &history, nil
+}
+
+func (c *Client) CreateCar(ctx context.Context, carReq *models.CarRequest) (*models.Car, error) {
+	var car models.Car
+
+	if err := c.do(ctx, http.MethodPost, "/cars", carReq, &car); err != nil {
+		return nil, err
+	}
+
+	return &car, nil
+}
+
+func (c *Client) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (*models.Car, error) {
+	var car models.Car
+
+	if err := c.do(ctx, http.MethodPut, "/cars/"+url.PathEscape(id), carReq, &car); err != nil {
+		return nil, err
+	}
+
+	return &car, nil
+}
+
+func (c *Client) DeleteCar(ctx context.Context, id string) (*models.Car, error) {
+	var car models.Car
+
+	if err := c.do(ctx, http.MethodDelete, "/cars/"+url.PathEscape(id), nil, &car); err != nil {
+		return nil, err
+	}
+
+	return &car, nil
+}
+
+func (c *Client) GetEngine(ctx context.Context, id string) (*models.Engine, error) {
+	var engine models.Engine
+
+	if err := c.do(ctx, http.MethodGet, "/engine/"+url.PathEscape(id), nil, &engine); err != nil {
+		return nil, err
+	}
+
+	return &engine, nil
+}
+
+func (c *Client) ListEngines(ctx context.Context, query url.Values) (*models.EnginePage, error) {
+	var page models.EnginePage
+
+	path := "/engines"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	if err := c.do(ctx, http.MethodGet, path, nil, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+func (c *Client) ListEngineCars(ctx context.Context, id string) ([]models.Car, error) {
+	var cars []models.Car
+
+	if err := c.do(ctx, http.MethodGet, "/engine/"+url.PathEscape(id)+"/cars", nil, &cars); err != nil {
+		return nil, err
+	}
+
+	return cars, nil
+}
+
+func (c *Client) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (*models.Engine, error) {
+	var engine models.Engine
+
+	if err := c.do(ctx, http.MethodPost, "/engine", engineReq, &engine); err != nil {
+		return nil, err
+	}
+
+	return &engine, nil
+}
+
+func (c *Client) UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest) (*models.Engine, error) {
+	var engine models.Engine
+
+	if err := c.do(ctx, http.MethodPut, "/engine/"+url.PathEscape(id), engineReq, &engine); err != nil {
+		return nil, err
+	}
+
+	return &engine, nil
+}
+
+func (c *Client) DeleteEngine(ctx context.Context, id string) (*models.Engine, error) {
+	var engine models.Engine
+
+	if err := c.do(ctx, http.MethodDelete, "/engine/"+url.PathEscape(id), nil, &engine); err != nil {
+		return nil, err
+	}
+
+	return &engine, nil
+}
+
+// existingEngine returns the engine the API suggested in place of a
+// duplicate one that could not be created.
+func existingEngine(err error) (*models.Engine, bool) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		return nil, false
+	}
+
+	var resp struct {
+		Existing *models.Engine `json:"existing_engine"`
+	}
+	if json.Unmarshal([]byte(apiErr.Message), &resp) != nil || resp.Existing == nil {
+		return nil, false
+	}
+
+	return resp.Existing, true
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(respBody))}
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
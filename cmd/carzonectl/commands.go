@@ -0,0 +1,503 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/money"
+)
+
+// errPartial is returned when a bulk import created some but not all rows.
+var errPartial = errors.New("some rows failed to import")
+
+func (a *app) login(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("login")
+	user := fs.String("u", os.Getenv("CARZONE_USER"), "user name")
+	password := fs.String("p", os.Getenv("CARZONE_PASSWORD"), "password (defaults to $CARZONE_PASSWORD)")
+
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *user == "" || *password == "" {
+		return fmt.Errorf("%w: login requires -u and -p (or $CARZONE_USER and $CARZONE_PASSWORD)", errUsage)
+	}
+
+	client := NewClient(a.server, "", a.timeout)
+
+	token, err := client.Login(ctx, models.Credentials{UserName: *user, Password: *password})
+	if err != nil {
+		return err
+	}
+
+	err = saveToken(a.tokenFile, &cachedToken{
+		Server:   a.server,
+		UserName: *user,
+		Token:    token,
+		IssuedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("caching token: %w", err)
+	}
+
+	fmt.Fprintf(a.stderr, "logged in to %s as %s\n", a.server, *user)
+	return nil
+}
+
+func (a *app) car(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: car requires a subcommand", errUsage)
+	}
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	sub, args := args[0], args[1:]
+
+	switch sub {
+	case "get":
+		id, err := requireID(sub, args)
+		if err != nil {
+			return err
+		}
+
+		car, err := client.GetCar(ctx, id)
+		if err != nil {
+			return err
+		}
+		return a.printer().cars(*car)
+
+	case "list":
+		fs := a.newFlagSet("car list")
+		brand := fs.String("brand", "", "brand to list")
+		withEngine := fs.Bool("engine", false, "include engine details")
+		query := url.Values{}
+		for name, help := range map[string]string{
+			"min-mileage":     "minimum mileage in -mileage-unit",
+			"max-mileage":     "maximum mileage in -mileage-unit",
+			"mileage-unit":    strings.Join(models.MileageUnits, " or ") + " (default km)",
+			"min-condition":   "worst condition to include: " + strings.Join(models.ConditionGrades, ", "),
+			"max-owners":      "maximum number of previous owners",
+			"service-history": strings.Join(models.ServiceHistories, ", "),
+		} {
+			fs.Func(name, help, func(value string) error {
+				query.Set(strings.ReplaceAll(name, "-", "_"), value)
+				return nil
+			})
+		}
+		accidentFree := fs.Bool("accident-free", false, "only cars with no recorded accidents")
+		if err := parseFlags(fs, args); err != nil {
+			return err
+		}
+		if *brand == "" {
+			return fmt.Errorf("%w: car list requires -brand", errUsage)
+		}
+		if *withEngine {
+			query.Set("isEngine", "true")
+		}
+		if *accidentFree {
+			query.Set("accident_free", "true")
+		}
+
+		cars, err := client.ListCarsByBrand(ctx, *brand, query)
+		if err != nil {
+			return err
+		}
+		return a.printer().cars(cars...)
+
+	case "create":
+		carReq, err := a.carRequest(ctx, client, "car create", args)
+		if err != nil {
+			return err
+		}
+
+		car, err := client.CreateCar(ctx, carReq)
+		if err != nil {
+			return err
+		}
+		return a.printer().cars(*car)
+
+	case "update":
+		id, err := requireID(sub, args)
+		if err != nil {
+			return err
+		}
+
+		carReq, err := a.carRequest(ctx, client, "car update", args[1:])
+		if err != nil {
+			return err
+		}
+
+		car, err := client.UpdateCar(ctx, id, carReq)
+		if err != nil {
+			return err
+		}
+		return a.printer().cars(*car)
+
+	case "prices":
+		id, err := requireID(sub, args)
+		if err != nil {
+			return err
+		}
+
+		history, err := client.GetPriceHistory(ctx, id)
+		if err != nil {
+			return err
+		}
+		return a.printer().prices(*history)
+
+	case "delete":
+		id, err := requireID(sub, args)
+		if err != nil {
+			return err
+		}
+
+		car, err := client.DeleteCar(ctx, id)
+		if err != nil {
+			return err
+		}
+		return a.printer().cars(*car)
+
+	case "import":
+		return a.importFile(ctx, client, args, importCars)
+	}
+
+	return fmt.Errorf("%w: unknown car subcommand %q", errUsage, sub)
+}
+
+func (a *app) engine(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: engine requires a subcommand", errUsage)
+	}
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	sub, args := args[0], args[1:]
+
+	switch sub {
+	case "get":
+		id, err := requireID(sub, args)
+		if err != nil {
+			return err
+		}
+
+		engine, err := client.GetEngine(ctx, id)
+		if err != nil {
+			return err
+		}
+		return a.printer().engines(*engine)
+
+	case "list":
+		fs := a.newFlagSet("engine list")
+		query := url.Values{}
+		for name, help := range map[string]string{
+			"powertrain":       strings.Join(models.Powertrains, ", "),
+			"min-displacement": "minimum displacement in cc",
+			"max-displacement": "maximum displacement in cc",
+			"cylinders":        "number of cylinders",
+			"min-range":        "minimum range",
+			"max-range":        "maximum range",
+			"sort":             "created, displacement, cylinders or range; prefix - for descending",
+			"limit":            "engines per page",
+			"offset":           "engines to skip",
+		} {
+			fs.Func(name, help, func(value string) error {
+				query.Set(strings.ReplaceAll(name, "-", "_"), value)
+				return nil
+			})
+		}
+		if err := parseFlags(fs, args); err != nil {
+			return err
+		}
+
+		page, err := client.ListEngines(ctx, query)
+		if err != nil {
+			return err
+		}
+		return a.printer().engines(page.Engines...)
+
+	case "cars":
+		id, err := requireID(sub, args)
+		if err != nil {
+			return err
+		}
+
+		cars, err := client.ListEngineCars(ctx, id)
+		if err != nil {
+			return err
+		}
+		return a.printer().cars(cars...)
+
+	case "create":
+		engineReq, err := a.engineRequest("engine create", args)
+		if err != nil {
+			return err
+		}
+
+		engine, err := client.CreateEngine(ctx, engineReq)
+		if err != nil {
+			return err
+		}
+		return a.printer().engines(*engine)
+
+	case "update":
+		id, err := requireID(sub, args)
+		if err != nil {
+			return err
+		}
+
+		engineReq, err := a.engineRequest("engine update", args[1:])
+		if err != nil {
+			return err
+		}
+
+		engine, err := client.UpdateEngine(ctx, id, engineReq)
+		if err != nil {
+			return err
+		}
+		return a.printer().engines(*engine)
+
+	case "delete":
+		id, err := requireID(sub, args)
+		if err != nil {
+			return err
+		}
+
+		engine, err := client.DeleteEngine(ctx, id)
+		if err != nil {
+			return err
+		}
+		return a.printer().engines(*engine)
+
+	case "import":
+		return a.importFile(ctx, client, args, importEngines)
+	}
+
+	return fmt.Errorf("%w: unknown engine subcommand %q", errUsage, sub)
+}
+
+// carRequest builds a car request from either a JSON file (-f) or individual
+// flags. The API validates the engine specs along with the car, so they are
+// looked up from the engine ID when not supplied in the file.
+func (a *app) carRequest(ctx context.Context, client *Client, name string, args []string) (*models.CarRequest, error) {
+	var carReq models.CarRequest
+	var engineID, price, currency string
+	var mileage int64
+	var mileageUnit string
+
+	fs := a.newFlagSet(name)
+	file := fs.String("f", "", "JSON file with the car request ('-' for stdin)")
+	fs.StringVar(&carReq.Name, "name", "", "car name")
+	fs.StringVar(&carReq.Year, "year", "", "model year")
+	fs.StringVar(&carReq.Brand, "brand", "", "brand name or alias")
+	fs.StringVar(&carReq.Model, "model", "", "model name or alias within the brand")
+	fs.StringVar(&carReq.FuelType, "fuel", "", "fuel type")
+	fs.StringVar(&engineID, "engine-id", "", "engine ID")
+	fs.StringVar(&price, "price", "", "price, e.g. 24000.00")
+	fs.StringVar(&currency, "currency", money.USD, "ISO 4217 currency of -price")
+	fs.StringVar(&carReq.Trim, "trim", "", "trim level")
+	fs.StringVar(&carReq.Transmission, "transmission", "", "transmission code, e.g. automatic")
+	fs.StringVar(&carReq.Drivetrain, "drivetrain", "", "drivetrain code, e.g. awd")
+	fs.StringVar(&carReq.BodyStyle, "body-style", "", "body style code, e.g. suv")
+	fs.StringVar(&carReq.Colour, "colour", "", "colour code, e.g. silver")
+	fs.IntVar(&carReq.Doors, "doors", 0, "number of doors")
+	fs.IntVar(&carReq.Seats, "seats", 0, "number of seats")
+	fs.Int64Var(&mileage, "mileage", 0, "odometer reading in -mileage-unit")
+	fs.StringVar(&mileageUnit, "mileage-unit", models.MileageKilometres, strings.Join(models.MileageUnits, " or "))
+	fs.StringVar(&carReq.Condition, "condition", "", strings.Join(models.ConditionGrades, ", "))
+	fs.Func("owners", "number of previous owners", func(value string) error {
+		n, err := strconv.Atoi(value)
+		carReq.PreviousOwners = &n
+		return err
+	})
+	fs.Func("accidents", "number of recorded accidents", func(value string) error {
+		n, err := strconv.Atoi(value)
+		carReq.Accidents = &n
+		return err
+	})
+	fs.StringVar(&carReq.ServiceHistory, "service-history", "", strings.Join(models.ServiceHistories, ", "))
+
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+
+	if *file != "" {
+		if err := a.readJSON(*file, &carReq); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := setIfPresent(fs, "engine-id", func() error {
+			id, err := uuid.Parse(engineID)
+			carReq.Engine.EngineID = id
+			return err
+		}); err != nil {
+			return nil, fmt.Errorf("%w: invalid -engine-id", errUsage)
+		}
+		if err := setIfPresent(fs, "price", func() error {
+			var err error
+			carReq.Price, err = money.Parse(price, strings.ToUpper(currency))
+			return err
+		}); err != nil {
+			return nil, fmt.Errorf("%w: invalid -price", errUsage)
+		}
+		setIfPresent(fs, "mileage", func() error {
+			carReq.Mileage = &models.Mileage{Value: mileage, Unit: mileageUnit}
+			return nil
+		})
+	}
+
+	if carReq.Engine.EngineID != uuid.Nil && carReq.Engine.Displacement == 0 {
+		engine, err := client.GetEngine(ctx, carReq.Engine.EngineID.String())
+		if err != nil {
+			return nil, fmt.Errorf("looking up engine: %w", err)
+		}
+		carReq.Engine = *engine
+	}
+
+	return &carReq, nil
+}
+
+func (a *app) engineRequest(name string, args []string) (*models.EngineRequest, error) {
+	var engineReq models.EngineRequest
+
+	fs := a.newFlagSet(name)
+	file := fs.String("f", "", "JSON file with the engine request ('-' for stdin)")
+	fs.Int64Var(&engineReq.Displacement, "displacement", 0, "displacement in cc")
+	fs.Int64Var(&engineReq.NoOfCylinders, "cylinders", 0, "number of cylinders")
+	fs.Int64Var(&engineReq.CarRange, "range", 0, "range")
+	fs.StringVar(&engineReq.Powertrain, "powertrain", "", "powertrain: "+strings.Join(models.Powertrains, ", "))
+
+	var (
+		motor    models.Motor
+		battery  models.Battery
+		combined models.CombinedRating
+	)
+	fs.Int64Var(&motor.PowerKW, "motor-kw", 0, "motor power in kW")
+	fs.Int64Var(&motor.TorqueNm, "motor-nm", 0, "motor torque in Nm")
+	fs.Float64Var(&battery.CapacityKWh, "battery-kwh", 0, "battery capacity in kWh")
+	fs.Func("charging", "comma-separated charging standards: "+strings.Join(models.ChargingStandards, ", "), func(value string) error {
+		battery.ChargingStandards = strings.Split(value, ",")
+		return nil
+	})
+	fs.Float64Var(&battery.MaxACChargeKW, "ac-kw", 0, "maximum AC charge power in kW")
+	fs.Float64Var(&battery.MaxDCChargeKW, "dc-kw", 0, "maximum DC charge power in kW")
+	fs.Int64Var(&combined.PowerKW, "combined-kw", 0, "combined hybrid power in kW")
+	fs.Int64Var(&combined.TorqueNm, "combined-nm", 0, "combined hybrid torque in Nm")
+
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+
+	if motor != (models.Motor{}) {
+		engineReq.Motor = &motor
+	}
+	if battery.CapacityKWh != 0 || len(battery.ChargingStandards) > 0 {
+		engineReq.Battery = &battery
+	}
+	if combined != (models.CombinedRating{}) {
+		engineReq.Combined = &combined
+	}
+
+	if *file != "" {
+		if err := a.readJSON(*file, &engineReq); err != nil {
+			return nil, err
+		}
+	}
+
+	return &engineReq, nil
+}
+
+func (a *app) importFile(ctx context.Context, client *Client, args []string, importer func(context.Context, *Client, io.Reader) ([]importResult, error)) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: import requires exactly one CSV file", errUsage)
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	results, err := importer(ctx, client, f)
+	if err != nil {
+		return err
+	}
+
+	if err := a.printer().importResults(results); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, res := range results {
+		if res.Error != "" {
+			failed++
+		}
+	}
+
+	switch {
+	case failed == 0:
+		return nil
+	case failed == len(results):
+		return fmt.Errorf("all %d rows failed to import", failed)
+	}
+
+	return fmt.Errorf("%w: %d of %d", errPartial, failed, len(results))
+}
+
+func (a *app) readJSON(path string, v interface{}) error {
+	var data []byte
+	var err error
+
+	if path == "-" {
+		data, err = io.ReadAll(a.stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: parsing %s: %v", errUsage, path, err)
+	}
+
+	return nil
+}
+
+func requireID(sub string, args []string) (string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", fmt.Errorf("%w: %s requires an ID", errUsage, sub)
+	}
+	return args[0], nil
+}
+
+// setIfPresent runs set only when the named flag was given on the command line.
+func setIfPresent(fs *flag.FlagSet, name string, set func() error) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			err = set()
+		}
+	})
+	return err
+}
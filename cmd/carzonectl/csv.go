@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/money"
+)
+
+type importResult struct {
+	Line  int    `json:"line"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// csvRecords reads a CSV file with a header row and returns each data row
+// keyed by the lower-cased header name, along with its line number.
+func csvRecords(r io.Reader, required ...string) ([]map[string]string, []int, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, errors.New("csv file is empty")
+		}
+		return nil, nil, err
+	}
+
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	for _, col := range required {
+		found := false
+		for _, h := range header {
+			if h == col {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("csv header is missing column %q", col)
+		}
+	}
+
+	var records []map[string]string
+	var lines []int
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		record := make(map[string]string, len(header))
+		for i, value := range row {
+			if i < len(header) {
+				record[header[i]] = strings.TrimSpace(value)
+			}
+		}
+
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+
+	return records, lines, nil
+}
+
+func engineRequestFromRecord(record map[string]string) (*models.EngineRequest, error) {
+	displacement, err := strconv.ParseInt(record["displacement"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid displacement %q", record["displacement"])
+	}
+
+	cylinders, err := strconv.ParseInt(record["no_of_cylinders"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid no_of_cylinders %q", record["no_of_cylinders"])
+	}
+
+	carRange, err := strconv.ParseInt(record["car_range"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid car_range %q", record["car_range"])
+	}
+
+	return &models.EngineRequest{
+		Displacement:  displacement,
+		NoOfCylinders: cylinders,
+		CarRange:      carRange,
+	}, nil
+}
+
+func carRequestFromRecord(record map[string]string) (*models.CarRequest, error) {
+	engineID, err := uuid.Parse(record["engine_id"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid engine_id %q", record["engine_id"])
+	}
+
+	// The currency column is optional; prices without one are in dollars.
+	currency := strings.ToUpper(record["currency"])
+	if currency == "" {
+		currency = money.USD
+	}
+
+	price, err := money.Parse(record["price"], currency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid price %q: %w", record["price"], err)
+	}
+
+	return &models.CarRequest{
+		Name:     record["name"],
+		Year:     record["year"],
+		Brand:    record["brand"],
+		FuelType: record["fuel_type"],
+		Engine:   models.Engine{EngineID: engineID},
+		Price:    price,
+	}, nil
+}
+
+func importEngines(ctx context.Context, client *Client, r io.Reader) ([]importResult, error) {
+	records, lines, err := csvRecords(r, "displacement", "no_of_cylinders", "car_range")
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]importResult, 0, len(records))
+
+	for i, record := range records {
+		res := importResult{Line: lines[i]}
+
+		engineReq, err := engineRequestFromRecord(record)
+		if err == nil {
+			var engine *models.Engine
+			engine, err = client.CreateEngine(ctx, engineReq)
+			if existing, ok := existingEngine(err); ok {
+				// Rows matching an existing engine resolve to it, so
+				// importing the same file twice creates nothing new.
+				engine, err = existing, nil
+			}
+			if err == nil {
+				res.ID = engine.EngineID.String()
+			}
+		}
+
+		if err != nil {
+			res.Error = err.Error()
+		}
+
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+func importCars(ctx context.Context, client *Client, r io.Reader) ([]importResult, error) {
+	records, lines, err := csvRecords(r, "name", "year", "brand", "fuel_type", "engine_id", "price")
+	if err != nil {
+		return nil, err
+	}
+
+	// Car creation requires the full engine specs, so each referenced engine
+	// is fetched once and reused for every row that points at it.
+	engines := make(map[uuid.UUID]*models.Engine)
+	results := make([]importResult, 0, len(records))
+
+	for i, record := range records {
+		res := importResult{Line: lines[i]}
+
+		carReq, err := carRequestFromRecord(record)
+		if err == nil {
+			engine, ok := engines[carReq.Engine.EngineID]
+			if !ok {
+				engine, err = client.GetEngine(ctx, carReq.Engine.EngineID.String())
+				if err == nil {
+					engines[carReq.Engine.EngineID] = engine
+				}
+			}
+
+			if err == nil {
+				carReq.Engine = *engine
+
+				var car *models.Car
+				car, err = client.CreateCar(ctx, carReq)
+				if err == nil {
+					res.ID = car.ID.String()
+				}
+			}
+		}
+
+		if err != nil {
+			res.Error = err.Error()
+		}
+
+		results = append(results, res)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,203 @@
+// Command carzonectl is an operator CLI for the carzone API.
+//
+//	carzonectl [global flags] <command> [args]
+//
+// Run `carzonectl help` for the list of commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Exit codes are part of the CLI contract so scripts can branch on them.
+const (
+	exitOK           = 0
+	exitError        = 1
+	exitUsage        = 2
+	exitUnauthorized = 3
+	exitNotFound     = 4
+	exitPartial      = 5
+)
+
+var (
+	// errUsage marks errors caused by invalid command-line input.
+	errUsage = errors.New("usage error")
+
+	// errNotLoggedIn is returned when no usable token is available.
+	errNotLoggedIn = errors.New("not logged in")
+)
+
+const usage = `Usage: carzonectl [global flags] <command> [args]
+
+Commands:
+  login -u <user> [-p <password>]    log in and cache the token
+  logout                             remove the cached token
+
+  car get <id>
+  car list -brand <brand> [-engine] [-min-mileage ..] [-max-mileage ..] [-mileage-unit km|mi] [-min-condition ..]
+           [-max-owners ..] [-accident-free] [-service-history full|partial|none]
+  car create (-f <file.json> | -name .. -year .. -brand .. -fuel .. -engine-id .. -price .. [-currency EUR]
+              [-model ..] [-trim ..] [-transmission ..] [-drivetrain ..] [-body-style ..] [-colour ..] [-doors ..] [-seats ..]
+              [-mileage .. [-mileage-unit mi]] [-condition ..] [-owners ..] [-accidents ..] [-service-history ..])
+  car update <id> (-f <file.json> | flags as for create)
+  car delete <id>
+  car prices <id>                    show a car's price history
+  car import <file.csv>              columns: name,year,brand,fuel_type,engine_id,price[,currency]
+
+  engine get <id>
+  engine list [-powertrain ..] [-min-displacement ..] [-max-displacement ..] [-cylinders ..] [-min-range ..] [-max-range ..]
+              [-sort created|displacement|cylinders|range] [-limit ..] [-offset ..]
+  engine cars <id>                   list the cars using an engine
+  engine create (-f <file.json> | -displacement .. -cylinders .. -range .. [-powertrain ..]
+                 [-motor-kw .. -motor-nm ..] [-battery-kwh .. -charging CCS2,Type2 -ac-kw .. -dc-kw ..]
+                 [-combined-kw .. -combined-nm ..])
+  engine update <id> (-f <file.json> | flags as for create)
+  engine delete <id>
+  engine import <file.csv>           columns: displacement,no_of_cylinders,car_range
+
+Global flags:
+`
+
+type app struct {
+	server    string
+	output    string
+	tokenFile string
+	timeout   time.Duration
+
+	stdout io.Writer
+	stderr io.Writer
+	stdin  io.Reader
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	a := &app{stdout: os.Stdout, stderr: os.Stderr, stdin: os.Stdin}
+
+	fs := flag.NewFlagSet("carzonectl", flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.StringVar(&a.server, "server", envOr("CARZONE_SERVER", "http://localhost:8080"), "carzone API base URL")
+	fs.StringVar(&a.output, "o", outputTable, "output format: table, json or yaml")
+	fs.StringVar(&a.tokenFile, "token-file", defaultTokenFile(), "where the login token is cached")
+	fs.DurationVar(&a.timeout, "timeout", 30*time.Second, "per-request timeout")
+	fs.Usage = func() {
+		fmt.Fprint(a.stderr, usage)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if !validOutput(a.output) {
+		fmt.Fprintf(a.stderr, "unknown output format %q\n", a.output)
+		return exitUsage
+	}
+
+	if fs.NArg() == 0 || fs.Arg(0) == "help" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	ctx := context.Background()
+
+	var err error
+	switch cmd, rest := fs.Arg(0), fs.Args()[1:]; cmd {
+	case "login":
+		err = a.login(ctx, rest)
+	case "logout":
+		err = removeToken(a.tokenFile)
+	case "car", "cars":
+		err = a.car(ctx, rest)
+	case "engine", "engines":
+		err = a.engine(ctx, rest)
+	default:
+		err = fmt.Errorf("%w: unknown command %q", errUsage, cmd)
+	}
+
+	return a.exitCode(err)
+}
+
+func (a *app) exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	fmt.Fprintln(a.stderr, "error:", err)
+
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		return exitUsage
+	case errors.Is(err, errNotLoggedIn):
+		return exitUnauthorized
+	case errors.Is(err, errPartial):
+		return exitPartial
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized:
+		return exitUnauthorized
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		return exitNotFound
+	}
+
+	return exitError
+}
+
+func (a *app) printer() printer {
+	return printer{w: a.stdout, format: a.output}
+}
+
+// client returns an API client authenticated with the cached token, or with
+// $CARZONE_TOKEN when it is set.
+func (a *app) client() (*Client, error) {
+	token := os.Getenv("CARZONE_TOKEN")
+
+	if token == "" {
+		cached, err := loadToken(a.tokenFile)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("%w, run `carzonectl login` first", errNotLoggedIn)
+			}
+			return nil, err
+		}
+		if cached.Server != a.server {
+			return nil, fmt.Errorf("%w to %s, run `carzonectl login` again", errNotLoggedIn, a.server)
+		}
+		token = cached.Token
+	}
+
+	return NewClient(a.server, token, a.timeout), nil
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// newFlagSet returns a flag set for a subcommand that reports errors as usage
+// errors instead of exiting.
+func (a *app) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/michgboxy2/carzone/models"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+func validOutput(format string) bool {
+	return format == outputTable || format == outputJSON || format == outputYAML
+}
+
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func (p printer) cars(cars ...models.Car) error {
+	switch p.format {
+	case outputJSON, outputYAML:
+		if len(cars) == 1 {
+			return p.encode(cars[0])
+		}
+		return p.encode(cars)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tYEAR\tBRAND\tFUEL\tENGINE\tPRICE")
+
+	for _, car := range cars {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			car.ID, car.Name, car.Year, car.Brand, car.FuelType, car.Engine.EngineID,
+			car.Price)
+	}
+
+	return tw.Flush()
+}
+
+// prices prints a car's price history, the summary above the timeline.
+func (p printer) prices(history models.PriceHistory) error {
+	switch p.format {
+	case outputJSON, outputYAML:
+		return p.encode(history)
+	}
+
+	change := "-"
+	if history.ChangePercent != nil {
+		change = fmt.Sprintf("%+.2f%%", *history.ChangePercent)
+	}
+
+	fmt.Fprintf(p.w, "%d days on market, %d days at current price, %d reductions, %d increases, %s overall\n\n",
+		history.DaysOnMarket, history.DaysAtCurrentPrice, history.Reductions, history.Increases, change)
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHANGED\tPRICE\tCHANGE")
+
+	for _, point := range history.Prices {
+		change := "-"
+		if point.ChangePercent != nil {
+			change = fmt.Sprintf("%+.2f%%", *point.ChangePercent)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", point.ChangedAt.Format(time.RFC3339), point.Price, change)
+	}
+
+	return tw.Flush()
+}
+
+func (p printer) engines(engines ...models.Engine) error {
+	switch p.format {
+	case outputJSON, outputYAML:
+		if len(engines) == 1 {
+			return p.encode(engines[0])
+		}
+		return p.encode(engines)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPOWERTRAIN\tDISPLACEMENT\tCYLINDERS\tRANGE")
+
+	for _, engine := range engines {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\n",
+			engine.EngineID, engine.Powertrain, engine.Displacement, engine.NoOfCylinders, engine.CarRange)
+	}
+
+	return tw.Flush()
+}
+
+func (p printer) importResults(results []importResult) error {
+	switch p.format {
+	case outputJSON, outputYAML:
+		return p.encode(results)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LINE\tSTATUS\tID\tERROR")
+
+	for _, res := range results {
+		status := "created"
+		if res.Error != "" {
+			status = "failed"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", res.Line, status, res.ID, res.Error)
+	}
+
+	return tw.Flush()
+}
+
+// encode writes v as JSON or YAML. YAML is produced from the JSON encoding so
+// both formats use the same field names and ordering as the API.
+func (p printer) encode(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if p.format == outputJSON {
+		_, err = fmt.Fprintln(p.w, string(data))
+		return err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	resetStyle(&node)
+
+	enc := yaml.NewEncoder(p.w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// resetStyle drops the flow style the YAML parser records for JSON input so
+// the output is rendered as block YAML.
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cachedToken is what `carzonectl login` writes to disk so later commands
+// can authenticate without asking for credentials again.
+type cachedToken struct {
+	Server   string    `json:"server"`
+	UserName string    `json:"username"`
+	Token    string    `json:"token"`
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+func defaultTokenFile() string {
+	if path := os.Getenv("CARZONE_TOKEN_FILE"); path != "" {
+		return path
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "carzonectl", "token.json")
+}
+
+func loadToken(path string) (*cachedToken, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var token cachedToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func saveToken(path string, token *cachedToken) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o600)
+}
+
+func removeToken(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,653 @@
+// Package config loads the service configuration.
+//
+// Values are layered, each layer overriding the previous one:
+//
+//  1. built-in defaults
+//  2. a YAML file given by -config or $CARZONE_CONFIG
+//  3. environment variables (and a .env file when present)
+//  4. command-line flags
+//
+// Secrets can also be read from files by setting the matching *_FILE
+// variable, e.g. DB_PASSWORD_FILE=/run/secrets/db_password, which is how
+// Docker and Kubernetes mount them.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/michgboxy2/carzone/money"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server    Server    `yaml:"server"`
+	Database  Database  `yaml:"database"`
+	Tracing   Tracing   `yaml:"tracing"`
+	Auth      Auth      `yaml:"auth"`
+	Health    Health    `yaml:"health"`
+	Cache     Cache     `yaml:"cache"`
+	Events    Events    `yaml:"events"`
+	Webhooks  Webhooks  `yaml:"webhooks"`
+	Stream    Stream    `yaml:"stream"`
+	Blob      Blob      `yaml:"blob"`
+	Images    Images    `yaml:"images"`
+	Documents Documents `yaml:"documents"`
+	Currency  Currency  `yaml:"currency"`
+	FuelTypes FuelTypes `yaml:"fuel_types"`
+	Alerts    Alerts    `yaml:"alerts"`
+	Similar   Similar   `yaml:"similar"`
+}
+
+type Server struct {
+	Port              int           `yaml:"port"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// ShutdownDelay keeps the server accepting requests for a while after
+	// readiness turns false, giving load balancers time to stop routing.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay"`
+	// ShutdownTimeout bounds how long in-flight requests and background
+	// workers get to finish after SIGTERM.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type Database struct {
+	// PrimaryDSN, when set, is used as is instead of building a connection
+	// string from the fields below.
+	PrimaryDSN Secret `yaml:"primary_dsn"`
+
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password Secret `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// ConnectTimeout is how long startup keeps retrying before giving up on
+	// the database.
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+
+	// RetryAttempts is how many times a store call is retried after a
+	// transient error; 0 disables retries.
+	RetryAttempts        int           `yaml:"retry_attempts"`
+	RetryInitialInterval time.Duration `yaml:"retry_initial_interval"`
+	RetryMaxInterval     time.Duration `yaml:"retry_max_interval"`
+
+	// Replicas are connection strings for read replicas. Lookups are spread
+	// over the replicas that are reachable and no further behind the
+	// primary than MaxReplicationLag; otherwise they go to the primary.
+	Replicas             []Secret      `yaml:"replicas"`
+	MaxReplicationLag    time.Duration `yaml:"max_replication_lag"`
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval"`
+}
+
+type Tracing struct {
+	Enabled     bool   `yaml:"enabled"`
+	Host        string `yaml:"host"`
+	Port        int    `yaml:"port"`
+	ServiceName string `yaml:"service_name"`
+}
+
+type Health struct {
+	CheckTimeout   time.Duration `yaml:"check_timeout"`
+	MaxPingLatency time.Duration `yaml:"max_ping_latency"`
+}
+
+// Cache configures the read-through cache in front of the car and engine
+// lookups. Backend is "memory" (per-process LRU), "redis" (shared between
+// instances) or "none".
+type Cache struct {
+	Backend string        `yaml:"backend"`
+	TTL     time.Duration `yaml:"ttl"`
+	// Size is the maximum number of entries held by the memory backend.
+	Size int `yaml:"size"`
+
+	RedisAddr     string `yaml:"redis_addr"`
+	RedisPassword Secret `yaml:"redis_password"`
+	RedisDB       int    `yaml:"redis_db"`
+}
+
+// Events configures where the outbox relay publishes domain events. Sink is
+// "log", "webhook" or "nats".
+type Events struct {
+	Sink           string        `yaml:"sink"`
+	WebhookURL     string        `yaml:"webhook_url"`
+	WebhookTimeout time.Duration `yaml:"webhook_timeout"`
+	NATSURL        string        `yaml:"nats_url"`
+	// NATSSubject is the subject prefix; events go to
+	// <prefix>.<aggregate>.<type>.
+	NATSSubject string `yaml:"nats_subject"`
+
+	RelayInterval time.Duration `yaml:"relay_interval"`
+	BatchSize     int           `yaml:"batch_size"`
+	// PublishTimeout bounds how long a batch may take to publish. Events
+	// not reached by then wait for the next batch, and those of a relay that
+	// stopped mid-batch are published again once it has passed.
+	PublishTimeout time.Duration `yaml:"publish_timeout"`
+	// Retention is how long published events stay in the outbox.
+	Retention time.Duration `yaml:"retention"`
+	// An event that fails to publish is retried after RelayInterval,
+	// doubled for every earlier failure up to MaxBackoff. After MaxAttempts
+	// it is parked, holding back its aggregate until it is requeued by hand.
+	MaxAttempts int           `yaml:"max_attempts"`
+	MaxBackoff  time.Duration `yaml:"max_backoff"`
+}
+
+// Webhooks configures delivery to partner webhook subscriptions.
+type Webhooks struct {
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxAttempts is how many times a delivery is tried before it is
+	// dead-lettered.
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	PollInterval   time.Duration `yaml:"poll_interval"`
+	BatchSize      int           `yaml:"batch_size"`
+}
+
+// Stream configures the live change feed at /cars/stream and /cars/ws.
+type Stream struct {
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+	// ReplayLimit caps how many missed events a reconnecting client is sent.
+	ReplayLimit int `yaml:"replay_limit"`
+	// ClientBuffer is how many events may queue for a slow client before it
+	// is disconnected.
+	ClientBuffer int `yaml:"client_buffer"`
+	// AllowedOrigins are the cross-origin pages allowed to open WebSockets.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// Blob configures where uploaded files are kept. Backend is "filesystem",
+// which stores them under Dir.
+type Blob struct {
+	Backend string `yaml:"backend"`
+	Dir     string `yaml:"dir"`
+}
+
+// Images configures car photo uploads and the signed URLs they are served
+// from.
+type Images struct {
+	// MaxUploadSize is the largest accepted upload, in bytes.
+	MaxUploadSize  int `yaml:"max_upload_size"`
+	MaxPerCar      int `yaml:"max_per_car"`
+	ThumbnailWidth int `yaml:"thumbnail_width"`
+	// URLTTL is how long a signed image URL stays valid.
+	URLTTL time.Duration `yaml:"url_ttl"`
+	// URLSigningKey signs image URLs. It defaults to auth.jwt_key.
+	URLSigningKey Secret `yaml:"url_signing_key"`
+}
+
+// Documents configures the files, such as titles and service records,
+// attached to cars. Only tokens with one of ReadRoles may list and download
+// them, and only WriteRoles may upload and delete them.
+type Documents struct {
+	// MaxUploadSize is the largest accepted upload, in bytes.
+	MaxUploadSize int      `yaml:"max_upload_size"`
+	MaxPerCar     int      `yaml:"max_per_car"`
+	ReadRoles     []string `yaml:"read_roles"`
+	WriteRoles    []string `yaml:"write_roles"`
+}
+
+// Currency configures price conversion. Exchange rates are loaded against
+// Base, through PUT /admin/exchange-rates or `carzone rates import`.
+type Currency struct {
+	Base string `yaml:"base"`
+}
+
+// FuelTypes configures the fuel type registry. Cars are validated against a
+// copy of it that each instance reloads once CacheTTL has passed; changes
+// made through another instance show up within that time.
+type FuelTypes struct {
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+// Alerts configures saved search alerts. Notifier is "log", "smtp" or
+// "webhook"; the smtp notifier needs a server that takes plain SMTP, such as
+// a local MailHog in development.
+type Alerts struct {
+	Notifier     string `yaml:"notifier"`
+	SMTPAddr     string `yaml:"smtp_addr"`
+	SMTPFrom     string `yaml:"smtp_from"`
+	SMTPUsername string `yaml:"smtp_username"`
+	SMTPPassword Secret `yaml:"smtp_password"`
+	// WebhookURL receives each digest as JSON.
+	WebhookURL     string        `yaml:"webhook_url"`
+	WebhookTimeout time.Duration `yaml:"webhook_timeout"`
+	PollInterval   time.Duration `yaml:"poll_interval"`
+	BatchSize      int           `yaml:"batch_size"`
+	// RetryInterval is the wait before a digest that failed to send is
+	// tried again.
+	RetryInterval      time.Duration `yaml:"retry_interval"`
+	MaxSearchesPerUser int           `yaml:"max_searches_per_user"`
+}
+
+// Similar configures GET /cars/{id}/similar, which ranks cars by a weighted
+// distance over their brand, fuel type, year, price and engine. Rankings
+// are kept for CacheTTL, so new and changed cars take up to that long to
+// show up in them.
+type Similar struct {
+	Weights SimilarWeights `yaml:"weights"`
+	// YearSpan is the difference in years, and PriceBand the fraction of the
+	// car's price, at which cars count as entirely unlike in that respect.
+	YearSpan  int     `yaml:"year_span"`
+	PriceBand float64 `yaml:"price_band"`
+	// Limit is how many cars are returned when ?limit= is not given.
+	Limit     int           `yaml:"limit"`
+	CacheTTL  time.Duration `yaml:"cache_ttl"`
+	CacheSize int           `yaml:"cache_size"`
+}
+
+// SimilarWeights are how much each part of the distance counts. A zero
+// weight leaves that part out.
+type SimilarWeights struct {
+	Brand    float64 `yaml:"brand"`
+	FuelType float64 `yaml:"fuel_type"`
+	Year     float64 `yaml:"year"`
+	Price    float64 `yaml:"price"`
+	Engine   float64 `yaml:"engine"`
+}
+
+type Auth struct {
+	JWTKey        Secret        `yaml:"jwt_key"`
+	TokenTTL      time.Duration `yaml:"token_ttl"`
+	AdminUser     string        `yaml:"admin_user"`
+	AdminPassword Secret        `yaml:"admin_password"`
+}
+
+// Default returns the configuration used when nothing else is set.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:              8080,
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: Database{
+			Host:    "localhost",
+			Port:    5432,
+			User:    "postgres",
+			Name:    "postgres",
+			SSLMode: "disable",
+
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectTimeout:  60 * time.Second,
+
+			RetryAttempts:        3,
+			RetryInitialInterval: 50 * time.Millisecond,
+			RetryMaxInterval:     time.Second,
+
+			MaxReplicationLag:    5 * time.Second,
+			ReplicaCheckInterval: 5 * time.Second,
+		},
+		Tracing: Tracing{
+			Enabled:     true,
+			Host:        "jaeger",
+			Port:        4318,
+			ServiceName: "carzone",
+		},
+		Auth: Auth{
+			TokenTTL:      24 * time.Hour,
+			AdminUser:     "admin",
+			AdminPassword: "admin123",
+		},
+		Health: Health{
+			CheckTimeout:   2 * time.Second,
+			MaxPingLatency: 250 * time.Millisecond,
+		},
+		Cache: Cache{
+			Backend:   "memory",
+			TTL:       5 * time.Minute,
+			Size:      10000,
+			RedisAddr: "localhost:6379",
+		},
+		Events: Events{
+			Sink:           "log",
+			WebhookTimeout: 10 * time.Second,
+			NATSURL:        "nats://localhost:4222",
+			NATSSubject:    "carzone.events",
+			RelayInterval:  time.Second,
+			BatchSize:      100,
+			PublishTimeout: time.Minute,
+			Retention:      7 * 24 * time.Hour,
+			MaxAttempts:    10,
+			MaxBackoff:     5 * time.Minute,
+		},
+		Webhooks: Webhooks{
+			Timeout:        10 * time.Second,
+			MaxAttempts:    8,
+			InitialBackoff: 30 * time.Second,
+			MaxBackoff:     time.Hour,
+			PollInterval:   2 * time.Second,
+			BatchSize:      20,
+		},
+		Stream: Stream{
+			HeartbeatInterval: 15 * time.Second,
+			ReplayLimit:       1000,
+			ClientBuffer:      256,
+		},
+		Blob: Blob{
+			Backend: "filesystem",
+			Dir:     "data/blobs",
+		},
+		Images: Images{
+			MaxUploadSize:  10 << 20,
+			MaxPerCar:      20,
+			ThumbnailWidth: 320,
+			URLTTL:         15 * time.Minute,
+		},
+		Documents: Documents{
+			MaxUploadSize: 25 << 20,
+			MaxPerCar:     50,
+			ReadRoles:     []string{"admin", "dealer"},
+			WriteRoles:    []string{"admin", "dealer"},
+		},
+		Currency: Currency{
+			Base: money.USD,
+		},
+		FuelTypes: FuelTypes{
+			CacheTTL: time.Minute,
+		},
+		Alerts: Alerts{
+			Notifier:           "log",
+			SMTPAddr:           "localhost:1025",
+			SMTPFrom:           "alerts@carzone.local",
+			WebhookTimeout:     10 * time.Second,
+			PollInterval:       30 * time.Second,
+			BatchSize:          20,
+			RetryInterval:      5 * time.Minute,
+			MaxSearchesPerUser: 20,
+		},
+		Similar: Similar{
+			Weights: SimilarWeights{
+				Brand:    3,
+				FuelType: 2,
+				Year:     1,
+				Price:    2,
+				Engine:   1,
+			},
+			YearSpan:  10,
+			PriceBand: 0.5,
+			Limit:     6,
+			CacheTTL:  10 * time.Minute,
+			CacheSize: 1000,
+		},
+	}
+}
+
+// DSN returns the lib/pq connection string for the primary.
+func (d Database) DSN() string {
+	if d.PrimaryDSN != "" {
+		return d.PrimaryDSN.Value()
+	}
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		d.Host, d.Port, d.User, d.Password.Value(), d.Name, d.SSLMode)
+}
+
+// Endpoint returns the host:port of the OTLP HTTP collector.
+func (t Tracing) Endpoint() string {
+	return fmt.Sprintf("%s:%d", t.Host, t.Port)
+}
+
+// Load registers the configuration flags on fs, parses args and returns the
+// layered, validated configuration. Callers may register their own flags on
+// fs before calling Load.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	configFile := fs.String("config", os.Getenv("CARZONE_CONFIG"), "path to a YAML config file")
+	overrides := registerFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+
+	if *configFile != "" {
+		if err := loadFile(&cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("loading .env: %w", err)
+	}
+
+	if err := loadEnv(&cfg); err != nil {
+		return nil, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		if apply, ok := overrides[f.Name]; ok {
+			apply(&cfg)
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	// An empty file decodes to io.EOF and simply leaves the defaults alone.
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Validate reports every setting that would stop the service from working.
+func (c *Config) Validate() error {
+	var problems []string
+
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		problems = append(problems, "server.port must be between 1 and 65535")
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.ReadHeaderTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		problems = append(problems, "server timeouts must not be negative")
+	}
+	if c.Server.ShutdownDelay < 0 {
+		problems = append(problems, "server.shutdown_delay must not be negative")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		problems = append(problems, "server.shutdown_timeout must be positive")
+	}
+
+	if c.Database.PrimaryDSN == "" {
+		if c.Database.Host == "" {
+			problems = append(problems, "database.host is required")
+		}
+		if c.Database.Port <= 0 || c.Database.Port > 65535 {
+			problems = append(problems, "database.port must be between 1 and 65535")
+		}
+		if c.Database.User == "" {
+			problems = append(problems, "database.user is required")
+		}
+		if c.Database.Name == "" {
+			problems = append(problems, "database.name is required")
+		}
+	}
+
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		problems = append(problems, "database pool sizes must not be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		problems = append(problems, "database.max_idle_conns must not exceed database.max_open_conns")
+	}
+	if c.Database.ConnectTimeout <= 0 {
+		problems = append(problems, "database.connect_timeout must be positive")
+	}
+	if c.Database.RetryAttempts < 0 {
+		problems = append(problems, "database.retry_attempts must not be negative")
+	}
+	if c.Database.RetryAttempts > 0 && (c.Database.RetryInitialInterval <= 0 || c.Database.RetryMaxInterval < c.Database.RetryInitialInterval) {
+		problems = append(problems, "database retry intervals must be positive with max >= initial")
+	}
+	for i, dsn := range c.Database.Replicas {
+		if dsn == "" {
+			problems = append(problems, fmt.Sprintf("database.replicas[%d] is empty", i))
+		}
+	}
+	if len(c.Database.Replicas) > 0 && (c.Database.MaxReplicationLag <= 0 || c.Database.ReplicaCheckInterval <= 0) {
+		problems = append(problems, "database.max_replication_lag and database.replica_check_interval must be positive")
+	}
+
+	if c.Tracing.Enabled && (c.Tracing.Host == "" || c.Tracing.Port <= 0) {
+		problems = append(problems, "tracing.host and tracing.port are required when tracing is enabled")
+	}
+
+	if c.Auth.JWTKey.Value() == "" {
+		problems = append(problems, "auth.jwt_key is required (set JWT_KEY or JWT_KEY_FILE)")
+	}
+	if c.Auth.TokenTTL <= 0 {
+		problems = append(problems, "auth.token_ttl must be positive")
+	}
+	if c.Auth.AdminUser == "" || c.Auth.AdminPassword.Value() == "" {
+		problems = append(problems, "auth.admin_user and auth.admin_password are required")
+	}
+
+	if c.Health.CheckTimeout <= 0 || c.Health.MaxPingLatency <= 0 {
+		problems = append(problems, "health.check_timeout and health.max_ping_latency must be positive")
+	}
+
+	switch c.Cache.Backend {
+	case "none":
+	case "memory":
+		if c.Cache.Size <= 0 {
+			problems = append(problems, "cache.size must be positive for the memory backend")
+		}
+	case "redis":
+		if c.Cache.RedisAddr == "" {
+			problems = append(problems, "cache.redis_addr is required for the redis backend")
+		}
+	default:
+		problems = append(problems, "cache.backend must be one of none, memory, redis")
+	}
+	if c.Cache.Backend != "none" && c.Cache.TTL <= 0 {
+		problems = append(problems, "cache.ttl must be positive")
+	}
+
+	switch c.Events.Sink {
+	case "log":
+	case "webhook":
+		if c.Events.WebhookURL == "" || c.Events.WebhookTimeout <= 0 {
+			problems = append(problems, "events.webhook_url and a positive events.webhook_timeout are required for the webhook sink")
+		}
+	case "nats":
+		if c.Events.NATSURL == "" || c.Events.NATSSubject == "" {
+			problems = append(problems, "events.nats_url and events.nats_subject are required for the nats sink")
+		}
+	default:
+		problems = append(problems, "events.sink must be one of log, webhook, nats")
+	}
+	if c.Events.RelayInterval <= 0 || c.Events.BatchSize <= 0 || c.Events.PublishTimeout <= 0 || c.Events.Retention <= 0 || c.Events.MaxAttempts <= 0 || c.Events.MaxBackoff <= 0 {
+		problems = append(problems, "events.relay_interval, events.batch_size, events.publish_timeout, events.retention, events.max_attempts and events.max_backoff must be positive")
+	}
+
+	if c.Webhooks.Timeout <= 0 || c.Webhooks.PollInterval <= 0 || c.Webhooks.BatchSize <= 0 || c.Webhooks.MaxAttempts <= 0 {
+		problems = append(problems, "webhooks.timeout, webhooks.poll_interval, webhooks.batch_size and webhooks.max_attempts must be positive")
+	}
+	if c.Webhooks.InitialBackoff <= 0 || c.Webhooks.MaxBackoff < c.Webhooks.InitialBackoff {
+		problems = append(problems, "webhook backoff intervals must be positive with max >= initial")
+	}
+
+	if c.Stream.HeartbeatInterval <= 0 || c.Stream.ReplayLimit <= 0 || c.Stream.ClientBuffer <= 0 {
+		problems = append(problems, "stream.heartbeat_interval, stream.replay_limit and stream.client_buffer must be positive")
+	}
+
+	if c.Blob.Backend != "filesystem" {
+		problems = append(problems, "blob.backend must be filesystem")
+	} else if c.Blob.Dir == "" {
+		problems = append(problems, "blob.dir is required for the filesystem backend")
+	}
+
+	if c.Images.MaxUploadSize <= 0 || c.Images.MaxPerCar <= 0 || c.Images.ThumbnailWidth <= 0 || c.Images.URLTTL <= 0 {
+		problems = append(problems, "images.max_upload_size, images.max_per_car, images.thumbnail_width and images.url_ttl must be positive")
+	}
+
+	if c.Documents.MaxUploadSize <= 0 || c.Documents.MaxPerCar <= 0 {
+		problems = append(problems, "documents.max_upload_size and documents.max_per_car must be positive")
+	}
+	if len(c.Documents.ReadRoles) == 0 || len(c.Documents.WriteRoles) == 0 {
+		problems = append(problems, "documents.read_roles and documents.write_roles must not be empty")
+	}
+
+	if err := money.ValidateCurrency(c.Currency.Base); err != nil {
+		problems = append(problems, "currency.base: "+err.Error())
+	}
+
+	if c.FuelTypes.CacheTTL <= 0 {
+		problems = append(problems, "fuel_types.cache_ttl must be positive")
+	}
+
+	switch c.Alerts.Notifier {
+	case "log":
+	case "smtp":
+		if c.Alerts.SMTPAddr == "" || c.Alerts.SMTPFrom == "" {
+			problems = append(problems, "alerts.smtp_addr and alerts.smtp_from are required for the smtp notifier")
+		}
+	case "webhook":
+		if c.Alerts.WebhookURL == "" || c.Alerts.WebhookTimeout <= 0 {
+			problems = append(problems, "alerts.webhook_url and a positive alerts.webhook_timeout are required for the webhook notifier")
+		}
+	default:
+		problems = append(problems, "alerts.notifier must be one of log, smtp, webhook")
+	}
+	if c.Alerts.PollInterval <= 0 || c.Alerts.BatchSize <= 0 || c.Alerts.RetryInterval <= 0 || c.Alerts.MaxSearchesPerUser <= 0 {
+		problems = append(problems, "alerts.poll_interval, alerts.batch_size, alerts.retry_interval and alerts.max_searches_per_user must be positive")
+	}
+
+	w := c.Similar.Weights
+	if w.Brand < 0 || w.FuelType < 0 || w.Year < 0 || w.Price < 0 || w.Engine < 0 {
+		problems = append(problems, "similar.weights must not be negative")
+	} else if w.Brand+w.FuelType+w.Year+w.Price+w.Engine == 0 {
+		problems = append(problems, "similar.weights must not all be zero")
+	}
+	if c.Similar.YearSpan <= 0 || c.Similar.PriceBand <= 0 || c.Similar.Limit <= 0 || c.Similar.CacheTTL <= 0 || c.Similar.CacheSize <= 0 {
+		problems = append(problems, "similar.year_span, similar.price_band, similar.limit, similar.cache_ttl and similar.cache_size must be positive")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// Redacted renders the configuration as YAML with secrets masked, suitable
+// for logging at startup.
+func (c Config) Redacted() string {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Sprintf("<unprintable config: %v>", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,360 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// binding ties a configuration field to its environment variable and,
+// optionally, a command-line flag. Secrets are never exposed as flags since
+// flags show up in process listings; they can be read from <env>_FILE.
+type binding struct {
+	env    string
+	flag   string
+	usage  string
+	secret bool
+	field  func(*Config) interface{}
+}
+
+func bindings() []binding {
+	return []binding{
+		{env: "PORT", flag: "port", usage: "HTTP listen port",
+			field: func(c *Config) interface{} { return &c.Server.Port }},
+		{env: "HTTP_READ_TIMEOUT", flag: "read-timeout", usage: "maximum time to read a request",
+			field: func(c *Config) interface{} { return &c.Server.ReadTimeout }},
+		{env: "HTTP_READ_HEADER_TIMEOUT", flag: "read-header-timeout", usage: "maximum time to read request headers",
+			field: func(c *Config) interface{} { return &c.Server.ReadHeaderTimeout }},
+		{env: "HTTP_WRITE_TIMEOUT", flag: "write-timeout", usage: "maximum time to write a response",
+			field: func(c *Config) interface{} { return &c.Server.WriteTimeout }},
+		{env: "HTTP_IDLE_TIMEOUT", flag: "idle-timeout", usage: "how long keep-alive connections stay idle",
+			field: func(c *Config) interface{} { return &c.Server.IdleTimeout }},
+		{env: "SHUTDOWN_DELAY", flag: "shutdown-delay", usage: "how long to keep serving after readiness turns false",
+			field: func(c *Config) interface{} { return &c.Server.ShutdownDelay }},
+		{env: "SHUTDOWN_TIMEOUT", flag: "shutdown-timeout", usage: "how long to drain requests and workers on shutdown",
+			field: func(c *Config) interface{} { return &c.Server.ShutdownTimeout }},
+
+		{env: "DB_PRIMARY_DSN", secret: true,
+			field: func(c *Config) interface{} { return &c.Database.PrimaryDSN }},
+		{env: "DB_HOST", flag: "db-host", usage: "database host",
+			field: func(c *Config) interface{} { return &c.Database.Host }},
+		{env: "DB_PORT", flag: "db-port", usage: "database port",
+			field: func(c *Config) interface{} { return &c.Database.Port }},
+		{env: "DB_USER", flag: "db-user", usage: "database user",
+			field: func(c *Config) interface{} { return &c.Database.User }},
+		{env: "DB_PASSWORD", secret: true,
+			field: func(c *Config) interface{} { return &c.Database.Password }},
+		{env: "DB_NAME", flag: "db-name", usage: "database name",
+			field: func(c *Config) interface{} { return &c.Database.Name }},
+		{env: "DB_SSLMODE", flag: "db-sslmode", usage: "database sslmode",
+			field: func(c *Config) interface{} { return &c.Database.SSLMode }},
+		{env: "DB_MAX_OPEN_CONNS", flag: "db-max-open-conns", usage: "maximum open database connections (0 = unlimited)",
+			field: func(c *Config) interface{} { return &c.Database.MaxOpenConns }},
+		{env: "DB_MAX_IDLE_CONNS", flag: "db-max-idle-conns", usage: "maximum idle database connections",
+			field: func(c *Config) interface{} { return &c.Database.MaxIdleConns }},
+		{env: "DB_CONN_MAX_LIFETIME", flag: "db-conn-max-lifetime", usage: "maximum lifetime of a database connection",
+			field: func(c *Config) interface{} { return &c.Database.ConnMaxLifetime }},
+		{env: "DB_CONN_MAX_IDLE_TIME", flag: "db-conn-max-idle-time", usage: "maximum idle time of a database connection",
+			field: func(c *Config) interface{} { return &c.Database.ConnMaxIdleTime }},
+		{env: "DB_CONNECT_TIMEOUT", flag: "db-connect-timeout", usage: "how long to wait for the database at startup",
+			field: func(c *Config) interface{} { return &c.Database.ConnectTimeout }},
+		{env: "DB_RETRY_ATTEMPTS", flag: "db-retry-attempts", usage: "retries for transient database errors (0 disables)",
+			field: func(c *Config) interface{} { return &c.Database.RetryAttempts }},
+		{env: "DB_RETRY_INITIAL_INTERVAL", flag: "db-retry-initial-interval", usage: "first backoff between database retries",
+			field: func(c *Config) interface{} { return &c.Database.RetryInitialInterval }},
+		{env: "DB_RETRY_MAX_INTERVAL", flag: "db-retry-max-interval", usage: "largest backoff between database retries",
+			field: func(c *Config) interface{} { return &c.Database.RetryMaxInterval }},
+		// Comma-separated; DSNs carry passwords, hence secret.
+		{env: "DB_REPLICAS", secret: true,
+			field: func(c *Config) interface{} { return &c.Database.Replicas }},
+		{env: "DB_MAX_REPLICATION_LAG", flag: "db-max-replication-lag", usage: "replicas further behind than this are not read from",
+			field: func(c *Config) interface{} { return &c.Database.MaxReplicationLag }},
+		{env: "DB_REPLICA_CHECK_INTERVAL", flag: "db-replica-check-interval", usage: "how often replica health and lag are checked",
+			field: func(c *Config) interface{} { return &c.Database.ReplicaCheckInterval }},
+
+		{env: "TRACING_ENABLED", flag: "tracing", usage: "export traces over OTLP",
+			field: func(c *Config) interface{} { return &c.Tracing.Enabled }},
+		{env: "JAEGER_AGENT_HOST", flag: "tracing-host", usage: "OTLP collector host",
+			field: func(c *Config) interface{} { return &c.Tracing.Host }},
+		{env: "JAEGER_AGENT_PORT", flag: "tracing-port", usage: "OTLP collector HTTP port",
+			field: func(c *Config) interface{} { return &c.Tracing.Port }},
+
+		{env: "JWT_KEY", secret: true,
+			field: func(c *Config) interface{} { return &c.Auth.JWTKey }},
+		{env: "TOKEN_TTL", flag: "token-ttl", usage: "lifetime of issued API tokens",
+			field: func(c *Config) interface{} { return &c.Auth.TokenTTL }},
+		{env: "ADMIN_USER", flag: "admin-user", usage: "login name of the admin account",
+			field: func(c *Config) interface{} { return &c.Auth.AdminUser }},
+		{env: "ADMIN_PASSWORD", secret: true,
+			field: func(c *Config) interface{} { return &c.Auth.AdminPassword }},
+
+		{env: "HEALTH_CHECK_TIMEOUT", flag: "health-check-timeout", usage: "timeout for each health check",
+			field: func(c *Config) interface{} { return &c.Health.CheckTimeout }},
+		{env: "HEALTH_MAX_PING_LATENCY", flag: "health-max-ping-latency", usage: "database ping latency reported as degraded",
+			field: func(c *Config) interface{} { return &c.Health.MaxPingLatency }},
+
+		{env: "CACHE_BACKEND", flag: "cache", usage: "lookup cache backend: none, memory or redis",
+			field: func(c *Config) interface{} { return &c.Cache.Backend }},
+		{env: "CACHE_TTL", flag: "cache-ttl", usage: "how long cached lookups are served",
+			field: func(c *Config) interface{} { return &c.Cache.TTL }},
+		{env: "CACHE_SIZE", flag: "cache-size", usage: "maximum entries in the memory cache",
+			field: func(c *Config) interface{} { return &c.Cache.Size }},
+		{env: "REDIS_ADDR", flag: "redis-addr", usage: "redis host:port for the redis cache backend",
+			field: func(c *Config) interface{} { return &c.Cache.RedisAddr }},
+		{env: "REDIS_PASSWORD", secret: true,
+			field: func(c *Config) interface{} { return &c.Cache.RedisPassword }},
+		{env: "REDIS_DB", flag: "redis-db", usage: "redis database number",
+			field: func(c *Config) interface{} { return &c.Cache.RedisDB }},
+
+		{env: "EVENTS_SINK", flag: "events-sink", usage: "where domain events are published: log, webhook or nats",
+			field: func(c *Config) interface{} { return &c.Events.Sink }},
+		{env: "EVENTS_WEBHOOK_URL", flag: "events-webhook-url", usage: "URL the webhook sink posts events to",
+			field: func(c *Config) interface{} { return &c.Events.WebhookURL }},
+		{env: "EVENTS_WEBHOOK_TIMEOUT", flag: "events-webhook-timeout", usage: "timeout for each webhook delivery",
+			field: func(c *Config) interface{} { return &c.Events.WebhookTimeout }},
+		{env: "NATS_URL", flag: "nats-url", usage: "NATS server URL for the nats sink",
+			field: func(c *Config) interface{} { return &c.Events.NATSURL }},
+		{env: "EVENTS_NATS_SUBJECT", flag: "events-nats-subject", usage: "subject prefix for events published to NATS",
+			field: func(c *Config) interface{} { return &c.Events.NATSSubject }},
+		{env: "OUTBOX_RELAY_INTERVAL", flag: "outbox-relay-interval", usage: "how often the outbox is polled for new events",
+			field: func(c *Config) interface{} { return &c.Events.RelayInterval }},
+		{env: "OUTBOX_BATCH_SIZE", flag: "outbox-batch-size", usage: "maximum events published per relay round",
+			field: func(c *Config) interface{} { return &c.Events.BatchSize }},
+		{env: "OUTBOX_PUBLISH_TIMEOUT", flag: "outbox-publish-timeout", usage: "longest a relay round may spend publishing to the sink",
+			field: func(c *Config) interface{} { return &c.Events.PublishTimeout }},
+		{env: "OUTBOX_RETENTION", flag: "outbox-retention", usage: "how long published events are kept",
+			field: func(c *Config) interface{} { return &c.Events.Retention }},
+		{env: "OUTBOX_MAX_ATTEMPTS", flag: "outbox-max-attempts", usage: "failed publishes before an event is parked",
+			field: func(c *Config) interface{} { return &c.Events.MaxAttempts }},
+		{env: "OUTBOX_MAX_BACKOFF", flag: "outbox-max-backoff", usage: "longest wait before a failed event is published again",
+			field: func(c *Config) interface{} { return &c.Events.MaxBackoff }},
+
+		{env: "WEBHOOK_TIMEOUT", flag: "webhook-timeout", usage: "timeout for each webhook delivery attempt",
+			field: func(c *Config) interface{} { return &c.Webhooks.Timeout }},
+		{env: "WEBHOOK_MAX_ATTEMPTS", flag: "webhook-max-attempts", usage: "attempts before a webhook delivery is dead-lettered",
+			field: func(c *Config) interface{} { return &c.Webhooks.MaxAttempts }},
+		{env: "WEBHOOK_INITIAL_BACKOFF", flag: "webhook-initial-backoff", usage: "wait before the first webhook retry",
+			field: func(c *Config) interface{} { return &c.Webhooks.InitialBackoff }},
+		{env: "WEBHOOK_MAX_BACKOFF", flag: "webhook-max-backoff", usage: "longest wait between webhook retries",
+			field: func(c *Config) interface{} { return &c.Webhooks.MaxBackoff }},
+		{env: "WEBHOOK_POLL_INTERVAL", flag: "webhook-poll-interval", usage: "how often due webhook deliveries are picked up",
+			field: func(c *Config) interface{} { return &c.Webhooks.PollInterval }},
+		{env: "WEBHOOK_BATCH_SIZE", flag: "webhook-batch-size", usage: "webhook deliveries attempted concurrently per round",
+			field: func(c *Config) interface{} { return &c.Webhooks.BatchSize }},
+
+		{env: "STREAM_HEARTBEAT_INTERVAL", flag: "stream-heartbeat-interval", usage: "idle time before a stream heartbeat is sent",
+			field: func(c *Config) interface{} { return &c.Stream.HeartbeatInterval }},
+		{env: "STREAM_REPLAY_LIMIT", flag: "stream-replay-limit", usage: "most missed events replayed to a reconnecting client",
+			field: func(c *Config) interface{} { return &c.Stream.ReplayLimit }},
+		{env: "STREAM_CLIENT_BUFFER", flag: "stream-client-buffer", usage: "events queued for a slow stream client before it is dropped",
+			field: func(c *Config) interface{} { return &c.Stream.ClientBuffer }},
+		{env: "STREAM_ALLOWED_ORIGINS", flag: "stream-allowed-origins", usage: "comma-separated origins allowed to open WebSockets",
+			field: func(c *Config) interface{} { return &c.Stream.AllowedOrigins }},
+
+		{env: "BLOB_BACKEND", flag: "blob-backend", usage: "where uploaded files are stored: filesystem",
+			field: func(c *Config) interface{} { return &c.Blob.Backend }},
+		{env: "BLOB_DIR", flag: "blob-dir", usage: "directory of the filesystem blob store",
+			field: func(c *Config) interface{} { return &c.Blob.Dir }},
+
+		{env: "IMAGE_MAX_UPLOAD_SIZE", flag: "image-max-upload-size", usage: "largest accepted image upload in bytes",
+			field: func(c *Config) interface{} { return &c.Images.MaxUploadSize }},
+		{env: "IMAGE_MAX_PER_CAR", flag: "image-max-per-car", usage: "most images a car may have",
+			field: func(c *Config) interface{} { return &c.Images.MaxPerCar }},
+		{env: "IMAGE_THUMBNAIL_WIDTH", flag: "image-thumbnail-width", usage: "width of generated thumbnails in pixels",
+			field: func(c *Config) interface{} { return &c.Images.ThumbnailWidth }},
+		{env: "IMAGE_URL_TTL", flag: "image-url-ttl", usage: "how long signed image URLs stay valid",
+			field: func(c *Config) interface{} { return &c.Images.URLTTL }},
+		{env: "IMAGE_URL_SIGNING_KEY", secret: true,
+			field: func(c *Config) interface{} { return &c.Images.URLSigningKey }},
+
+		{env: "DOCUMENT_MAX_UPLOAD_SIZE", flag: "document-max-upload-size", usage: "largest accepted document upload in bytes",
+			field: func(c *Config) interface{} { return &c.Documents.MaxUploadSize }},
+		{env: "DOCUMENT_MAX_PER_CAR", flag: "document-max-per-car", usage: "most documents a car may have",
+			field: func(c *Config) interface{} { return &c.Documents.MaxPerCar }},
+		{env: "DOCUMENT_READ_ROLES", flag: "document-read-roles", usage: "comma-separated roles allowed to list and download documents",
+			field: func(c *Config) interface{} { return &c.Documents.ReadRoles }},
+		{env: "DOCUMENT_WRITE_ROLES", flag: "document-write-roles", usage: "comma-separated roles allowed to upload and delete documents",
+			field: func(c *Config) interface{} { return &c.Documents.WriteRoles }},
+
+		{env: "CURRENCY_BASE", flag: "currency-base", usage: "ISO 4217 currency exchange rates are given against",
+			field: func(c *Config) interface{} { return &c.Currency.Base }},
+
+		{env: "FUEL_TYPE_CACHE_TTL", flag: "fuel-type-cache-ttl", usage: "how long the fuel type registry is cached",
+			field: func(c *Config) interface{} { return &c.FuelTypes.CacheTTL }},
+
+		{env: "ALERT_NOTIFIER", flag: "alert-notifier", usage: "how saved search alerts are sent: log, smtp or webhook",
+			field: func(c *Config) interface{} { return &c.Alerts.Notifier }},
+		{env: "ALERT_SMTP_ADDR", flag: "alert-smtp-addr", usage: "host:port of the SMTP server alerts are sent through",
+			field: func(c *Config) interface{} { return &c.Alerts.SMTPAddr }},
+		{env: "ALERT_SMTP_FROM", flag: "alert-smtp-from", usage: "sender address of alert emails",
+			field: func(c *Config) interface{} { return &c.Alerts.SMTPFrom }},
+		{env: "ALERT_SMTP_USERNAME", flag: "alert-smtp-username", usage: "SMTP user, if the server requires one",
+			field: func(c *Config) interface{} { return &c.Alerts.SMTPUsername }},
+		{env: "ALERT_SMTP_PASSWORD", secret: true,
+			field: func(c *Config) interface{} { return &c.Alerts.SMTPPassword }},
+		{env: "ALERT_WEBHOOK_URL", flag: "alert-webhook-url", usage: "URL the webhook notifier posts digests to",
+			field: func(c *Config) interface{} { return &c.Alerts.WebhookURL }},
+		{env: "ALERT_WEBHOOK_TIMEOUT", flag: "alert-webhook-timeout", usage: "timeout for each alert webhook request",
+			field: func(c *Config) interface{} { return &c.Alerts.WebhookTimeout }},
+		{env: "ALERT_POLL_INTERVAL", flag: "alert-poll-interval", usage: "how often due alert digests are picked up",
+			field: func(c *Config) interface{} { return &c.Alerts.PollInterval }},
+		{env: "ALERT_BATCH_SIZE", flag: "alert-batch-size", usage: "alert digests sent per round",
+			field: func(c *Config) interface{} { return &c.Alerts.BatchSize }},
+		{env: "ALERT_RETRY_INTERVAL", flag: "alert-retry-interval", usage: "wait before a failed alert digest is sent again",
+			field: func(c *Config) interface{} { return &c.Alerts.RetryInterval }},
+		{env: "ALERT_MAX_SEARCHES_PER_USER", flag: "alert-max-searches-per-user", usage: "most saved searches a user may have",
+			field: func(c *Config) interface{} { return &c.Alerts.MaxSearchesPerUser }},
+
+		{env: "SIMILAR_WEIGHT_BRAND", flag: "similar-weight-brand", usage: "weight of a different brand in similar car rankings",
+			field: func(c *Config) interface{} { return &c.Similar.Weights.Brand }},
+		{env: "SIMILAR_WEIGHT_FUEL_TYPE", flag: "similar-weight-fuel-type", usage: "weight of a different fuel type in similar car rankings",
+			field: func(c *Config) interface{} { return &c.Similar.Weights.FuelType }},
+		{env: "SIMILAR_WEIGHT_YEAR", flag: "similar-weight-year", usage: "weight of the year gap in similar car rankings",
+			field: func(c *Config) interface{} { return &c.Similar.Weights.Year }},
+		{env: "SIMILAR_WEIGHT_PRICE", flag: "similar-weight-price", usage: "weight of the price gap in similar car rankings",
+			field: func(c *Config) interface{} { return &c.Similar.Weights.Price }},
+		{env: "SIMILAR_WEIGHT_ENGINE", flag: "similar-weight-engine", usage: "weight of engine differences in similar car rankings",
+			field: func(c *Config) interface{} { return &c.Similar.Weights.Engine }},
+		{env: "SIMILAR_YEAR_SPAN", flag: "similar-year-span", usage: "years apart at which cars are entirely unlike in year",
+			field: func(c *Config) interface{} { return &c.Similar.YearSpan }},
+		{env: "SIMILAR_PRICE_BAND", flag: "similar-price-band", usage: "fraction of the price at which cars are entirely unlike in price",
+			field: func(c *Config) interface{} { return &c.Similar.PriceBand }},
+		{env: "SIMILAR_LIMIT", flag: "similar-limit", usage: "similar cars returned by default",
+			field: func(c *Config) interface{} { return &c.Similar.Limit }},
+		{env: "SIMILAR_CACHE_TTL", flag: "similar-cache-ttl", usage: "how long similar car rankings are cached",
+			field: func(c *Config) interface{} { return &c.Similar.CacheTTL }},
+		{env: "SIMILAR_CACHE_SIZE", flag: "similar-cache-size", usage: "most similar car rankings kept in the cache",
+			field: func(c *Config) interface{} { return &c.Similar.CacheSize }},
+	}
+}
+
+func loadEnv(cfg *Config) error {
+	for _, b := range bindings() {
+		value, ok, err := lookupEnv(b)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		if err := setField(b.field(cfg), value); err != nil {
+			return fmt.Errorf("%s: %w", b.env, err)
+		}
+	}
+
+	return nil
+}
+
+// lookupEnv reads the binding's variable, falling back to the file named by
+// <env>_FILE for secrets.
+func lookupEnv(b binding) (string, bool, error) {
+	if value, ok := os.LookupEnv(b.env); ok {
+		return value, true, nil
+	}
+
+	if !b.secret {
+		return "", false, nil
+	}
+
+	path, ok := os.LookupEnv(b.env + "_FILE")
+	if !ok {
+		return "", false, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s_FILE: %w", b.env, err)
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// registerFlags adds a flag for every non-secret binding and returns the
+// functions that apply each flag to a Config once it is known to be set.
+func registerFlags(fs *flag.FlagSet) map[string]func(*Config) {
+	overrides := make(map[string]func(*Config))
+
+	for _, b := range bindings() {
+		if b.flag == "" {
+			continue
+		}
+
+		value := new(string)
+		usage := fmt.Sprintf("%s (env %s)", b.usage, b.env)
+		parse := func(s string) error {
+			if err := setField(b.field(&Config{}), s); err != nil {
+				return err
+			}
+			*value = s
+			return nil
+		}
+
+		if _, isBool := b.field(&Config{}).(*bool); isBool {
+			fs.BoolFunc(b.flag, usage, parse)
+		} else {
+			fs.Func(b.flag, usage, parse)
+		}
+
+		overrides[b.flag] = func(c *Config) {
+			// Already validated by the flag parser above.
+			_ = setField(b.field(c), *value)
+		}
+	}
+
+	return overrides
+}
+
+func setField(field interface{}, value string) error {
+	switch f := field.(type) {
+	case *string:
+		*f = value
+	case *Secret:
+		*f = Secret(value)
+	case *[]Secret:
+		*f = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*f = append(*f, Secret(item))
+			}
+		}
+	case *[]string:
+		*f = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*f = append(*f, item)
+			}
+		}
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*f = n
+	case *float64:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*f = n
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*f = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*f = d
+	default:
+		return fmt.Errorf("unsupported config field type %T", field)
+	}
+
+	return nil
+}
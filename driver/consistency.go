@@ -0,0 +1,50 @@
+package driver
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type primaryKey struct{}
+
+type trackerKey struct{}
+
+// WithPrimaryReads makes every lookup made with the returned context go to
+// the primary. It is used for clients that wrote recently, so they read
+// their own writes even while the replicas catch up.
+func WithPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// WriteTracker records whether anything was written with a context returned
+// by TrackWrites.
+type WriteTracker struct {
+	written atomic.Bool
+}
+
+// TrackWrites returns a context whose writes are recorded on the returned
+// tracker. Once something has been written, lookups made with the context
+// go to the primary.
+func TrackWrites(ctx context.Context) (context.Context, *WriteTracker) {
+	tracker := &WriteTracker{}
+	return context.WithValue(ctx, trackerKey{}, tracker), tracker
+}
+
+func (t *WriteTracker) Written() bool {
+	return t.written.Load()
+}
+
+func markWrite(ctx context.Context) {
+	if tracker, ok := ctx.Value(trackerKey{}).(*WriteTracker); ok {
+		tracker.written.Store(true)
+	}
+}
+
+func readsFromPrimary(ctx context.Context) bool {
+	if primary, _ := ctx.Value(primaryKey{}).(bool); primary {
+		return true
+	}
+
+	tracker, ok := ctx.Value(trackerKey{}).(*WriteTracker)
+	return ok && tracker.Written()
+}
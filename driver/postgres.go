@@ -1,60 +1,104 @@
 package driver
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
-	"os"
+	"sync/atomic"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	_ "github.com/lib/pq"
+	"github.com/michgboxy2/carzone/config"
 )
 
-var db *sql.DB
+// DB is the service's connection pool. It embeds *sql.DB, the primary, so it
+// can be used wherever the standard library pool is expected. Lookups made
+// through Reader may be served by a read replica.
+type DB struct {
+	*sql.DB
 
-func InitDB() {
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-	)
-
-	fmt.Println("Waiting for the database startup...")
-	time.Sleep(5 * time.Second)
+	replicas      []*replica
+	next          atomic.Uint64
+	maxLag        time.Duration
+	checkInterval time.Duration
+}
 
-	var err error
-	db, err = sql.Open("postgres", connStr) // Ensure the driver name is "postgres"
+// Open creates the pool and waits for the database to accept connections,
+// retrying with exponential backoff until cfg.ConnectTimeout has passed or
+// ctx is cancelled.
+func Open(ctx context.Context, cfg config.Database) (*DB, error) {
+	sqlDB, err := sql.Open("postgres", cfg.DSN()) // Ensure the driver name is "postgres"
 
 	if err != nil {
-		log.Fatalf("Error Opening database: %v", err)
-		return // Exit the function if there is an error
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	policy := backoff.NewExponentialBackOff()
+	policy.InitialInterval = 500 * time.Millisecond
+	policy.MaxInterval = 5 * time.Second
+	policy.MaxElapsedTime = cfg.ConnectTimeout
+
+	ping := func() error {
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		return sqlDB.PingContext(pingCtx)
+	}
+
+	notify := func(err error, wait time.Duration) {
+		log.Printf("database not ready (%v), retrying in %s", err, wait.Round(time.Millisecond))
 	}
 
-	err = db.Ping()
+	if err := backoff.RetryNotify(ping, backoff.WithContext(policy, ctx), notify); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("error connecting to the database after %s: %w", cfg.ConnectTimeout, err)
+	}
+
+	log.Println("Successfully connected to the database")
+
+	replicas, err := openReplicas(cfg)
 	if err != nil {
-		log.Fatalf("Error Connecting to the database: %v", err)
-		return // Exit the function if there is an error
+		sqlDB.Close()
+		return nil, err
 	}
 
-	fmt.Println("Successfully connected to the database")
-}
+	db := &DB{
+		DB:            sqlDB,
+		replicas:      replicas,
+		maxLag:        cfg.MaxReplicationLag,
+		checkInterval: cfg.ReplicaCheckInterval,
+	}
 
-func GetDB() *sql.DB {
-	if db == nil {
-		log.Println("Warning: Attempting to access a nil database connection.")
-		return nil // Return nil if the database connection is not initialized
+	// Replicas are not waited for: until one passes a check, reads go to
+	// the primary.
+	if len(replicas) > 0 {
+		db.checkReplicas(ctx)
+		for _, status := range db.Replicas() {
+			if !status.Healthy {
+				log.Printf("replica %s not in rotation: %s", status.Name, status.Error)
+			}
+		}
 	}
-	return db // Return the initialized database connection
+
+	return db, nil
 }
 
-func CloseDB() {
-	if db != nil {
-		if err := db.Close(); err != nil {
-			log.Fatalf("Error Closing The Database: %v", err)
+func (d *DB) Close() error {
+	for _, r := range d.replicas {
+		if err := r.db.Close(); err != nil {
+			log.Printf("error closing %s: %v", r.name, err)
 		}
-	} else {
-		log.Println("Database connection is nil, nothing to close.")
 	}
+
+	if err := d.DB.Close(); err != nil {
+		return fmt.Errorf("error closing the database: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,208 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/michgboxy2/carzone/config"
+)
+
+// lagQuery reports how far a replica is behind the primary. A replica that
+// has replayed everything it received is not lagging even if the last
+// replayed transaction is old, which is the case on an idle primary. A
+// server that is not in recovery is a primary and never lags.
+const lagQuery = `
+	SELECT CASE
+		WHEN NOT pg_is_in_recovery() THEN 0
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END`
+
+type ReplicaStatus struct {
+	Name      string        `json:"name"`
+	Healthy   bool          `json:"healthy"`
+	Lag       time.Duration `json:"lag"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Error     string        `json:"error,omitempty"`
+}
+
+type replica struct {
+	name string
+	db   *sql.DB
+
+	mu     sync.RWMutex
+	status ReplicaStatus
+}
+
+func (r *replica) healthy() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.status.Healthy
+}
+
+func (r *replica) setStatus(status ReplicaStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if status.Healthy != r.status.Healthy && !r.status.CheckedAt.IsZero() {
+		if status.Healthy {
+			log.Printf("replica %s is back in rotation", r.name)
+		} else {
+			log.Printf("replica %s taken out of rotation: %s", r.name, status.Error)
+		}
+	}
+
+	r.status = status
+}
+
+// markDown takes the replica out of rotation after a connection error seen
+// by a query, without waiting for the next check to notice.
+func (r *replica) markDown(err error) {
+	r.setStatus(ReplicaStatus{Name: r.name, CheckedAt: time.Now(), Error: err.Error()})
+}
+
+func openReplicas(cfg config.Database) ([]*replica, error) {
+	replicas := make([]*replica, 0, len(cfg.Replicas))
+
+	for i, dsn := range cfg.Replicas {
+		sqlDB, err := sql.Open("postgres", dsn.Value())
+		if err != nil {
+			for _, r := range replicas {
+				r.db.Close()
+			}
+			return nil, fmt.Errorf("error opening replica %d: %w", i, err)
+		}
+
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+		sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+		name := fmt.Sprintf("replica-%d", i)
+		replicas = append(replicas, &replica{name: name, db: sqlDB, status: ReplicaStatus{Name: name}})
+	}
+
+	return replicas, nil
+}
+
+// Reader returns where a lookup should run: the transaction carried by ctx,
+// the primary when ctx asks for it or has already written, or else the next
+// healthy replica. With no healthy replica it falls back to the primary.
+func (d *DB) Reader(ctx context.Context) Querier {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx
+	}
+
+	if len(d.replicas) == 0 || readsFromPrimary(ctx) {
+		return d.DB
+	}
+
+	start := d.next.Add(1)
+	for i := range d.replicas {
+		r := d.replicas[(start+uint64(i))%uint64(len(d.replicas))]
+		if r.healthy() {
+			return replicaConn{r}
+		}
+	}
+
+	return d.DB
+}
+
+// Replicas returns the last known status of every replica.
+func (d *DB) Replicas() []ReplicaStatus {
+	statuses := make([]ReplicaStatus, len(d.replicas))
+	for i, r := range d.replicas {
+		r.mu.RLock()
+		statuses[i] = r.status
+		r.mu.RUnlock()
+	}
+	return statuses
+}
+
+// MonitorReplicas checks every replica's reachability and lag at the
+// configured interval until ctx is cancelled. Replicas lagging more than
+// the configured tolerance are taken out of rotation until they catch up.
+func (d *DB) MonitorReplicas(ctx context.Context) {
+	ticker := time.NewTicker(d.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.checkReplicas(ctx)
+		}
+	}
+}
+
+func (d *DB) checkReplicas(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, r := range d.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.setStatus(d.checkReplica(ctx, r))
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (d *DB) checkReplica(ctx context.Context, r *replica) ReplicaStatus {
+	ctx, cancel := context.WithTimeout(ctx, d.checkInterval)
+	defer cancel()
+
+	status := ReplicaStatus{Name: r.name, CheckedAt: time.Now()}
+
+	var seconds float64
+	if err := r.db.QueryRowContext(ctx, lagQuery).Scan(&seconds); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	status.Lag = time.Duration(seconds * float64(time.Second))
+
+	if status.Lag > d.maxLag {
+		status.Error = fmt.Sprintf("lagging %s behind the primary, above %s", status.Lag.Round(time.Millisecond), d.maxLag)
+		return status
+	}
+
+	status.Healthy = true
+	return status
+}
+
+// replicaConn runs lookups on a replica and takes it out of rotation as soon
+// as it fails with a connection-level error, so a retry goes elsewhere.
+type replicaConn struct {
+	r *replica
+}
+
+func (c replicaConn) check(err error) {
+	if err != nil && IsTransient(err) {
+		c.r.markDown(err)
+	}
+}
+
+func (c replicaConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := c.r.db.ExecContext(ctx, query, args...)
+	c.check(err)
+	return result, err
+}
+
+func (c replicaConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := c.r.db.QueryContext(ctx, query, args...)
+	c.check(err)
+	return rows, err
+}
+
+func (c replicaConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := c.r.db.QueryRowContext(ctx, query, args...)
+	c.check(row.Err())
+	return row
+}
@@ -0,0 +1,122 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/lib/pq"
+	"github.com/michgboxy2/carzone/config"
+)
+
+// Retrier re-runs database operations that failed for transient reasons.
+type Retrier struct {
+	attempts        int
+	initialInterval time.Duration
+	maxInterval     time.Duration
+}
+
+func NewRetrier(cfg config.Database) *Retrier {
+	return &Retrier{
+		attempts:        cfg.RetryAttempts,
+		initialInterval: cfg.RetryInitialInterval,
+		maxInterval:     cfg.RetryMaxInterval,
+	}
+}
+
+// Read retries op on any transient error. Use it only for operations that
+// are safe to repeat.
+func (r *Retrier) Read(ctx context.Context, op func() error) error {
+	return r.do(ctx, op, IsTransient)
+}
+
+// Write retries op only when the database guarantees nothing was committed:
+// serialization failures, deadlocks and connections that were refused before
+// the statement was sent. A connection lost mid-commit is not retried since
+// the write may already have been applied.
+func (r *Retrier) Write(ctx context.Context, op func() error) error {
+	return r.do(ctx, op, IsRetryableWrite)
+}
+
+func (r *Retrier) do(ctx context.Context, op func() error, retryable func(error) bool) error {
+	if r == nil || r.attempts <= 0 {
+		return op()
+	}
+
+	policy := backoff.NewExponentialBackOff()
+	policy.InitialInterval = r.initialInterval
+	policy.MaxInterval = r.maxInterval
+	policy.MaxElapsedTime = 0
+
+	attempt := func() error {
+		err := op()
+		if err != nil && !retryable(err) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}
+
+	return backoff.Retry(attempt, backoff.WithContext(backoff.WithMaxRetries(policy, uint64(r.attempts)), ctx))
+}
+
+// IsTransient reports whether err is likely to go away if the operation is
+// simply run again. A cancelled or timed-out context is the caller giving
+// up, not the database failing, so it never is.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// context.DeadlineExceeded is also a net.Error.
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if IsRetryableWrite(err) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Class 08: connection exceptions; 57P01/57P02: server shutting down.
+		return pqErr.Code.Class() == "08" || pqErr.Code == "57P01" || pqErr.Code == "57P02"
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// IsRetryableWrite reports whether err guarantees the failed transaction had
+// no effect, so repeating it cannot apply a write twice.
+func IsRetryableWrite(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "40001", // serialization_failure
+			"40P01", // deadlock_detected
+			"53300", // too_many_connections
+			"57P03": // cannot_connect_now
+			return true
+		}
+		return false
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,43 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "bad connection", err: driver.ErrBadConn, want: true},
+		{name: "serialization failure", err: &pq.Error{Code: "40001"}, want: true},
+		{name: "connection exception", err: &pq.Error{Code: "08006"}, want: true},
+		{name: "admin shutdown", err: &pq.Error{Code: "57P01"}, want: true},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}, want: false},
+		{name: "dial error", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{name: "read error", err: &net.OpError{Op: "read", Err: errors.New("connection reset")}, want: true},
+		{name: "unexpected EOF", err: fmt.Errorf("reading row: %w", io.ErrUnexpectedEOF), want: true},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, want: false},
+		{name: "wrapped deadline exceeded", err: fmt.Errorf("query: %w", context.DeadlineExceeded), want: false},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "other", err: errors.New("syntax error"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Fatalf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
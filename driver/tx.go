@@ -0,0 +1,163 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Querier is the subset of *sql.DB and *sql.Tx the stores use, so the same
+// store code runs inside or outside a transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type txKey struct{}
+
+// txState is what WithinTx puts in the context: the transaction itself and
+// the hooks to run once it has committed.
+type txState struct {
+	tx          *sql.Tx
+	afterCommit []func()
+	savepoints  int
+}
+
+func txFromContext(ctx context.Context) *sql.Tx {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.tx
+	}
+	return nil
+}
+
+// AfterCommit runs fn once the transaction carried by ctx has committed, and
+// never if it rolls back. Outside a transaction fn runs straight away. It is
+// meant for side effects such as cache invalidation that must not be
+// observed before the data they describe.
+func AfterCommit(ctx context.Context, fn func()) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// InTx reports whether ctx carries a transaction.
+func InTx(ctx context.Context) bool {
+	return txFromContext(ctx) != nil
+}
+
+// Conn returns the transaction carried by ctx, or the primary when there is
+// none. Use it for writes and for reads that must see the latest data; the
+// request is then treated as having written (see TrackWrites).
+func (d *DB) Conn(ctx context.Context) Querier {
+	markWrite(ctx)
+
+	if tx := txFromContext(ctx); tx != nil {
+		return tx
+	}
+	return d.DB
+}
+
+// WithinTx runs fn with a context carrying a transaction. If ctx already
+// carries one, fn joins it and the outermost caller decides whether to
+// commit. Otherwise a new transaction is started and committed when fn
+// returns nil, or rolled back when it returns an error or panics.
+func (d *DB) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if InTx(ctx) {
+		return fn(ctx)
+	}
+
+	markWrite(ctx)
+
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	state := &txState{tx: tx}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+			}
+			return
+		}
+
+		if err = tx.Commit(); err != nil {
+			return
+		}
+
+		for _, hook := range state.afterCommit {
+			hook()
+		}
+	}()
+
+	return fn(context.WithValue(ctx, txKey{}, state))
+}
+
+// Savepoint runs fn under a savepoint of the transaction carried by ctx, so
+// an error from fn undoes only fn's writes and leaves the transaction usable
+// for the rest of the work. AfterCommit hooks added by fn are dropped along
+// with its writes. Without a transaction in ctx it is WithinTx.
+func (d *DB) Savepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		return d.WithinTx(ctx, fn)
+	}
+
+	state.savepoints++
+	name := fmt.Sprintf("sp_%d", state.savepoints)
+	hooks := len(state.afterCommit)
+
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	if err := fn(ctx); err != nil {
+		state.afterCommit = state.afterCommit[:hooks]
+		if _, rbErr := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	_, err := state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// TxManager starts units of work that span several stores. The whole unit is
+// retried when it fails for a reason that guarantees nothing was committed.
+type TxManager struct {
+	db      *DB
+	retrier *Retrier
+}
+
+func NewTxManager(db *DB, retrier *Retrier) *TxManager {
+	return &TxManager{db: db, retrier: retrier}
+}
+
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if InTx(ctx) {
+		return fn(ctx)
+	}
+
+	return m.retrier.Write(ctx, func() error {
+		return m.db.WithinTx(ctx, fn)
+	})
+}
+
+func (m *TxManager) Savepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !InTx(ctx) {
+		return m.WithinTx(ctx, fn)
+	}
+
+	return m.db.Savepoint(ctx, fn)
+}
@@ -0,0 +1,281 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/m3db/prometheus_client_golang/prometheus"
+	"github.com/michgboxy2/carzone/config"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+)
+
+var (
+	publishedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_events_published_total",
+			Help: "Total number of outbox events published",
+		},
+		[]string{"type"},
+	)
+
+	failedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_publish_failures_total",
+			Help: "Total number of failed attempts to publish an outbox event",
+		},
+		[]string{"type"},
+	)
+
+	parkedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_events_parked_total",
+			Help: "Total number of outbox events parked after running out of attempts",
+		},
+		[]string{"type"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(publishedCounter, failedCounter, parkedCounter)
+}
+
+// cleanupInterval is how often published events past the retention period
+// are deleted.
+const cleanupInterval = time.Hour
+
+// Relay moves events from the outbox to a sink. Instances take turns to
+// claim a batch, so events leave in the order they were written. When an
+// event fails, later events for the same aggregate are held back until it
+// goes through, while other aggregates carry on. A failed event is retried
+// with backoff and parked after cfg.MaxAttempts, after which its aggregate
+// stays held back until the event is requeued. Delivery is at least once: an
+// event is marked published only after the sinks accepted it.
+//
+// The sink is published to outside any database transaction, so a slow
+// sink holds neither locks nor a connection, and a transaction that is
+// retried never sends events again. The local sinks, such as the webhook
+// fanout and the search matcher, write to the database and run in the
+// transaction that marks their events published.
+type Relay struct {
+	tx     store.Transactor
+	outbox store.OutboxStoreInterface
+	sink   Sink
+	local  Sink
+	cfg    config.Events
+}
+
+func NewRelay(tx store.Transactor, outbox store.OutboxStoreInterface, sink, local Sink, cfg config.Events) *Relay {
+	return &Relay{tx: tx, outbox: outbox, sink: sink, local: local, cfg: cfg}
+}
+
+// Run relays until ctx is cancelled. Full batches are followed straight away
+// by the next one; otherwise the relay waits for the next tick.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.RelayInterval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+
+	for {
+		published, err := r.relayBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("outbox relay: %v", err)
+		}
+
+		if time.Since(lastCleanup) > cleanupInterval {
+			lastCleanup = time.Now()
+			r.cleanup(ctx)
+		}
+
+		if err == nil && published == r.cfg.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	deadline := time.Now().Add(r.cfg.PublishTimeout)
+
+	pending, err := r.claim(ctx, deadline)
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+
+	sent, failed := r.publish(ctx, pending, deadline)
+
+	var published []int64
+
+	err = r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		published = published[:0]
+
+		blocked := make(map[string]bool)
+		var unsent []int64
+
+		for _, event := range pending {
+			key := event.OrderingKey()
+
+			if blocked[key] {
+				unsent = append(unsent, event.Sequence)
+				continue
+			}
+
+			if err, ok := failed[event.Sequence]; ok {
+				blocked[key] = true
+				if err := r.fail(ctx, event, err); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if !sent[event.Sequence] {
+				unsent = append(unsent, event.Sequence)
+				continue
+			}
+
+			// A failed write would abort the transaction, losing the
+			// failure record and every event already published in the
+			// batch, so each event's writes are undone on their own instead.
+			err := r.tx.Savepoint(ctx, func(ctx context.Context) error {
+				return r.local.Publish(ctx, event)
+			})
+			if err != nil {
+				blocked[key] = true
+				if err := r.fail(ctx, event, err); err != nil {
+					return err
+				}
+				continue
+			}
+
+			publishedCounter.WithLabelValues(event.Type).Inc()
+			published = append(published, event.Sequence)
+		}
+
+		if err := r.outbox.Release(ctx, unsent); err != nil {
+			return err
+		}
+
+		return r.outbox.MarkPublished(ctx, published)
+	})
+
+	return len(published), err
+}
+
+// claim returns the next batch, claimed until well after deadline so that
+// no other instance publishes it meanwhile. It returns nothing while another
+// instance is claiming.
+func (r *Relay) claim(ctx context.Context, deadline time.Time) ([]models.Event, error) {
+	var pending []models.Event
+
+	err := r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		pending = nil
+
+		locked, err := r.outbox.TryLock(ctx)
+		if err != nil || !locked {
+			return err
+		}
+
+		pending, err = r.outbox.Pending(ctx, r.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		sequences := make([]int64, len(pending))
+		for i, event := range pending {
+			sequences[i] = event.Sequence
+		}
+
+		// The claim outlasts the deadline by as long again, leaving time to
+		// record what was published.
+		return r.outbox.Claim(ctx, sequences, deadline.Add(r.cfg.PublishTimeout))
+	})
+
+	return pending, err
+}
+
+// publish sends events to the sink in order until deadline. It returns the
+// events the sink accepted and the errors of those it did not; events it
+// held back or did not get to are in neither.
+func (r *Relay) publish(ctx context.Context, pending []models.Event, deadline time.Time) (map[int64]bool, map[int64]error) {
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	sent := make(map[int64]bool)
+	failed := make(map[int64]error)
+	blocked := make(map[string]bool)
+
+	for _, event := range pending {
+		key := event.OrderingKey()
+		if blocked[key] {
+			continue
+		}
+
+		if err := r.sink.Publish(ctx, event); err != nil {
+			// Running out of time is not the event's fault; it and the
+			// rest wait for the next batch.
+			if ctx.Err() != nil {
+				break
+			}
+
+			blocked[key] = true
+			failed[event.Sequence] = err
+			continue
+		}
+
+		sent[event.Sequence] = true
+	}
+
+	return sent, failed
+}
+
+// fail records a failed publish of event, to be retried after a backoff or
+// parked once it is out of attempts.
+func (r *Relay) fail(ctx context.Context, event models.Event, err error) error {
+	failedCounter.WithLabelValues(event.Type).Inc()
+	log.Printf("outbox relay: publishing event %d (%s): %v", event.Sequence, event.Type, err)
+
+	attempts := event.Attempts + 1
+
+	var retryAt *time.Time
+	if attempts < r.cfg.MaxAttempts {
+		at := time.Now().Add(r.backoff(attempts))
+		retryAt = &at
+	} else {
+		parkedCounter.WithLabelValues(event.Type).Inc()
+		log.Printf("outbox relay: parked event %d (%s) after %d attempts; %s %s is held back until it is requeued",
+			event.Sequence, event.Type, attempts, event.AggregateType, event.AggregateID)
+	}
+
+	return r.outbox.MarkFailed(ctx, event.Sequence, err.Error(), retryAt)
+}
+
+// backoff returns the wait before retrying an event that has failed the
+// given number of times: the relay interval doubled for every earlier
+// failure, capped at cfg.MaxBackoff.
+func (r *Relay) backoff(attempts int) time.Duration {
+	wait := r.cfg.RelayInterval
+	for i := 1; i < attempts && wait < r.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+
+	return min(wait, r.cfg.MaxBackoff)
+}
+
+func (r *Relay) cleanup(ctx context.Context) {
+	deleted, err := r.outbox.DeletePublishedBefore(ctx, time.Now().Add(-r.cfg.Retention))
+	if err != nil {
+		log.Printf("outbox cleanup: %v", err)
+		return
+	}
+
+	if deleted > 0 {
+		log.Printf("outbox cleanup: removed %d published events", deleted)
+	}
+}
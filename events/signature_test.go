@@ -0,0 +1,95 @@
+package events
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	const secret = "0123456789abcdef"
+	body := []byte(`{"type":"car.created"}`)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		header  string
+		body    []byte
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			header: Sign(secret, now, body),
+			body:   body,
+		},
+		{
+			name:   "valid with a rotated signature alongside",
+			header: Sign("another-secret-value", now, body) + ",v1=" + computeSignature(secret, strconv.FormatInt(now.Unix(), 10), body),
+			body:   body,
+		},
+		{
+			name:    "wrong secret",
+			header:  Sign("another-secret-value", now, body),
+			body:    body,
+			wantErr: "signature mismatch",
+		},
+		{
+			name:    "tampered body",
+			header:  Sign(secret, now, body),
+			body:    []byte(`{"type":"car.deleted"}`),
+			wantErr: "signature mismatch",
+		},
+		{
+			name:    "timestamp too old",
+			header:  Sign(secret, now.Add(-10*time.Minute), body),
+			body:    body,
+			wantErr: "outside tolerance",
+		},
+		{
+			name:    "timestamp in the future",
+			header:  Sign(secret, now.Add(10*time.Minute), body),
+			body:    body,
+			wantErr: "outside tolerance",
+		},
+		{
+			name:    "timestamp changed after signing",
+			header:  strings.Replace(Sign(secret, now, body), "t="+strconv.FormatInt(now.Unix(), 10), "t="+strconv.FormatInt(now.Unix()-1, 10), 1),
+			body:    body,
+			wantErr: "signature mismatch",
+		},
+		{
+			name:    "malformed timestamp",
+			header:  "t=yesterday,v1=" + computeSignature(secret, "yesterday", body),
+			body:    body,
+			wantErr: "malformed signature timestamp",
+		},
+		{
+			name:    "missing signature",
+			header:  "t=" + strconv.FormatInt(now.Unix(), 10),
+			body:    body,
+			wantErr: "malformed signature header",
+		},
+		{
+			name:    "empty header",
+			header:  "",
+			body:    body,
+			wantErr: "malformed signature header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(secret, tt.header, tt.body, 5*time.Minute)
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Verify() = %v, want nil", err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("Verify() = nil, want error containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("Verify() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
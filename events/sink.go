@@ -0,0 +1,163 @@
+// Package events publishes the domain events recorded in the outbox.
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/michgboxy2/carzone/config"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/nats-io/nats.go"
+)
+
+// Sink delivers one event downstream. Publish must only return nil once the
+// event has been accepted; the relay retries it otherwise, so a sink may see
+// the same event more than once and consumers should dedupe on its ID.
+type Sink interface {
+	Publish(ctx context.Context, event models.Event) error
+	Close() error
+}
+
+// NewSink builds the sink selected by cfg.Sink.
+func NewSink(cfg config.Events) (Sink, error) {
+	switch cfg.Sink {
+	case "log":
+		return LogSink{}, nil
+	case "webhook":
+		return NewWebhookSink(cfg.WebhookURL, &http.Client{Timeout: cfg.WebhookTimeout}), nil
+	case "nats":
+		return NewNATSSink(cfg.NATSURL, cfg.NATSSubject)
+	default:
+		return nil, fmt.Errorf("unknown event sink %q", cfg.Sink)
+	}
+}
+
+// LogSink writes events to the service log. It is the default and is
+// useful in development.
+type LogSink struct{}
+
+func (LogSink) Publish(ctx context.Context, event models.Event) error {
+	log.Printf("event %d %s %s: %s", event.Sequence, event.Type, event.OrderingKey(), event.Payload)
+	return nil
+}
+
+func (LogSink) Close() error {
+	return nil
+}
+
+// WebhookSink POSTs each event as JSON to a fixed URL. Any 2xx response
+// counts as delivered.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, client *http.Client) *WebhookSink {
+	return &WebhookSink{url: url, client: client}
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, event models.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", event.ID.String())
+	req.Header.Set("X-Event-Type", event.Type)
+	req.Header.Set("X-Ordering-Key", event.OrderingKey())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (s *WebhookSink) Close() error {
+	return nil
+}
+
+// NATSSink publishes to NATS JetStream on <subject>.<aggregate>.<type>, e.g.
+// carzone.events.car.CarCreated. A stream must be configured to capture the
+// subjects; JetStream acknowledges each message and drops duplicates by
+// event ID within its deduplication window.
+type NATSSink struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	subject string
+}
+
+func NewNATSSink(url, subject string) (*NATSSink, error) {
+	conn, err := nats.Connect(url, nats.Name("carzone"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("connecting to NATS: %w", err)
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("opening JetStream: %w", err)
+	}
+
+	return &NATSSink{conn: conn, js: js, subject: subject}, nil
+}
+
+func (s *NATSSink) Publish(ctx context.Context, event models.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(fmt.Sprintf("%s.%s.%s", s.subject, event.AggregateType, event.Type))
+	msg.Data = data
+	msg.Header.Set("Carzone-Ordering-Key", event.OrderingKey())
+
+	_, err = s.js.PublishMsg(msg, nats.Context(ctx), nats.MsgId(event.ID.String()))
+	return err
+}
+
+func (s *NATSSink) Close() error {
+	return s.conn.Drain()
+}
+
+type multiSink []Sink
+
+// Multi publishes every event to each of sinks in turn. If one fails the
+// event is retried on all of them, so each sink must tolerate duplicates.
+func Multi(sinks ...Sink) Sink {
+	return multiSink(sinks)
+}
+
+func (m multiSink) Publish(ctx context.Context, event models.Event) error {
+	for _, sink := range m {
+		if err := sink.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiSink) Close() error {
+	var errs []error
+	for _, sink := range m {
+		errs = append(errs, sink.Close())
+	}
+	return errors.Join(errs...)
+}
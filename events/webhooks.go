@@ -0,0 +1,210 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/m3db/prometheus_client_golang/prometheus"
+	"github.com/michgboxy2/carzone/config"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+)
+
+var deliveryCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "webhook_delivery_attempts_total",
+		Help: "Total number of webhook delivery attempts by result (delivered, failed or dead)",
+	},
+	[]string{"result"},
+)
+
+func init() {
+	prometheus.MustRegister(deliveryCounter)
+}
+
+// SubscriptionFanout is a Sink that queues a delivery of each event for every
+// webhook subscription interested in it. Running inside the relay's
+// transaction, the deliveries are queued exactly when the event is marked
+// published.
+type SubscriptionFanout struct {
+	store store.WebhookStoreInterface
+}
+
+func NewSubscriptionFanout(store store.WebhookStoreInterface) *SubscriptionFanout {
+	return &SubscriptionFanout{store: store}
+}
+
+func (f *SubscriptionFanout) Publish(ctx context.Context, event models.Event) error {
+	_, err := f.store.EnqueueDeliveries(ctx, event)
+	return err
+}
+
+func (f *SubscriptionFanout) Close() error {
+	return nil
+}
+
+// Dispatcher sends queued webhook deliveries. Failed attempts are retried
+// with exponential backoff and jitter; after cfg.MaxAttempts the delivery is
+// dead-lettered until someone redelivers it. Several instances can dispatch
+// at once, each claiming different deliveries.
+type Dispatcher struct {
+	store  store.WebhookStoreInterface
+	client *http.Client
+	cfg    config.Webhooks
+}
+
+// NewDispatcher uses client for deliveries; pass nil for a client with the
+// configured timeout. Redirects are never followed.
+func NewDispatcher(store store.WebhookStoreInterface, client *http.Client, cfg config.Webhooks) *Dispatcher {
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+
+	c := *client
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Dispatcher{store: store, client: &c, cfg: cfg}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		claimed, err := d.DispatchDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("webhook dispatcher: %v", err)
+		}
+
+		if err == nil && claimed == d.cfg.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue claims the deliveries that are due, attempts them concurrently
+// and records the outcomes. It returns how many it claimed.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	// A claimed delivery is invisible to other dispatchers for the lease,
+	// which comfortably outlasts one attempt.
+	lease := d.cfg.Timeout + time.Minute
+
+	due, err := d.store.ClaimDue(ctx, d.cfg.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.attempt(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+
+	return len(due), nil
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, due models.DueDelivery) {
+	delivery := due.Delivery
+
+	statusCode, err := d.send(ctx, due)
+	if err == nil {
+		deliveryCounter.WithLabelValues("delivered").Inc()
+		if err := d.store.MarkDelivered(ctx, delivery.ID, statusCode); err != nil {
+			log.Printf("webhook delivery %s: recording success: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	// The worker is stopping; the lease expires and the attempt is made
+	// again later without counting this one.
+	if ctx.Err() != nil {
+		return
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+
+	attempts := delivery.Attempts + 1
+
+	var next *time.Time
+	if attempts < d.cfg.MaxAttempts {
+		at := time.Now().Add(d.backoff(attempts))
+		next = &at
+		deliveryCounter.WithLabelValues("failed").Inc()
+	} else {
+		deliveryCounter.WithLabelValues("dead").Inc()
+		log.Printf("webhook delivery %s to %s dead after %d attempts: %v", delivery.ID, due.URL, attempts, err)
+	}
+
+	if err := d.store.MarkFailed(ctx, delivery.ID, code, err.Error(), next); err != nil {
+		log.Printf("webhook delivery %s: recording failure: %v", delivery.ID, err)
+	}
+}
+
+// send posts the delivery and returns the response status, or 0 if there
+// was no response.
+func (d *Dispatcher) send(ctx context.Context, due models.DueDelivery) (int, error) {
+	body := []byte(due.Delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, due.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "carzone-webhooks")
+	req.Header.Set("X-Carzone-Event", due.Delivery.EventType)
+	req.Header.Set("X-Carzone-Event-ID", due.Delivery.EventID.String())
+	req.Header.Set("X-Carzone-Delivery", due.Delivery.ID.String())
+	req.Header.Set(SignatureHeader, Sign(due.Secret, time.Now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver returned %s: %s", resp.Status, bytes.TrimSpace(snippet))
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the wait before the attempt after the given one: the
+// initial interval doubled for every earlier failure, capped, with up to 20%
+// jitter so retries to one receiver do not arrive in bursts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.cfg.InitialBackoff
+	for i := 1; i < attempts && wait < d.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.cfg.MaxBackoff {
+		wait = d.cfg.MaxBackoff
+	}
+
+	return wait + time.Duration(rand.Int63n(int64(wait)/5+1))
+}
@@ -0,0 +1,384 @@
+package events
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/config"
+	"github.com/michgboxy2/carzone/models"
+)
+
+const testSecret = "0123456789abcdef"
+
+// memWebhookStore keeps deliveries in memory the way the Postgres store
+// keeps them in webhook_deliveries. Its clock only moves when advanced, so
+// tests can step over the backoff.
+type memWebhookStore struct {
+	mu         sync.Mutex
+	now        time.Time
+	sub        models.WebhookSubscription
+	deliveries map[uuid.UUID]*models.WebhookDelivery
+}
+
+func newMemWebhookStore(url string) *memWebhookStore {
+	return &memWebhookStore{
+		now:        time.Now(),
+		sub:        models.WebhookSubscription{ID: uuid.New(), URL: url, Secret: testSecret, Active: true},
+		deliveries: make(map[uuid.UUID]*models.WebhookDelivery),
+	}
+}
+
+func (s *memWebhookStore) advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+}
+
+func (s *memWebhookStore) advanceTo(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = t
+}
+
+func (s *memWebhookStore) get(id uuid.UUID) models.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.deliveries[id]
+}
+
+func (s *memWebhookStore) CreateSubscription(ctx context.Context, sub models.WebhookSubscription) (models.WebhookSubscription, error) {
+	return sub, nil
+}
+
+func (s *memWebhookStore) GetSubscription(ctx context.Context, id uuid.UUID) (models.WebhookSubscription, error) {
+	return s.sub, nil
+}
+
+func (s *memWebhookStore) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return []models.WebhookSubscription{s.sub}, nil
+}
+
+func (s *memWebhookStore) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (s *memWebhookStore) EnqueueDeliveries(ctx context.Context, event models.Event) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now
+	delivery := &models.WebhookDelivery{
+		ID:             uuid.New(),
+		SubscriptionID: s.sub.ID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		Payload:        event.Payload,
+		Status:         models.DeliveryPending,
+		NextAttemptAt:  &now,
+	}
+	s.deliveries[delivery.ID] = delivery
+
+	return 1, nil
+}
+
+func (s *memWebhookStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.DueDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []models.DueDelivery
+	for _, delivery := range s.deliveries {
+		if len(due) == limit {
+			break
+		}
+		if delivery.Status != models.DeliveryPending || delivery.NextAttemptAt.After(s.now) {
+			continue
+		}
+
+		next := s.now.Add(lease)
+		delivery.NextAttemptAt = &next
+		due = append(due, models.DueDelivery{Delivery: *delivery, URL: s.sub.URL, Secret: s.sub.Secret})
+	}
+
+	return due, nil
+}
+
+func (s *memWebhookStore) MarkDelivered(ctx context.Context, id uuid.UUID, statusCode int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery := s.deliveries[id]
+	now := s.now
+	delivery.Status = models.DeliveryDelivered
+	delivery.Attempts++
+	delivery.LastStatusCode = &statusCode
+	delivery.NextAttemptAt = nil
+	delivery.DeliveredAt = &now
+
+	return nil
+}
+
+func (s *memWebhookStore) MarkFailed(ctx context.Context, id uuid.UUID, statusCode *int, reason string, next *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery := s.deliveries[id]
+	delivery.Status = models.DeliveryPending
+	if next == nil {
+		delivery.Status = models.DeliveryDead
+	}
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = &reason
+	delivery.NextAttemptAt = next
+
+	return nil
+}
+
+func (s *memWebhookStore) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deliveries []models.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if status == "" || delivery.Status == status {
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (s *memWebhookStore) Redeliver(ctx context.Context, subscriptionID, deliveryID uuid.UUID) (models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, ok := s.deliveries[deliveryID]
+	if !ok || delivery.SubscriptionID != subscriptionID {
+		return models.WebhookDelivery{}, models.ErrDeliveryNotFound
+	}
+
+	now := s.now
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+
+	return *delivery, nil
+}
+
+// receiver is a webhook endpoint answering with the status codes it is
+// given, in turn, and with the last one once they run out.
+type receiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	calls    atomic.Int32
+}
+
+func (rc *receiver) setStatuses(statuses ...int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.statuses = statuses
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.calls.Add(1)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rc.t.Errorf("reading body: %v", err)
+	}
+
+	if err := Verify(testSecret, r.Header.Get(SignatureHeader), body, time.Minute); err != nil {
+		rc.t.Errorf("signature of delivery %s: %v", r.Header.Get("X-Carzone-Delivery"), err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	rc.mu.Lock()
+	status := rc.statuses[0]
+	if len(rc.statuses) > 1 {
+		rc.statuses = rc.statuses[1:]
+	}
+	rc.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func setupDispatcher(t *testing.T, statuses ...int) (*Dispatcher, *memWebhookStore, *receiver, uuid.UUID) {
+	t.Helper()
+
+	rc := &receiver{t: t, statuses: statuses}
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	store := newMemWebhookStore(server.URL)
+	cfg := config.Webhooks{
+		Timeout:        5 * time.Second,
+		MaxAttempts:    4,
+		InitialBackoff: time.Minute,
+		MaxBackoff:     3 * time.Minute,
+		PollInterval:   time.Second,
+		BatchSize:      10,
+	}
+
+	event := models.Event{ID: uuid.New(), Type: models.EventCarCreated, Payload: []byte(`{"id":"car"}`)}
+	if _, err := store.EnqueueDeliveries(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, _ := store.ListDeliveries(context.Background(), store.sub.ID, "", 1)
+
+	return NewDispatcher(store, server.Client(), cfg), store, rc, deliveries[0].ID
+}
+
+func dispatch(t *testing.T, d *Dispatcher, want int) {
+	t.Helper()
+
+	claimed, err := d.DispatchDue(context.Background())
+	if err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+	if claimed != want {
+		t.Fatalf("DispatchDue claimed %d deliveries, want %d", claimed, want)
+	}
+}
+
+func TestDispatcherSignsDeliveries(t *testing.T) {
+	d, store, rc, id := setupDispatcher(t, http.StatusNoContent)
+
+	dispatch(t, d, 1)
+
+	delivery := store.get(id)
+	if delivery.Status != models.DeliveryDelivered {
+		t.Fatalf("status = %q, want %q", delivery.Status, models.DeliveryDelivered)
+	}
+	if delivery.LastStatusCode == nil || *delivery.LastStatusCode != http.StatusNoContent {
+		t.Fatalf("last status code = %v, want %d", delivery.LastStatusCode, http.StatusNoContent)
+	}
+	if calls := rc.calls.Load(); calls != 1 {
+		t.Fatalf("receiver called %d times, want 1", calls)
+	}
+}
+
+func TestDispatcherRetriesServerErrorsWithBackoff(t *testing.T) {
+	d, store, rc, id := setupDispatcher(t, http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusOK)
+
+	for attempt, base := range []time.Duration{d.cfg.InitialBackoff, 2 * d.cfg.InitialBackoff} {
+		attempt++
+
+		before := time.Now()
+		dispatch(t, d, 1)
+
+		delivery := store.get(id)
+		if delivery.Status != models.DeliveryPending || delivery.Attempts != attempt {
+			t.Fatalf("after attempt %d: status %q with %d attempts, want pending with %d", attempt, delivery.Status, delivery.Attempts, attempt)
+		}
+		if delivery.LastStatusCode == nil || *delivery.LastStatusCode < 500 {
+			t.Fatalf("after attempt %d: last status code = %v, want a 5xx", attempt, delivery.LastStatusCode)
+		}
+
+		// The backoff doubles, with up to 20% jitter on top.
+		wait := delivery.NextAttemptAt.Sub(before)
+		if wait < base || wait > base+base/5+time.Second {
+			t.Fatalf("after attempt %d: retry in %s, want between %s and %s", attempt, wait, base, base+base/5)
+		}
+
+		// Not due yet.
+		dispatch(t, d, 0)
+
+		store.advanceTo(*delivery.NextAttemptAt)
+	}
+
+	dispatch(t, d, 1)
+
+	if delivery := store.get(id); delivery.Status != models.DeliveryDelivered || delivery.Attempts != 3 {
+		t.Fatalf("status %q with %d attempts, want delivered with 3", delivery.Status, delivery.Attempts)
+	}
+	if calls := rc.calls.Load(); calls != 3 {
+		t.Fatalf("receiver called %d times, want 3", calls)
+	}
+}
+
+func TestDispatcherDeadLettersAfterMaxAttempts(t *testing.T) {
+	d, store, rc, id := setupDispatcher(t, http.StatusBadGateway)
+
+	for attempt := 1; attempt <= d.cfg.MaxAttempts; attempt++ {
+		dispatch(t, d, 1)
+		store.advance(d.cfg.MaxBackoff * 2)
+	}
+
+	delivery := store.get(id)
+	if delivery.Status != models.DeliveryDead || delivery.Attempts != d.cfg.MaxAttempts {
+		t.Fatalf("status %q with %d attempts, want dead with %d", delivery.Status, delivery.Attempts, d.cfg.MaxAttempts)
+	}
+	if delivery.NextAttemptAt != nil {
+		t.Fatalf("next attempt at %v, want none", delivery.NextAttemptAt)
+	}
+
+	// Dead deliveries are left alone.
+	dispatch(t, d, 0)
+
+	if calls := rc.calls.Load(); int(calls) != d.cfg.MaxAttempts {
+		t.Fatalf("receiver called %d times, want %d", calls, d.cfg.MaxAttempts)
+	}
+}
+
+func TestDispatcherSendsRedeliveredDeliveries(t *testing.T) {
+	d, store, rc, id := setupDispatcher(t, http.StatusInternalServerError)
+
+	for attempt := 1; attempt <= d.cfg.MaxAttempts; attempt++ {
+		dispatch(t, d, 1)
+		store.advance(d.cfg.MaxBackoff * 2)
+	}
+
+	if delivery := store.get(id); delivery.Status != models.DeliveryDead {
+		t.Fatalf("status = %q, want %q", delivery.Status, models.DeliveryDead)
+	}
+
+	rc.setStatuses(http.StatusOK)
+
+	if _, err := store.Redeliver(context.Background(), store.sub.ID, id); err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+
+	dispatch(t, d, 1)
+
+	delivery := store.get(id)
+	if delivery.Status != models.DeliveryDelivered || delivery.Attempts != 1 {
+		t.Fatalf("status %q with %d attempts, want delivered with 1", delivery.Status, delivery.Attempts)
+	}
+	if calls := rc.calls.Load(); int(calls) != d.cfg.MaxAttempts+1 {
+		t.Fatalf("receiver called %d times, want %d", calls, d.cfg.MaxAttempts+1)
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	d := NewDispatcher(nil, nil, config.Webhooks{InitialBackoff: time.Minute, MaxBackoff: 5 * time.Minute})
+
+	tests := []struct {
+		attempts int
+		base     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 5 * time.Minute},
+		{10, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			wait := d.backoff(tt.attempts)
+			if wait < tt.base || wait > tt.base+tt.base/5 {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", tt.attempts, wait, tt.base, tt.base+tt.base/5)
+			}
+		}
+	}
+}
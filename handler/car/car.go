@@ -1,27 +1,61 @@
 package car
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/money"
 	"github.com/michgboxy2/carzone/service"
 	"go.opentelemetry.io/otel"
 )
 
 type CarHandler struct {
 	service service.CarServiceInterface
+	rates   service.ExchangeRateServiceInterface
 }
 
-func NewCarHandler(service service.CarServiceInterface) *CarHandler {
+func NewCarHandler(service service.CarServiceInterface, rates service.ExchangeRateServiceInterface) *CarHandler {
 	return &CarHandler{
 		service: service,
+		rates:   rates,
 	}
 }
 
+// convertPrices converts the cars' prices to the currency asked for with
+// ?currency=, if any. It writes the error response and returns false when
+// they cannot be converted.
+func (h *CarHandler) convertPrices(ctx context.Context, w http.ResponseWriter, r *http.Request, cars []models.Car) bool {
+	currency := strings.ToUpper(r.URL.Query().Get("currency"))
+	if currency == "" {
+		return true
+	}
+
+	if err := money.ValidateCurrency(currency); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	if err := h.rates.ConvertCars(ctx, currency, cars); err != nil {
+		if errors.Is(err, money.ErrNoRate) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return false
+	}
+
+	return true
+}
+
 func (h *CarHandler) GetCarById(w http.ResponseWriter, r *http.Request) {
 	// ctx := r.Context()
 	tracer := otel.Tracer("CarHandler")
@@ -36,15 +70,25 @@ func (h *CarHandler) GetCarById(w http.ResponseWriter, r *http.Request) {
 	resp, err := h.service.GetCarById(ctx, id)
 
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-
 		span.RecordError(err)
 
+		if errors.Is(err, models.ErrCarNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+
 		log.Println("Error : ", err)
 		return
 	}
 
-	body, err := json.Marshal(resp)
+	cars := []models.Car{*resp}
+	if !h.convertPrices(ctx, w, r, cars) {
+		return
+	}
+
+	body, err := json.Marshal(cars[0])
 
 	if err != nil {
 		span.RecordError(err)
@@ -65,6 +109,49 @@ func (h *CarHandler) GetCarById(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// CompareCars lays the cars given by ?ids=a,b,c out side by side with
+// their engines, naming the attributes that differ and how each car's
+// price, range and displacement compare with the first one's. Prices are
+// converted to ?currency= first, if given.
+func (h *CarHandler) CompareCars(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CarHandler")
+
+	ctx, span := tracer.Start(r.Context(), "CompareCars-Handler")
+
+	defer span.End()
+
+	ids, err := models.ParseComparedCars(r.URL.Query().Get("ids"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cars, err := h.service.GetCarsByIds(ctx, ids)
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, models.ErrCarNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if !h.convertPrices(ctx, w, r, cars) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(models.NewComparison(cars)); err != nil {
+		span.RecordError(err)
+		log.Println("Error writing response:", err)
+	}
+}
+
+// GetCarByBrand lists the cars of a brand. They can be filtered by
+// ?min_mileage= and ?max_mileage= in ?mileage_unit=km|mi (km by default),
+// ?min_condition=, ?max_owners=, ?accident_free=true and ?service_history=.
 func (h *CarHandler) GetCarByBrand(w http.ResponseWriter, r *http.Request) {
 	tracer := otel.Tracer("CarHandler")
 
@@ -76,13 +163,23 @@ func (h *CarHandler) GetCarByBrand(w http.ResponseWriter, r *http.Request) {
 	brand := vars["brand"]
 	isEngine := r.URL.Query().Get("isEngine") == "true"
 
-	cars, err := h.service.GetCarByBrand(ctx, brand, isEngine)
+	filter, err := parseCarFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cars, err := h.service.GetCarByBrand(ctx, brand, isEngine, filter)
 	if err != nil {
 		span.RecordError(err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if !h.convertPrices(ctx, w, r, cars) {
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(cars)
 }
@@ -104,7 +201,11 @@ func (h *CarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
 	createdCar, err := h.service.CreateCar(ctx, &carReq)
 	if err != nil {
 		span.RecordError(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if isInvalidReference(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -139,7 +240,14 @@ func (h *CarHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
 	updatedCar, err := h.service.UpdateCar(ctx, carID, &carReq)
 	if err != nil {
 		span.RecordError(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, models.ErrCarNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case isInvalidReference(err):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -159,10 +267,68 @@ func (h *CarHandler) DeleteCar(w http.ResponseWriter, r *http.Request) {
 	deletedCar, err := h.service.DeleteCar(ctx, id)
 	if err != nil {
 		span.RecordError(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if errors.Is(err, models.ErrCarNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(deletedCar)
 }
+
+// isInvalidReference reports whether err is about a car naming a reference
+// value, brand, model or fuel type that cannot be used, a fuel type its
+// engine cannot run on, or winding its odometer back.
+func isInvalidReference(err error) bool {
+	return errors.Is(err, models.ErrReferenceNotFound) ||
+		errors.Is(err, models.ErrBrandNotFound) || errors.Is(err, models.ErrModelNotFound) ||
+		errors.Is(err, models.ErrFuelTypeNotFound) || errors.Is(err, models.ErrFuelTypeDeprecated) ||
+		errors.Is(err, models.ErrPowertrainMismatch) || errors.Is(err, models.ErrMileageDecreased)
+}
+
+// parseCarFilter reads the history filters of a car listing.
+func parseCarFilter(query url.Values) (models.CarFilter, error) {
+	filter := models.CarFilter{
+		MileageUnit:    models.MileageKilometres,
+		MinCondition:   query.Get("min_condition"),
+		ServiceHistory: query.Get("service_history"),
+	}
+
+	if unit := query.Get("mileage_unit"); unit != "" {
+		filter.MileageUnit = unit
+	}
+
+	for name, target := range map[string]*int64{
+		"min_mileage": &filter.MinMileage,
+		"max_mileage": &filter.MaxMileage,
+	} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return filter, errors.New(name + " must be an integer")
+			}
+			*target = n
+		}
+	}
+
+	if value := query.Get("max_owners"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, errors.New("max_owners must be an integer")
+		}
+		filter.MaxOwners = &n
+	}
+
+	if value := query.Get("accident_free"); value != "" {
+		accidentFree, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("accident_free must be true or false")
+		}
+		filter.AccidentFree = accidentFree
+	}
+
+	return filter, filter.Validate()
+}
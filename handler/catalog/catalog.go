@@ -0,0 +1,302 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/service"
+	"go.opentelemetry.io/otel"
+)
+
+// CatalogHandler serves the brand and model catalogue.
+type CatalogHandler struct {
+	service service.CatalogServiceInterface
+}
+
+func NewCatalogHandler(service service.CatalogServiceInterface) *CatalogHandler {
+	return &CatalogHandler{
+		service: service,
+	}
+}
+
+func (h *CatalogHandler) ListBrands(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ListBrands-Handler")
+
+	defer span.End()
+
+	brands, err := h.service.ListBrands(ctx)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, brands)
+}
+
+// GetBrand returns the brand with its models.
+func (h *CatalogHandler) GetBrand(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogHandler")
+
+	ctx, span := tracer.Start(r.Context(), "GetBrand-Handler")
+
+	defer span.End()
+
+	id, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	brand, err := h.service.GetBrand(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, brand)
+}
+
+// CreateBrand adds a brand, e.g. {"name": "Volkswagen", "aliases": ["VW"]}.
+func (h *CatalogHandler) CreateBrand(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogHandler")
+
+	ctx, span := tracer.Start(r.Context(), "CreateBrand-Handler")
+
+	defer span.End()
+
+	req, ok := decodeRequest(w, r, models.ValidateBrandRequest)
+	if !ok {
+		return
+	}
+
+	brand, err := h.service.CreateBrand(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, brand)
+}
+
+// UpdateBrand renames a brand and replaces its aliases.
+func (h *CatalogHandler) UpdateBrand(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogHandler")
+
+	ctx, span := tracer.Start(r.Context(), "UpdateBrand-Handler")
+
+	defer span.End()
+
+	id, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	req, ok := decodeRequest(w, r, models.ValidateBrandRequest)
+	if !ok {
+		return
+	}
+
+	brand, err := h.service.UpdateBrand(ctx, id, req)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, brand)
+}
+
+func (h *CatalogHandler) DeleteBrand(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogHandler")
+
+	ctx, span := tracer.Start(r.Context(), "DeleteBrand-Handler")
+
+	defer span.End()
+
+	id, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteBrand(ctx, id); err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CatalogHandler) ListModels(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ListModels-Handler")
+
+	defer span.End()
+
+	brandID, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	carModels, err := h.service.ListModels(ctx, brandID)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, carModels)
+}
+
+// CreateModel adds a model to a brand, e.g. {"name": "Golf", "aliases": ["Rabbit"]}.
+func (h *CatalogHandler) CreateModel(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogHandler")
+
+	ctx, span := tracer.Start(r.Context(), "CreateModel-Handler")
+
+	defer span.End()
+
+	brandID, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	req, ok := decodeRequest(w, r, models.ValidateModelRequest)
+	if !ok {
+		return
+	}
+
+	model, err := h.service.CreateModel(ctx, brandID, req)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, model)
+}
+
+// UpdateModel renames a model and replaces its aliases.
+func (h *CatalogHandler) UpdateModel(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogHandler")
+
+	ctx, span := tracer.Start(r.Context(), "UpdateModel-Handler")
+
+	defer span.End()
+
+	brandID, modelID, ok := parseIDs(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := decodeRequest(w, r, models.ValidateModelRequest)
+	if !ok {
+		return
+	}
+
+	model, err := h.service.UpdateModel(ctx, brandID, modelID, req)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, model)
+}
+
+func (h *CatalogHandler) DeleteModel(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogHandler")
+
+	ctx, span := tracer.Start(r.Context(), "DeleteModel-Handler")
+
+	defer span.End()
+
+	brandID, modelID, ok := parseIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteModel(ctx, brandID, modelID); err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeRequest reads and validates a brand or model. It writes the error
+// response and returns false when the request is not acceptable.
+func decodeRequest(w http.ResponseWriter, r *http.Request, validate func(models.CatalogRequest) error) (*models.CatalogRequest, bool) {
+	var req models.CatalogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	req.Normalize()
+
+	if err := validate(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return &req, true
+}
+
+func parseIDs(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	vars := mux.Vars(r)
+
+	brandID, ok := parseID(w, vars["id"])
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	modelID, ok := parseID(w, vars["modelId"])
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return brandID, modelID, true
+}
+
+func parseID(w http.ResponseWriter, value string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeError maps the catalogue errors to their status.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrBrandNotFound), errors.Is(err, models.ErrModelNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrBrandExists), errors.Is(err, models.ErrBrandInUse),
+		errors.Is(err, models.ErrModelExists), errors.Is(err, models.ErrModelInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error writing response:", err)
+	}
+}
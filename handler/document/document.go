@@ -0,0 +1,292 @@
+package document
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/michgboxy2/carzone/blob"
+	"github.com/michgboxy2/carzone/middleware"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/service"
+	"go.opentelemetry.io/otel"
+)
+
+// multipartOverhead is allowed on top of the document size for the
+// multipart boundaries, headers and the category field.
+const multipartOverhead = 64 << 10
+
+// maxCategoryLength bounds the category field read ahead of the file.
+const maxCategoryLength = 64
+
+type DocumentHandler struct {
+	service       service.DocumentServiceInterface
+	maxUploadSize int
+}
+
+func NewDocumentHandler(service service.DocumentServiceInterface, maxUploadSize int) *DocumentHandler {
+	return &DocumentHandler{
+		service:       service,
+		maxUploadSize: maxUploadSize,
+	}
+}
+
+// UploadDocument takes a multipart/form-data body with the document's
+// category in its "category" field, followed by the file in its "file"
+// field. Uploading a file the car already has returns that document with
+// 200 instead of 201.
+func (h *DocumentHandler) UploadDocument(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("DocumentHandler")
+
+	ctx, span := tracer.Start(r.Context(), "UploadDocument-Handler")
+
+	defer span.End()
+
+	carID, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, int64(h.maxUploadSize)+multipartOverhead)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "body must be multipart/form-data", http.StatusBadRequest)
+		return
+	}
+
+	upload := models.DocumentUpload{UploadedBy: middleware.UserName(ctx)}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			http.Error(w, `"file" field is required`, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			span.RecordError(err)
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writeError(w, err)
+			} else {
+				http.Error(w, "malformed multipart body", http.StatusBadRequest)
+			}
+			return
+		}
+
+		switch part.FormName() {
+		case "category":
+			value, err := io.ReadAll(io.LimitReader(part, maxCategoryLength+1))
+			part.Close()
+			if err != nil {
+				http.Error(w, "malformed multipart body", http.StatusBadRequest)
+				return
+			}
+			upload.Category = string(value)
+			continue
+		case "file":
+		default:
+			part.Close()
+			continue
+		}
+
+		if upload.Category == "" {
+			part.Close()
+			http.Error(w, `"category" field is required before "file"`, http.StatusBadRequest)
+			return
+		}
+
+		if err := models.ValidateDocumentCategory(upload.Category); err != nil {
+			part.Close()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		upload.FileName = part.FileName()
+
+		doc, created, err := h.service.UploadDocument(ctx, carID, upload, part)
+		part.Close()
+		if err != nil {
+			span.RecordError(err)
+			writeError(w, err)
+			return
+		}
+
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+
+		writeJSON(w, status, doc)
+		return
+	}
+}
+
+// ListDocuments lists a car's documents, only those of ?category= when
+// given.
+func (h *DocumentHandler) ListDocuments(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("DocumentHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ListDocuments-Handler")
+
+	defer span.End()
+
+	carID, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	category := r.URL.Query().Get("category")
+	if category != "" {
+		if err := models.ValidateDocumentCategory(category); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	docs, err := h.service.ListDocuments(ctx, carID, category)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, docs)
+}
+
+func (h *DocumentHandler) GetDocument(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("DocumentHandler")
+
+	ctx, span := tracer.Start(r.Context(), "GetDocument-Handler")
+
+	defer span.End()
+
+	carID, documentID, ok := parseIDs(w, r)
+	if !ok {
+		return
+	}
+
+	doc, err := h.service.GetDocument(ctx, carID, documentID)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// DownloadDocument sends the file as an attachment under its original
+// name. The ETag is its checksum, so unchanged files are not sent again.
+func (h *DocumentHandler) DownloadDocument(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("DocumentHandler")
+
+	ctx, span := tracer.Start(r.Context(), "DownloadDocument-Handler")
+
+	defer span.End()
+
+	carID, documentID, ok := parseIDs(w, r)
+	if !ok {
+		return
+	}
+
+	rc, doc, err := h.service.OpenDocument(ctx, carID, documentID)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", doc.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": doc.FileName}))
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("ETag", `"`+doc.Checksum+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", doc.CreatedAt, rs)
+		return
+	}
+
+	if _, err := io.Copy(w, rc); err != nil {
+		log.Println("Error writing document:", err)
+	}
+}
+
+func (h *DocumentHandler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("DocumentHandler")
+
+	ctx, span := tracer.Start(r.Context(), "DeleteDocument-Handler")
+
+	defer span.End()
+
+	carID, documentID, ok := parseIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteDocument(ctx, carID, documentID); err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseIDs(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	vars := mux.Vars(r)
+
+	carID, ok := parseID(w, vars["id"])
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	documentID, ok := parseID(w, vars["documentId"])
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return carID, documentID, true
+}
+
+func parseID(w http.ResponseWriter, value string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, models.ErrCarNotFound), errors.Is(err, models.ErrDocumentNotFound), errors.Is(err, blob.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrTooManyDocuments):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrUnsupportedDocument):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, models.ErrDocumentTooLarge), errors.As(err, &maxBytesErr):
+		http.Error(w, models.ErrDocumentTooLarge.Error(), http.StatusRequestEntityTooLarge)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error writing response:", err)
+	}
+}
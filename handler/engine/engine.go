@@ -2,8 +2,10 @@ package engine
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -77,6 +79,17 @@ func (e *EngineHandler) CreateEngine(w http.ResponseWriter, r *http.Request) {
 	createdEngine, err := e.service.CreateEngine(ctx, &engineReq)
 	if err != nil {
 		span.RecordError(err)
+
+		// Point the client at the engine it should use instead.
+		var duplicate *models.DuplicateEngineError
+		if errors.As(err, &duplicate) {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{
+				"error":           models.ErrDuplicateEngine.Error(),
+				"existing_engine": duplicate.Existing,
+			})
+			return
+		}
+
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -125,7 +138,11 @@ func (e *EngineHandler) UpdateEngine(w http.ResponseWriter, r *http.Request) {
 	updatedEngine, err := e.service.UpdateEngine(ctx, engineID, &engineReq)
 	if err != nil {
 		span.RecordError(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if errors.Is(err, models.ErrPowertrainMismatch) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -178,3 +195,105 @@ func (e *EngineHandler) DeleteEngine(w http.ResponseWriter, r *http.Request) {
 		log.Println("Error writing response:", err)
 	}
 }
+
+// ListEngines returns a page of the engine catalogue. It is filtered by
+// ?powertrain=, ?min_displacement=, ?max_displacement=, ?cylinders=, ?min_range= and
+// ?max_range=, ordered by ?sort=created|displacement|cylinders|range (prefix
+// "-" for descending) and paged with ?limit= and ?offset=.
+func (e *EngineHandler) ListEngines(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("EngineHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ListEngines-Handler")
+
+	defer span.End()
+
+	query := r.URL.Query()
+
+	filter := models.EngineFilter{
+		Powertrain: query.Get("powertrain"),
+		Sort:       query.Get("sort"),
+		Limit:      models.DefaultEnginePageSize,
+	}
+
+	for name, target := range map[string]*int64{
+		"min_displacement": &filter.MinDisplacement,
+		"max_displacement": &filter.MaxDisplacement,
+		"cylinders":        &filter.Cylinders,
+		"min_range":        &filter.MinRange,
+		"max_range":        &filter.MaxRange,
+	} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				http.Error(w, name+" must be an integer", http.StatusBadRequest)
+				return
+			}
+			*target = n
+		}
+	}
+
+	for name, target := range map[string]*int{
+		"limit":  &filter.Limit,
+		"offset": &filter.Offset,
+	} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				http.Error(w, name+" must be an integer", http.StatusBadRequest)
+				return
+			}
+			*target = n
+		}
+	}
+
+	if err := filter.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := e.service.ListEngines(ctx, filter)
+	if err != nil {
+		span.RecordError(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// GetCarsByEngine lists the cars that use an engine.
+func (e *EngineHandler) GetCarsByEngine(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("EngineHandler")
+
+	ctx, span := tracer.Start(r.Context(), "GetCarsByEngine-Handler")
+
+	defer span.End()
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	cars, err := e.service.GetCarsByEngine(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, models.ErrEngineNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cars)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error writing response:", err)
+	}
+}
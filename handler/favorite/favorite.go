@@ -0,0 +1,117 @@
+package favorite
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/michgboxy2/carzone/middleware"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/service"
+	"go.opentelemetry.io/otel"
+)
+
+// FavoriteHandler serves the favorites of the user the token was issued
+// to.
+type FavoriteHandler struct {
+	service service.FavoriteServiceInterface
+}
+
+func NewFavoriteHandler(service service.FavoriteServiceInterface) *FavoriteHandler {
+	return &FavoriteHandler{
+		service: service,
+	}
+}
+
+// ListFavorites returns the user's favorite cars, latest first.
+func (h *FavoriteHandler) ListFavorites(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FavoriteHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ListFavorites-Handler")
+
+	defer span.End()
+
+	favorites, err := h.service.ListFavorites(ctx, middleware.UserName(ctx))
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, favorites)
+}
+
+// AddFavorite favorites a car. Favoriting it again changes nothing.
+func (h *FavoriteHandler) AddFavorite(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FavoriteHandler")
+
+	ctx, span := tracer.Start(r.Context(), "AddFavorite-Handler")
+
+	defer span.End()
+
+	carID, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	if err := h.service.AddFavorite(ctx, middleware.UserName(ctx), carID); err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *FavoriteHandler) RemoveFavorite(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FavoriteHandler")
+
+	ctx, span := tracer.Start(r.Context(), "RemoveFavorite-Handler")
+
+	defer span.End()
+
+	carID, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	if err := h.service.RemoveFavorite(ctx, middleware.UserName(ctx), carID); err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseID(w http.ResponseWriter, value string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrCarNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrTooManyFavorites):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error writing response:", err)
+	}
+}
@@ -0,0 +1,173 @@
+package fueltype
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/service"
+	"go.opentelemetry.io/otel"
+)
+
+type FuelTypeHandler struct {
+	service service.FuelTypeServiceInterface
+}
+
+func NewFuelTypeHandler(service service.FuelTypeServiceInterface) *FuelTypeHandler {
+	return &FuelTypeHandler{
+		service: service,
+	}
+}
+
+// ListFuelTypes returns every fuel type, the deprecated ones last.
+func (h *FuelTypeHandler) ListFuelTypes(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FuelTypeHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ListFuelTypes-Handler")
+
+	defer span.End()
+
+	fuelTypes, err := h.service.ListFuelTypes(ctx)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, fuelTypes)
+}
+
+func (h *FuelTypeHandler) GetFuelType(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FuelTypeHandler")
+
+	ctx, span := tracer.Start(r.Context(), "GetFuelType-Handler")
+
+	defer span.End()
+
+	fuelType, err := h.service.GetFuelType(ctx, mux.Vars(r)["code"])
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, fuelType)
+}
+
+// CreateFuelType adds a fuel type, e.g. {"code": "LPG", "name": "Liquefied
+// petroleum gas", "synonyms": ["Autogas"], "powertrains": ["combustion"],
+// "emission_factor": 1.51, "emission_unit": "kg_co2e_per_litre"}.
+func (h *FuelTypeHandler) CreateFuelType(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FuelTypeHandler")
+
+	ctx, span := tracer.Start(r.Context(), "CreateFuelType-Handler")
+
+	defer span.End()
+
+	var req models.FuelTypeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		span.RecordError(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req.Normalize()
+
+	if err := models.ValidateFuelTypeRequest(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fuelType, err := h.service.CreateFuelType(ctx, &req)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, fuelType)
+}
+
+// UpdateFuelType replaces a fuel type but for its code, e.g. to deprecate
+// it with {"deprecated": true, ...}.
+func (h *FuelTypeHandler) UpdateFuelType(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FuelTypeHandler")
+
+	ctx, span := tracer.Start(r.Context(), "UpdateFuelType-Handler")
+
+	defer span.End()
+
+	var req models.FuelTypeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		span.RecordError(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	code := mux.Vars(r)["code"]
+
+	if req.Code == "" {
+		req.Code = code
+	}
+
+	req.Normalize()
+
+	if req.Code != code {
+		http.Error(w, "code cannot be changed", http.StatusBadRequest)
+		return
+	}
+
+	if err := models.ValidateFuelTypeRequest(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fuelType, err := h.service.UpdateFuelType(ctx, code, &req)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, fuelType)
+}
+
+func (h *FuelTypeHandler) DeleteFuelType(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("FuelTypeHandler")
+
+	ctx, span := tracer.Start(r.Context(), "DeleteFuelType-Handler")
+
+	defer span.End()
+
+	if err := h.service.DeleteFuelType(ctx, mux.Vars(r)["code"]); err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError maps the fuel type errors to their status.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrFuelTypeNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrFuelTypeExists), errors.Is(err, models.ErrFuelTypeInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error writing response:", err)
+	}
+}
@@ -0,0 +1,75 @@
+package health
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/michgboxy2/carzone/health"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{
+		checker: checker,
+	}
+}
+
+// Liveness answers as long as the process can serve HTTP. It deliberately
+// checks no dependencies so a database outage does not get the container
+// restarted.
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": health.StatusUp})
+}
+
+// Readiness reports whether the instance should receive traffic. It fails
+// while the service is starting up or shutting down, and whenever a
+// critical dependency is down.
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	if !h.checker.Ready() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status": health.StatusDown,
+			"ready":  false,
+		})
+		return
+	}
+
+	report := h.checker.Run(r.Context())
+
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, map[string]interface{}{
+		"status": report.Status,
+		"ready":  report.Ready,
+		"checks": report.Checks,
+	})
+}
+
+// Health returns the full report: every dependency with its status and
+// latency, plus the running version.
+func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Run(r.Context())
+
+	status := http.StatusOK
+	if report.Status == health.StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, report)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error writing response: ", err)
+	}
+}
@@ -0,0 +1,293 @@
+package image
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/michgboxy2/carzone/blob"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/service"
+	imageService "github.com/michgboxy2/carzone/service/image"
+	"go.opentelemetry.io/otel"
+)
+
+// multipartOverhead is allowed on top of the image size for the multipart
+// boundaries and headers.
+const multipartOverhead = 64 << 10
+
+type ImageHandler struct {
+	service       service.ImageServiceInterface
+	signer        *blob.URLSigner
+	maxUploadSize int
+}
+
+func NewImageHandler(service service.ImageServiceInterface, signer *blob.URLSigner, maxUploadSize int) *ImageHandler {
+	return &ImageHandler{
+		service:       service,
+		signer:        signer,
+		maxUploadSize: maxUploadSize,
+	}
+}
+
+// UploadImage takes a multipart/form-data body with the photo in its
+// "image" field.
+func (h *ImageHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ImageHandler")
+
+	ctx, span := tracer.Start(r.Context(), "UploadImage-Handler")
+
+	defer span.End()
+
+	carID, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, int64(h.maxUploadSize)+multipartOverhead)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "body must be multipart/form-data", http.StatusBadRequest)
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			http.Error(w, `"image" field is required`, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			span.RecordError(err)
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writeError(w, err)
+			} else {
+				http.Error(w, "malformed multipart body", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if part.FormName() != "image" {
+			part.Close()
+			continue
+		}
+
+		img, err := h.service.UploadImage(ctx, carID, part)
+		part.Close()
+		if err != nil {
+			span.RecordError(err)
+			writeError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, img)
+		return
+	}
+}
+
+func (h *ImageHandler) ListImages(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ImageHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ListImages-Handler")
+
+	defer span.End()
+
+	carID, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	images, err := h.service.ListImages(ctx, carID)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, images)
+}
+
+func (h *ImageHandler) DeleteImage(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ImageHandler")
+
+	ctx, span := tracer.Start(r.Context(), "DeleteImage-Handler")
+
+	defer span.End()
+
+	vars := mux.Vars(r)
+
+	carID, ok := parseID(w, vars["id"])
+	if !ok {
+		return
+	}
+
+	imageID, ok := parseID(w, vars["imageId"])
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteImage(ctx, carID, imageID); err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ImageHandler) ReorderImages(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ImageHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ReorderImages-Handler")
+
+	defer span.End()
+
+	carID, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	var req models.ImageOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		span.RecordError(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.ValidateImageOrderRequest(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	images, err := h.service.ReorderImages(ctx, carID, &req)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, images)
+}
+
+func (h *ImageHandler) SetCover(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ImageHandler")
+
+	ctx, span := tracer.Start(r.Context(), "SetCover-Handler")
+
+	defer span.End()
+
+	vars := mux.Vars(r)
+
+	carID, ok := parseID(w, vars["id"])
+	if !ok {
+		return
+	}
+
+	imageID, ok := parseID(w, vars["imageId"])
+	if !ok {
+		return
+	}
+
+	images, err := h.service.SetCover(ctx, carID, imageID)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, images)
+}
+
+// ServeImage serves an image variant to anyone holding a valid signed URL.
+// It needs no bearer token, so the URLs work in <img> tags.
+func (h *ImageHandler) ServeImage(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ImageHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ServeImage-Handler")
+
+	defer span.End()
+
+	vars := mux.Vars(r)
+
+	imageID, err := uuid.Parse(vars["imageId"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	expires, err := h.signer.Verify(imageService.ImagePath(imageID, vars["variant"]), r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	rc, img, err := h.service.OpenImage(ctx, imageID, vars["variant"])
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+	defer rc.Close()
+
+	maxAge := int(time.Until(expires).Seconds())
+
+	w.Header().Set("Content-Type", img.ContentType)
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(maxAge))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// The filesystem store hands back files, which can serve ranges and
+	// conditional requests.
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", img.CreatedAt, rs)
+		return
+	}
+
+	if _, err := io.Copy(w, rc); err != nil {
+		log.Println("Error writing image:", err)
+	}
+}
+
+func parseID(w http.ResponseWriter, value string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, models.ErrCarNotFound), errors.Is(err, models.ErrImageNotFound), errors.Is(err, blob.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrTooManyImages):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrUnsupportedImage):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, models.ErrImageTooLarge), errors.As(err, &maxBytesErr):
+		http.Error(w, models.ErrImageTooLarge.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, models.ErrImageOrderMismatch):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error writing response:", err)
+	}
+}
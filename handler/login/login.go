@@ -1,6 +1,7 @@
 package login
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -8,10 +9,22 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/michgboxy2/carzone/config"
+	"github.com/michgboxy2/carzone/middleware"
 	"github.com/michgboxy2/carzone/models"
 )
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+type LoginHandler struct {
+	auth config.Auth
+}
+
+func NewLoginHandler(auth config.Auth) *LoginHandler {
+	return &LoginHandler{
+		auth: auth,
+	}
+}
+
+func (h *LoginHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var credentials models.Credentials
 
 	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
@@ -20,14 +33,15 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	valid := (credentials.UserName == "admin" && credentials.Password == "admin123")
+	valid := subtle.ConstantTimeCompare([]byte(credentials.UserName), []byte(h.auth.AdminUser)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(credentials.Password), []byte(h.auth.AdminPassword.Value())) == 1
 
 	if !valid {
 		http.Error(w, "Incorrect Username or password", http.StatusUnauthorized)
 		return
 	}
 
-	tokenString, err := GenerateToken(credentials.UserName)
+	tokenString, err := h.GenerateToken(credentials.UserName, models.RoleAdmin, h.auth.TokenTTL)
 
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
@@ -41,18 +55,23 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-func GenerateToken(userName string) (string, error) {
-	expiration := time.Now().Add(24 * time.Hour)
-
-	claims := &jwt.StandardClaims{
-		ExpiresAt: expiration.Unix(),
-		IssuedAt:  time.Now().Unix(),
-		Subject:   userName,
+// GenerateToken issues a token for userName acting in role.
+func (h *LoginHandler) GenerateToken(userName, role string, ttl time.Duration) (string, error) {
+	expiration := time.Now().Add(ttl)
+
+	claims := &middleware.Claims{
+		UserName: userName,
+		Role:     role,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expiration.Unix(),
+			IssuedAt:  time.Now().Unix(),
+			Subject:   userName,
+		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	signedToken, err := token.SignedString([]byte("some_value"))
+	signedToken, err := token.SignedString([]byte(h.auth.JWTKey.Value()))
 
 	if err != nil {
 		return "", err
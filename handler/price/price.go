@@ -0,0 +1,58 @@
+package price
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/service"
+	"go.opentelemetry.io/otel"
+)
+
+type PriceHandler struct {
+	service service.PriceServiceInterface
+}
+
+func NewPriceHandler(service service.PriceServiceInterface) *PriceHandler {
+	return &PriceHandler{
+		service: service,
+	}
+}
+
+// GetPriceHistory returns the car's prices, oldest first, with its days on
+// market, number of reductions and overall percentage change.
+func (h *PriceHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("PriceHandler")
+
+	ctx, span := tracer.Start(r.Context(), "GetPriceHistory-Handler")
+
+	defer span.End()
+
+	carID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.service.GetPriceHistory(ctx, carID)
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, models.ErrCarNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		span.RecordError(err)
+		log.Println("Error writing response:", err)
+	}
+}
@@ -0,0 +1,84 @@
+package rate
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/money"
+	"github.com/michgboxy2/carzone/service"
+	"go.opentelemetry.io/otel"
+)
+
+type ExchangeRateHandler struct {
+	service service.ExchangeRateServiceInterface
+}
+
+func NewExchangeRateHandler(service service.ExchangeRateServiceInterface) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		service: service,
+	}
+}
+
+func (h *ExchangeRateHandler) GetRates(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ExchangeRateHandler")
+
+	ctx, span := tracer.Start(r.Context(), "GetRates-Handler")
+
+	defer span.End()
+
+	rates, err := h.service.GetRates(ctx)
+	if err != nil {
+		span.RecordError(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rates)
+}
+
+// ReplaceRates takes the complete set of rates, e.g.
+// {"base": "USD", "rates": {"EUR": "0.92", "GBP": "0.79"}}.
+func (h *ExchangeRateHandler) ReplaceRates(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ExchangeRateHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ReplaceRates-Handler")
+
+	defer span.End()
+
+	var req money.Rates
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		span.RecordError(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rates, err := h.service.ReplaceRates(ctx, &req)
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, models.ErrRateBaseMismatch) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rates)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error writing response:", err)
+	}
+}
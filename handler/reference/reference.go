@@ -0,0 +1,169 @@
+package reference
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/service"
+	"go.opentelemetry.io/otel"
+)
+
+// ReferenceHandler serves the values of one reference kind.
+type ReferenceHandler struct {
+	service service.ReferenceServiceInterface
+	kind    string
+}
+
+func NewReferenceHandler(service service.ReferenceServiceInterface, kind string) *ReferenceHandler {
+	return &ReferenceHandler{
+		service: service,
+		kind:    kind,
+	}
+}
+
+func (h *ReferenceHandler) ListValues(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ReferenceHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ListValues-Handler")
+
+	defer span.End()
+
+	values, err := h.service.ListValues(ctx, h.kind)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, values)
+}
+
+func (h *ReferenceHandler) GetValue(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ReferenceHandler")
+
+	ctx, span := tracer.Start(r.Context(), "GetValue-Handler")
+
+	defer span.End()
+
+	value, err := h.service.GetValue(ctx, h.kind, mux.Vars(r)["code"])
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, value)
+}
+
+// CreateValue adds a value, e.g. {"code": "dct", "name": "Dual-clutch"}.
+func (h *ReferenceHandler) CreateValue(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ReferenceHandler")
+
+	ctx, span := tracer.Start(r.Context(), "CreateValue-Handler")
+
+	defer span.End()
+
+	var req models.ReferenceValueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		span.RecordError(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.ValidateReferenceCode(req.Code); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.ValidateReferenceName(req.Name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	value, err := h.service.CreateValue(ctx, h.kind, &req)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, value)
+}
+
+// UpdateValue renames a value, e.g. {"name": "Dual-clutch (DCT)"}.
+func (h *ReferenceHandler) UpdateValue(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ReferenceHandler")
+
+	ctx, span := tracer.Start(r.Context(), "UpdateValue-Handler")
+
+	defer span.End()
+
+	var req models.ReferenceValueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		span.RecordError(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	code := mux.Vars(r)["code"]
+
+	if req.Code != "" && req.Code != code {
+		http.Error(w, "code cannot be changed", http.StatusBadRequest)
+		return
+	}
+
+	if err := models.ValidateReferenceName(req.Name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	value, err := h.service.UpdateValue(ctx, h.kind, code, &req)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, value)
+}
+
+func (h *ReferenceHandler) DeleteValue(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ReferenceHandler")
+
+	ctx, span := tracer.Start(r.Context(), "DeleteValue-Handler")
+
+	defer span.End()
+
+	if err := h.service.DeleteValue(ctx, h.kind, mux.Vars(r)["code"]); err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError maps the reference errors to their status.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrReferenceNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrReferenceExists), errors.Is(err, models.ErrReferenceInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error writing response:", err)
+	}
+}
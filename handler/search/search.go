@@ -0,0 +1,228 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/michgboxy2/carzone/middleware"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/service"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	defaultAlertLimit = 50
+	maxAlertLimit     = 500
+)
+
+// SearchHandler serves the saved searches of the user the token was issued
+// to.
+type SearchHandler struct {
+	service service.SearchServiceInterface
+}
+
+func NewSearchHandler(service service.SearchServiceInterface) *SearchHandler {
+	return &SearchHandler{
+		service: service,
+	}
+}
+
+func (h *SearchHandler) ListSearches(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("SearchHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ListSearches-Handler")
+
+	defer span.End()
+
+	searches, err := h.service.ListSearches(ctx, middleware.UserName(ctx))
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, searches)
+}
+
+func (h *SearchHandler) GetSearch(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("SearchHandler")
+
+	ctx, span := tracer.Start(r.Context(), "GetSearch-Handler")
+
+	defer span.End()
+
+	id, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	search, err := h.service.GetSearch(ctx, middleware.UserName(ctx), id)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, search)
+}
+
+// CreateSearch saves a search, e.g. {"name": "Cheap hybrids", "fuel_type":
+// "Hybrid", "max_price": {"amount": "20000", "currency": "EUR"},
+// "min_year": 2018, "frequency": "daily"}.
+func (h *SearchHandler) CreateSearch(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("SearchHandler")
+
+	ctx, span := tracer.Start(r.Context(), "CreateSearch-Handler")
+
+	defer span.End()
+
+	req, ok := decodeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	search, err := h.service.CreateSearch(ctx, middleware.UserName(ctx), &req)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, search)
+}
+
+func (h *SearchHandler) UpdateSearch(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("SearchHandler")
+
+	ctx, span := tracer.Start(r.Context(), "UpdateSearch-Handler")
+
+	defer span.End()
+
+	id, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	req, ok := decodeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	search, err := h.service.UpdateSearch(ctx, middleware.UserName(ctx), id, &req)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, search)
+}
+
+func (h *SearchHandler) DeleteSearch(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("SearchHandler")
+
+	ctx, span := tracer.Start(r.Context(), "DeleteSearch-Handler")
+
+	defer span.End()
+
+	id, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSearch(ctx, middleware.UserName(ctx), id); err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAlerts returns a search's alerts, newest first, whether they have
+// been sent or are waiting for the next digest. ?limit= caps their number.
+func (h *SearchHandler) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("SearchHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ListAlerts-Handler")
+
+	defer span.End()
+
+	id, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	limit := defaultAlertLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxAlertLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxAlertLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	alerts, err := h.service.ListAlerts(ctx, middleware.UserName(ctx), id, limit)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, alerts)
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request) (models.SavedSearchRequest, bool) {
+	var req models.SavedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return req, false
+	}
+
+	req.Normalize()
+
+	if err := models.ValidateSavedSearchRequest(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return req, false
+	}
+
+	return req, true
+}
+
+func parseID(w http.ResponseWriter, value string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrSearchNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrTooManySearches):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrBrandNotFound), errors.Is(err, models.ErrFuelTypeNotFound),
+		errors.Is(err, models.ErrEmailRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error writing response:", err)
+	}
+}
@@ -0,0 +1,102 @@
+package similar
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/money"
+	"github.com/michgboxy2/carzone/service"
+	"go.opentelemetry.io/otel"
+)
+
+type SimilarHandler struct {
+	service service.SimilarServiceInterface
+	rates   service.ExchangeRateServiceInterface
+}
+
+func NewSimilarHandler(service service.SimilarServiceInterface, rates service.ExchangeRateServiceInterface) *SimilarHandler {
+	return &SimilarHandler{
+		service: service,
+		rates:   rates,
+	}
+}
+
+// GetSimilarCars returns the cars most like the given one, nearest first,
+// with how far each is from it. ?limit= caps their number and ?currency=
+// converts their prices.
+func (h *SimilarHandler) GetSimilarCars(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("SimilarHandler")
+
+	ctx, span := tracer.Start(r.Context(), "GetSimilarCars-Handler")
+
+	defer span.End()
+
+	carID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	var limit int
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > models.MaxSimilarCars {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(models.MaxSimilarCars), http.StatusBadRequest)
+			return
+		}
+	}
+
+	currency := strings.ToUpper(r.URL.Query().Get("currency"))
+	if currency != "" {
+		if err := money.ValidateCurrency(currency); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	similar, err := h.service.SimilarCars(ctx, carID, limit)
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, models.ErrCarNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if currency != "" {
+		cars := make([]models.Car, len(similar))
+		for i := range similar {
+			cars[i] = similar[i].Car
+		}
+
+		if err := h.rates.ConvertCars(ctx, currency, cars); err != nil {
+			span.RecordError(err)
+			if errors.Is(err, money.ErrNoRate) {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			} else {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		for i := range similar {
+			similar[i].Car = cars[i]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(similar); err != nil {
+		span.RecordError(err)
+		log.Println("Error writing response:", err)
+	}
+}
@@ -0,0 +1,285 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/stream"
+	"go.opentelemetry.io/otel"
+)
+
+// writeTimeout bounds each write to a client, so a stalled connection is
+// dropped instead of holding its goroutine.
+const writeTimeout = 10 * time.Second
+
+type StreamHandler struct {
+	broker    *stream.Broker
+	heartbeat time.Duration
+	upgrader  websocket.Upgrader
+}
+
+// NewStreamHandler sends a heartbeat on idle streams every heartbeat.
+// WebSocket connections are accepted from the service's own origin and from
+// allowedOrigins.
+func NewStreamHandler(broker *stream.Broker, heartbeat time.Duration, allowedOrigins []string) *StreamHandler {
+	h := &StreamHandler{broker: broker, heartbeat: heartbeat}
+
+	h.upgrader = websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+
+			for _, allowed := range allowedOrigins {
+				if allowed == "*" || strings.EqualFold(allowed, origin) {
+					return true
+				}
+			}
+
+			u, err := url.Parse(origin)
+			return err == nil && strings.EqualFold(u.Host, r.Host)
+		},
+	}
+
+	return h
+}
+
+// SSE streams car and engine changes as Server-Sent Events. Each event's id
+// is its outbox sequence, so a reconnecting EventSource resumes with
+// Last-Event-ID. ?brand=, ?fuel_type= and ?types= filter the stream.
+func (h *StreamHandler) SSE(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("StreamHandler")
+
+	ctx, span := tracer.Start(r.Context(), "SSE-Handler")
+
+	defer span.End()
+
+	lastEventID, err := parseLastEventID(r.Header.Get("Last-Event-ID"), r.URL.Query().Get("last_event_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, replay, err := h.broker.Subscribe(ctx, parseFilter(r), lastEventID)
+	if err != nil {
+		span.RecordError(err)
+		writeSubscribeError(w, err)
+		return
+	}
+	defer h.broker.Unsubscribe(sub)
+
+	// The stream outlives the server's read and write timeouts; each write
+	// gets its own deadline instead.
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Println(err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	write := func(format string, args ...interface{}) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	writeEvent := func(event models.Event) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return write("id: %d\nevent: %s\ndata: %s\n\n", event.Sequence, event.Type, data)
+	}
+
+	if err := write("retry: 3000\n\n"); err != nil {
+		return
+	}
+
+	replayed := make(map[int64]bool, len(replay))
+	for _, event := range replay {
+		replayed[event.Sequence] = true
+		if err := writeEvent(event); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if replayed[event.Sequence] {
+				continue
+			}
+			if err := writeEvent(event); err != nil {
+				return
+			}
+		case now := <-heartbeat.C:
+			if err := write(": heartbeat %s\n\n", now.UTC().Format(time.RFC3339)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// WebSocket streams the same events as SSE, one JSON message per event,
+// using ping frames as heartbeats. Clients resume with ?last_event_id=.
+func (h *StreamHandler) WebSocket(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("StreamHandler")
+
+	ctx, span := tracer.Start(r.Context(), "WebSocket-Handler")
+
+	defer span.End()
+
+	lastEventID, err := parseLastEventID(r.Header.Get("Last-Event-ID"), r.URL.Query().Get("last_event_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, replay, err := h.broker.Subscribe(ctx, parseFilter(r), lastEventID)
+	if err != nil {
+		span.RecordError(err)
+		writeSubscribeError(w, err)
+		return
+	}
+	defer h.broker.Unsubscribe(sub)
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied.
+		span.RecordError(err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Clients send nothing but pongs; reading is still needed to process
+	// them and to notice the connection closing.
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+	})
+
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	writeEvent := func(event models.Event) error {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return conn.WriteJSON(event)
+	}
+
+	replayed := make(map[int64]bool, len(replay))
+	for _, event := range replay {
+		replayed[event.Sequence] = true
+		if err := writeEvent(event); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "reconnect with last_event_id"),
+					time.Now().Add(time.Second))
+				return
+			}
+			if replayed[event.Sequence] {
+				continue
+			}
+			if err := writeEvent(event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func parseFilter(r *http.Request) stream.Filter {
+	query := r.URL.Query()
+
+	filter := stream.Filter{
+		Brand:    query.Get("brand"),
+		FuelType: query.Get("fuel_type"),
+	}
+
+	if types := query.Get("types"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				filter.Types = append(filter.Types, t)
+			}
+		}
+	}
+
+	return filter
+}
+
+func parseLastEventID(values ...string) (int64, error) {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 0 {
+			return 0, errors.New("invalid Last-Event-ID")
+		}
+		return id, nil
+	}
+
+	return 0, nil
+}
+
+func writeSubscribeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, stream.ErrClosed) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	log.Println(err)
+	http.Error(w, "could not start stream", http.StatusInternalServerError)
+}
@@ -0,0 +1,219 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/service"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+type WebhookHandler struct {
+	service service.WebhookServiceInterface
+}
+
+func NewWebhookHandler(service service.WebhookServiceInterface) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+	}
+}
+
+func (h *WebhookHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("WebhookHandler")
+
+	ctx, span := tracer.Start(r.Context(), "CreateSubscription-Handler")
+
+	defer span.End()
+
+	var req models.WebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		span.RecordError(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.ValidateWebhookSubscriptionRequest(req); err != nil {
+		span.RecordError(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.service.CreateSubscription(ctx, &req)
+	if err != nil {
+		span.RecordError(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+func (h *WebhookHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("WebhookHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ListSubscriptions-Handler")
+
+	defer span.End()
+
+	subs, err := h.service.ListSubscriptions(ctx)
+	if err != nil {
+		span.RecordError(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, subs)
+}
+
+func (h *WebhookHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("WebhookHandler")
+
+	ctx, span := tracer.Start(r.Context(), "GetSubscription-Handler")
+
+	defer span.End()
+
+	id, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.GetSubscription(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sub)
+}
+
+func (h *WebhookHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("WebhookHandler")
+
+	ctx, span := tracer.Start(r.Context(), "DeleteSubscription-Handler")
+
+	defer span.End()
+
+	id, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSubscription(ctx, id); err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries returns a subscription's delivery log, newest first.
+// ?status=pending|delivered|dead filters it and ?limit= caps its length.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("WebhookHandler")
+
+	ctx, span := tracer.Start(r.Context(), "ListDeliveries-Handler")
+
+	defer span.End()
+
+	id, ok := parseID(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		http.Error(w, "status must be pending, delivered or dead", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultDeliveryLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxDeliveryLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxDeliveryLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.service.ListDeliveries(ctx, id, status, limit)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// Redeliver queues a delivery for an immediate new attempt, whatever its
+// current state.
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("WebhookHandler")
+
+	ctx, span := tracer.Start(r.Context(), "Redeliver-Handler")
+
+	defer span.End()
+
+	vars := mux.Vars(r)
+
+	id, ok := parseID(w, vars["id"])
+	if !ok {
+		return
+	}
+
+	deliveryID, ok := parseID(w, vars["deliveryId"])
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.Redeliver(ctx, id, deliveryID)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+func parseID(w http.ResponseWriter, value string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, models.ErrSubscriptionNotFound) || errors.Is(err, models.ErrDeliveryNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error writing response:", err)
+	}
+}
@@ -0,0 +1,145 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/tracing"
+)
+
+// ErrDegraded marks a check result as degraded rather than down.
+var ErrDegraded = errors.New("degraded")
+
+func isDegraded(err error) bool {
+	return errors.Is(err, ErrDegraded)
+}
+
+// DatabasePing pings the pool and reports degraded when the round trip takes
+// longer than maxLatency.
+func DatabasePing(db *sql.DB, maxLatency time.Duration) Check {
+	return Check{
+		Name:     "database",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			start := time.Now()
+
+			if err := db.PingContext(ctx); err != nil {
+				return "", err
+			}
+
+			latency := time.Since(start)
+			stats := db.Stats()
+			detail := fmt.Sprintf("ping %s, %d open / %d in use connections",
+				latency.Round(time.Microsecond), stats.OpenConnections, stats.InUse)
+
+			if latency > maxLatency {
+				return detail, fmt.Errorf("%w: ping took %s, above %s", ErrDegraded, latency.Round(time.Millisecond), maxLatency)
+			}
+
+			return detail, nil
+		},
+	}
+}
+
+// TablesExist verifies the schema has been applied.
+func TablesExist(db *sql.DB, tables ...string) Check {
+	return Check{
+		Name:     "schema",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			var missing []string
+
+			for _, table := range tables {
+				var found bool
+
+				err := db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", "public."+table).Scan(&found)
+				if err != nil {
+					return "", err
+				}
+
+				if !found {
+					missing = append(missing, table)
+				}
+			}
+
+			if len(missing) > 0 {
+				return "", fmt.Errorf("missing tables: %s", strings.Join(missing, ", "))
+			}
+
+			return fmt.Sprintf("tables present: %s", strings.Join(tables, ", ")), nil
+		},
+	}
+}
+
+// TraceExporter reports whether spans are reaching the collector. Tracing is
+// never critical: losing spans should not take the API out of rotation.
+func TraceExporter(exporter *tracing.Exporter) Check {
+	return Check{
+		Name: "trace_exporter",
+		Run: func(ctx context.Context) (string, error) {
+			status := exporter.Status()
+
+			switch {
+			case !status.Enabled:
+				return "tracing disabled", nil
+			case status.LastError != nil && status.LastFailure.After(status.LastSuccess):
+				return "", fmt.Errorf("%w: last export failed at %s: %v",
+					ErrDegraded, status.LastFailure.Format(time.RFC3339), status.LastError)
+			case status.LastSuccess.IsZero():
+				return "no spans exported yet", nil
+			}
+
+			return fmt.Sprintf("last export at %s", status.LastSuccess.Format(time.RFC3339)), nil
+		},
+	}
+}
+
+// Pinger is anything that can cheaply test its connection.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Cache reports whether the lookup cache is reachable. It is not critical:
+// on cache errors lookups fall through to the database.
+func Cache(backend Pinger) Check {
+	return Check{
+		Name: "cache",
+		Run: func(ctx context.Context) (string, error) {
+			if err := backend.Ping(ctx); err != nil {
+				return "", fmt.Errorf("%w: %v", ErrDegraded, err)
+			}
+			return "reachable", nil
+		},
+	}
+}
+
+// Replicas reports the read replicas out of rotation. It is not critical:
+// without replicas lookups are served by the primary.
+func Replicas(db *driver.DB) Check {
+	return Check{
+		Name: "replicas",
+		Run: func(ctx context.Context) (string, error) {
+			statuses := db.Replicas()
+
+			var down []string
+			for _, status := range statuses {
+				if !status.Healthy {
+					down = append(down, fmt.Sprintf("%s (%s)", status.Name, status.Error))
+				}
+			}
+
+			detail := fmt.Sprintf("%d of %d replicas in rotation", len(statuses)-len(down), len(statuses))
+
+			if len(down) > 0 {
+				return detail, fmt.Errorf("%w: out of rotation: %s", ErrDegraded, strings.Join(down, ", "))
+			}
+
+			return detail, nil
+		},
+	}
+}
@@ -0,0 +1,151 @@
+// Package health runs the dependency checks behind the liveness, readiness
+// and detailed health endpoints.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+)
+
+// Check probes one dependency. Critical checks decide readiness; the others
+// are reported but never take the service out of rotation.
+type Check struct {
+	Name     string
+	Critical bool
+	// Run returns a short human-readable detail, or an error when the
+	// dependency is unusable. Returning ErrDegraded (wrapped or not) marks
+	// the dependency as degraded instead of down.
+	Run func(ctx context.Context) (string, error)
+}
+
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Detail    string  `json:"detail,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status    string        `json:"status"`
+	Ready     bool          `json:"ready"`
+	Version   string        `json:"version"`
+	StartedAt time.Time     `json:"started_at"`
+	Uptime    string        `json:"uptime"`
+	Checks    []CheckResult `json:"checks"`
+}
+
+type Checker struct {
+	version   string
+	startedAt time.Time
+	timeout   time.Duration
+	ready     atomic.Bool
+
+	mu     sync.RWMutex
+	checks []Check
+}
+
+// NewChecker returns a Checker that starts out not ready. timeout bounds each
+// individual check.
+func NewChecker(version string, timeout time.Duration) *Checker {
+	return &Checker{
+		version:   version,
+		startedAt: time.Now(),
+		timeout:   timeout,
+	}
+}
+
+func (c *Checker) Register(checks ...Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, checks...)
+}
+
+// SetReady flips the readiness flag. The service marks itself ready once
+// startup has finished and unready as soon as shutdown begins, so load
+// balancers stop routing to it before connections are drained.
+func (c *Checker) SetReady(ready bool) {
+	c.ready.Store(ready)
+}
+
+func (c *Checker) Ready() bool {
+	return c.ready.Load()
+}
+
+// Run executes every check concurrently and summarises the results. The
+// report is ready only if the service is marked ready and no critical check
+// is down.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]Check(nil), c.checks...)
+	c.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:    StatusUp,
+		Ready:     c.Ready(),
+		Version:   c.version,
+		StartedAt: c.startedAt,
+		Uptime:    time.Since(c.startedAt).Round(time.Second).String(),
+		Checks:    results,
+	}
+
+	for _, res := range results {
+		switch {
+		case res.Status == StatusDown && res.Critical:
+			report.Status = StatusDown
+			report.Ready = false
+		case res.Status != StatusUp && report.Status == StatusUp:
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := check.Run(ctx)
+
+	res := CheckResult{
+		Name:      check.Name,
+		Status:    StatusUp,
+		Critical:  check.Critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		Detail:    detail,
+	}
+
+	if err != nil {
+		res.Status = StatusDown
+		if isDegraded(err) {
+			res.Status = StatusDegraded
+		}
+		res.Error = err.Error()
+	}
+
+	return res
+}
@@ -8,36 +8,95 @@ import (
 	"golang.org/x/net/context"
 )
 
-var jwtKey = []byte("some_value")
-
 type Claims struct {
 	UserName string `json:"username"`
+	Role     string `json:"role"`
 	jwt.StandardClaims
 }
 
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorizatiom")
+type roleKey struct{}
 
-		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
-			return
-		}
+// AuthMiddleware rejects requests without a valid bearer token signed with
+// jwtKey.
+func AuthMiddleware(jwtKey []byte) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+
+			if authHeader == "" {
+				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+				return
+			}
+
+			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+
+			claims := &Claims{}
+
+			token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+				return jwtKey, nil
+			})
+
+			if err != nil || !token.Valid {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+
+			// Older tokens carry the user only as the subject.
+			userName := claims.UserName
+			if userName == "" {
+				userName = claims.Subject
+			}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer")
+			ctx := context.WithValue(r.Context(), "username", userName)
+			ctx = context.WithValue(ctx, roleKey{}, claims.Role)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireRole rejects requests whose token does not carry one of roles. It
+// must run after AuthMiddleware. Tokens issued without a role are rejected.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := Role(r.Context())
 
-		claims := &Claims{}
+			for _, allowed := range roles {
+				if role != "" && role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
+			http.Error(w, "Insufficient role", http.StatusForbidden)
 		})
+	}
+}
 
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
+// UserName returns the user of the request's token.
+func UserName(ctx context.Context) string {
+	userName, _ := ctx.Value("username").(string)
+	return userName
+}
+
+// Role returns the role of the request's token, or "" when it has none.
+func Role(ctx context.Context) string {
+	role, _ := ctx.Value(roleKey{}).(string)
+	return role
+}
+
+// TokenFromQuery lets clients that cannot set headers, such as browser
+// EventSource and WebSocket, pass their token as ?access_token=. It must run
+// before AuthMiddleware and should only wrap the routes that need it, since
+// tokens in URLs can end up in access logs.
+func TokenFromQuery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			if token := r.URL.Query().Get("access_token"); token != "" {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
 		}
 
-		ctx := context.WithValue(r.Context(), "username", claims.UserName)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r)
 	})
 }
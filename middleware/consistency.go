@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/michgboxy2/carzone/driver"
+)
+
+const readPrimaryCookie = "carzone_read_primary"
+
+// ReadYourWrites sends a client's lookups to the primary for window after it
+// has written anything, so it never reads older data from a replica than it
+// has just stored. Within a request this is handled by the driver; across
+// requests the deadline travels in a cookie. window should cover the
+// replication lag the service tolerates.
+func ReadYourWrites(window time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			if cookie, err := r.Cookie(readPrimaryCookie); err == nil {
+				if until, err := strconv.ParseInt(cookie.Value, 10, 64); err == nil && time.Now().Unix() < until {
+					ctx = driver.WithPrimaryReads(ctx)
+				}
+			}
+
+			ctx, tracker := driver.TrackWrites(ctx)
+
+			next.ServeHTTP(&consistencyWriter{ResponseWriter: w, tracker: tracker, window: window}, r.WithContext(ctx))
+		})
+	}
+}
+
+// consistencyWriter sets the cookie just before the headers go out, since
+// the handler only knows whether it wrote once it has done its work.
+type consistencyWriter struct {
+	http.ResponseWriter
+	tracker     *driver.WriteTracker
+	window      time.Duration
+	wroteHeader bool
+}
+
+func (cw *consistencyWriter) WriteHeader(statusCode int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+
+		if cw.tracker.Written() {
+			http.SetCookie(cw.ResponseWriter, &http.Cookie{
+				Name:     readPrimaryCookie,
+				Value:    strconv.FormatInt(time.Now().Add(cw.window).Unix(), 10),
+				Path:     "/",
+				MaxAge:   int(cw.window.Seconds()) + 1,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (cw *consistencyWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *consistencyWriter) Flush() {
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (cw *consistencyWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+func (cw *consistencyWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
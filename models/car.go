@@ -2,31 +2,49 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/money"
 )
 
 type Car struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Year      string    `json:"year"`
-	Brand     string    `json:"brand"`
-	FuelType  string    `json:"fuel_type"`
-	Engine    Engine    `json:"engine"`
-	Price     float64   `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uuid.UUID   `json:"id"`
+	Name      string      `json:"name"`
+	Year      string      `json:"year"`
+	Brand     string      `json:"brand"`
+	Model     string      `json:"model,omitempty"`
+	FuelType  string      `json:"fuel_type"`
+	Engine    Engine      `json:"engine"`
+	Price     money.Money `json:"price"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+
+	CarSpecs
+	CarHistory
+
+	// OriginalPrice is set when Price has been converted for a ?currency=
+	// read, and holds the price as listed.
+	OriginalPrice *money.Money `json:"original_price,omitempty"`
 }
 
+// CarRequest is the payload for creating or updating a car. When
+// Engine.EngineID is empty on create, a new engine is created from the
+// given specs in the same transaction as the car.
 type CarRequest struct {
-	Name     string  `json:"name"`
-	Year     string  `json:"year"`
-	Brand    string  `json:"brand"`
-	FuelType string  `json:"fuel_type"`
-	Engine   Engine  `json:"engine"`
-	Price    float64 `json:"price"`
+	Name     string      `json:"name"`
+	Year     string      `json:"year"`
+	Brand    string      `json:"brand"`
+	Model    string      `json:"model,omitempty"`
+	FuelType string      `json:"fuel_type"`
+	Engine   Engine      `json:"engine"`
+	Price    money.Money `json:"price"`
+
+	CarSpecs
+	CarHistory
 }
 
 func ValidateRequest(carReq CarRequest) error {
@@ -38,7 +56,11 @@ func ValidateRequest(carReq CarRequest) error {
 		return err
 	}
 
-	if err := ValidateFuelType(carReq.FuelType); err != nil {
+	if err := validateBrand(carReq.Brand, carReq.Model); err != nil {
+		return err
+	}
+
+	if err := validateFuelType(carReq.FuelType); err != nil {
 		return err
 	}
 
@@ -50,6 +72,14 @@ func ValidateRequest(carReq CarRequest) error {
 		return err
 	}
 
+	if err := validateCarSpecs(carReq.CarSpecs); err != nil {
+		return err
+	}
+
+	if err := validateCarHistory(carReq.CarHistory); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -83,41 +113,41 @@ func validateYear(year string) error {
 	return nil
 }
 
-func ValidateFuelType(fuelType string) error {
-	validateFuelTypes := []string{"Petrol", "Diesel", "Electric", "Hybrid"}
+// validateBrand checks the brand and model as given; they are resolved
+// against the catalogue later.
+func validateBrand(brand, model string) error {
+	if CatalogName(brand) == "" {
+		return errors.New("brand is required")
+	}
 
-	for _, validType := range validateFuelTypes {
-		if fuelType == validType {
-			return nil
-		}
+	if len(model) > MaxModelNameLength {
+		return fmt.Errorf("model must be at most %d characters", MaxModelNameLength)
 	}
 
-	return errors.New("FuelTpe must be one of the above")
+	return nil
 }
 
-func validateEngine(engine Engine) error {
-	if engine.EngineID == uuid.Nil {
-		return errors.New("EngineID is required")
-	}
-
-	if engine.Displacement <= 0 {
-		return errors.New("displacement must be greater than zero")
+// validateFuelType checks the fuel type is given; it is resolved against
+// the fuel type registry later.
+func validateFuelType(fuelType string) error {
+	if strings.TrimSpace(fuelType) == "" {
+		return errors.New("fuel_type is required")
 	}
 
-	if engine.NoOfCylinders <= 0 {
-		return errors.New("noOfCylinders must be greater than zero")
-	}
+	return nil
+}
 
-	if engine.CarRange <= 0 {
-		return errors.New("carRange must be greater than zero")
+// validateEngine checks the specs of an engine to be created along with the
+// car. Whether an engine suits the car's fuel type is checked once the fuel
+// type has been resolved.
+func validateEngine(engine Engine) error {
+	if engine.EngineID != uuid.Nil {
+		return nil
 	}
 
-	return nil
+	return ValidateEngineRequest(engine.Request())
 }
 
-func validatePrice(price float64) error {
-	if price <= 0 {
-		return errors.New("price must be greater than zero")
-	}
-	return nil
+func validatePrice(price money.Money) error {
+	return price.Validate()
 }
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrBrandNotFound = errors.New("brand not found")
+	ErrBrandExists   = errors.New("brand name or alias already exists")
+	ErrBrandInUse    = errors.New("brand is used by cars or saved searches")
+	ErrModelNotFound = errors.New("model not found")
+	ErrModelExists   = errors.New("model name or alias already exists")
+	ErrModelInUse    = errors.New("model is used by cars")
+)
+
+// Longest names and aliases of a brand and of a model, in bytes.
+const (
+	MaxBrandNameLength = 50
+	MaxModelNameLength = 100
+)
+
+// Brand is an entry of the brand catalogue. Cars carry its Name; any of its
+// Aliases resolves to it too, and both match whatever their case.
+type Brand struct {
+	ID        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	Aliases   []string   `json:"aliases"`
+	Models    []CarModel `json:"models,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// CarModel is a model of a brand, such as the Toyota Camry. Its name and
+// aliases are unique within the brand only.
+type CarModel struct {
+	ID        uuid.UUID `json:"id"`
+	BrandID   uuid.UUID `json:"brand_id"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CatalogRequest is the payload for creating or updating a brand or a
+// model. Updating replaces all of its aliases.
+type CatalogRequest struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+// CatalogName tidies a brand or model name as typed, trimming it and
+// collapsing runs of spaces, so "  Land   Rover " becomes "Land Rover".
+func CatalogName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Normalize tidies the name and every alias with CatalogName.
+func (r *CatalogRequest) Normalize() {
+	r.Name = CatalogName(r.Name)
+
+	for i, alias := range r.Aliases {
+		r.Aliases[i] = CatalogName(alias)
+	}
+}
+
+// Names returns the name followed by the aliases.
+func (r CatalogRequest) Names() []string {
+	return append([]string{r.Name}, r.Aliases...)
+}
+
+func ValidateBrandRequest(req CatalogRequest) error {
+	return validateCatalogRequest(req, MaxBrandNameLength)
+}
+
+func ValidateModelRequest(req CatalogRequest) error {
+	return validateCatalogRequest(req, MaxModelNameLength)
+}
+
+// validateCatalogRequest checks a normalised request. No two of its names
+// may differ only in case, as they would resolve to the same entry.
+func validateCatalogRequest(req CatalogRequest, maxLength int) error {
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(req.Name) > maxLength {
+		return fmt.Errorf("name must be at most %d characters", maxLength)
+	}
+
+	seen := map[string]bool{strings.ToLower(req.Name): true}
+
+	for _, alias := range req.Aliases {
+		if alias == "" {
+			return errors.New("aliases must not be empty")
+		}
+
+		if len(alias) > maxLength {
+			return fmt.Errorf("alias %q must be at most %d characters", alias, maxLength)
+		}
+
+		if seen[strings.ToLower(alias)] {
+			return fmt.Errorf("alias %q repeats the name or another alias", alias)
+		}
+		seen[strings.ToLower(alias)] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,248 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/money"
+)
+
+// How many cars can be compared at once.
+const (
+	MinComparedCars = 2
+	MaxComparedCars = 5
+)
+
+// Comparison lays cars out side by side. Each attribute holds one value per
+// car, in the order of Cars, with null where the car does not have it.
+type Comparison struct {
+	Cars       []Car               `json:"cars"`
+	Attributes []ComparedAttribute `json:"attributes"`
+	// Differences names the attributes whose values are not all the same.
+	Differences []string `json:"differences"`
+	// Deltas compare each car with the first one.
+	Deltas []CarDelta `json:"deltas"`
+}
+
+type ComparedAttribute struct {
+	Name    string        `json:"name"`
+	Values  []interface{} `json:"values"`
+	Differs bool          `json:"differs"`
+}
+
+// CarDelta is how much a car's price, range and displacement exceed those
+// of the first car compared; negative when they fall short.
+type CarDelta struct {
+	CarID uuid.UUID `json:"car_id"`
+	// Price and PricePercent are left out when the prices are in different
+	// currencies.
+	Price        *money.Money `json:"price,omitempty"`
+	PricePercent *float64     `json:"price_percent,omitempty"`
+	Range        int64        `json:"range"`
+	Displacement int64        `json:"displacement"`
+}
+
+// ValidateComparedCars checks the IDs of the cars to compare.
+func ValidateComparedCars(ids []uuid.UUID) error {
+	if len(ids) < MinComparedCars || len(ids) > MaxComparedCars {
+		return fmt.Errorf("between %d and %d cars can be compared", MinComparedCars, MaxComparedCars)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("car %s is given more than once", id)
+		}
+		seen[id] = true
+	}
+
+	return nil
+}
+
+// comparedAttributes are the attributes cars are compared on. Each returns
+// a comparable value, or nil when the car does not have the attribute.
+var comparedAttributes = []struct {
+	name  string
+	value func(Car) interface{}
+}{
+	{"name", func(c Car) interface{} { return c.Name }},
+	{"year", func(c Car) interface{} { return c.Year }},
+	{"brand", func(c Car) interface{} { return c.Brand }},
+	{"model", func(c Car) interface{} { return text(c.Model) }},
+	{"fuel_type", func(c Car) interface{} { return c.FuelType }},
+	{"price", func(c Car) interface{} { return c.Price }},
+	{"trim", func(c Car) interface{} { return text(c.Trim) }},
+	{"transmission", func(c Car) interface{} { return text(c.Transmission) }},
+	{"drivetrain", func(c Car) interface{} { return text(c.Drivetrain) }},
+	{"body_style", func(c Car) interface{} { return text(c.BodyStyle) }},
+	{"colour", func(c Car) interface{} { return text(c.Colour) }},
+	{"doors", func(c Car) interface{} { return count(c.Doors) }},
+	{"seats", func(c Car) interface{} { return count(c.Seats) }},
+	{"mileage", func(c Car) interface{} {
+		if c.Mileage == nil {
+			return nil
+		}
+		return *c.Mileage
+	}},
+	{"condition", func(c Car) interface{} { return text(c.Condition) }},
+	{"previous_owners", func(c Car) interface{} { return countOf(c.PreviousOwners) }},
+	{"accidents", func(c Car) interface{} { return countOf(c.Accidents) }},
+	{"service_history", func(c Car) interface{} { return text(c.ServiceHistory) }},
+	{"engine.powertrain", func(c Car) interface{} { return text(c.Engine.Powertrain) }},
+	{"engine.displacement", func(c Car) interface{} { return c.Engine.Displacement }},
+	{"engine.noOfCylinders", func(c Car) interface{} { return c.Engine.NoOfCylinders }},
+	{"engine.carRange", func(c Car) interface{} { return c.Engine.CarRange }},
+	{"engine.motor.power_kw", func(c Car) interface{} {
+		if c.Engine.Motor == nil {
+			return nil
+		}
+		return c.Engine.Motor.PowerKW
+	}},
+	{"engine.motor.torque_nm", func(c Car) interface{} {
+		if c.Engine.Motor == nil {
+			return nil
+		}
+		return c.Engine.Motor.TorqueNm
+	}},
+	{"engine.battery.capacity_kwh", func(c Car) interface{} {
+		if c.Engine.Battery == nil {
+			return nil
+		}
+		return c.Engine.Battery.CapacityKWh
+	}},
+	{"engine.battery.charging_standards", func(c Car) interface{} {
+		if c.Engine.Battery == nil {
+			return nil
+		}
+		return text(strings.Join(c.Engine.Battery.ChargingStandards, ", "))
+	}},
+	{"engine.battery.max_ac_charge_kw", func(c Car) interface{} {
+		if c.Engine.Battery == nil {
+			return nil
+		}
+		return c.Engine.Battery.MaxACChargeKW
+	}},
+	{"engine.battery.max_dc_charge_kw", func(c Car) interface{} {
+		if c.Engine.Battery == nil {
+			return nil
+		}
+		return c.Engine.Battery.MaxDCChargeKW
+	}},
+	{"engine.combined.power_kw", func(c Car) interface{} {
+		if c.Engine.Combined == nil {
+			return nil
+		}
+		return c.Engine.Combined.PowerKW
+	}},
+	{"engine.combined.torque_nm", func(c Car) interface{} {
+		if c.Engine.Combined == nil {
+			return nil
+		}
+		return c.Engine.Combined.TorqueNm
+	}},
+}
+
+// NewComparison compares cars, which must have their engines. Attributes
+// none of the cars have are left out.
+func NewComparison(cars []Car) Comparison {
+	comparison := Comparison{
+		Cars:        cars,
+		Attributes:  []ComparedAttribute{},
+		Differences: []string{},
+		Deltas:      make([]CarDelta, len(cars)),
+	}
+
+	for _, attribute := range comparedAttributes {
+		values := make([]interface{}, len(cars))
+		known := false
+		for i, car := range cars {
+			values[i] = attribute.value(car)
+			known = known || values[i] != nil
+		}
+
+		if !known {
+			continue
+		}
+
+		differs := false
+		for _, value := range values[1:] {
+			differs = differs || value != values[0]
+		}
+
+		comparison.Attributes = append(comparison.Attributes, ComparedAttribute{
+			Name:    attribute.name,
+			Values:  values,
+			Differs: differs,
+		})
+
+		if differs {
+			comparison.Differences = append(comparison.Differences, attribute.name)
+		}
+	}
+
+	if len(cars) == 0 {
+		return comparison
+	}
+
+	base := cars[0]
+	for i, car := range cars {
+		delta := CarDelta{
+			CarID:        car.ID,
+			Range:        car.Engine.CarRange - base.Engine.CarRange,
+			Displacement: car.Engine.Displacement - base.Engine.Displacement,
+		}
+
+		if car.Price.Currency == base.Price.Currency {
+			delta.Price = &money.Money{Minor: car.Price.Minor - base.Price.Minor, Currency: car.Price.Currency}
+			delta.PricePercent = changePercent(base.Price, car.Price)
+		}
+
+		comparison.Deltas[i] = delta
+	}
+
+	return comparison
+}
+
+// text and count return nil for attributes that are not set, so they show
+// as null.
+func text(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func count(n int) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+func countOf(n *int) interface{} {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
+
+// ParseComparedCars reads a comma-separated list of car IDs, as given to
+// ?ids=.
+func ParseComparedCars(list string) ([]uuid.UUID, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, errors.New("ids is required")
+	}
+
+	var ids []uuid.UUID
+	for _, value := range strings.Split(list, ",") {
+		id, err := uuid.Parse(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid car ID %q", strings.TrimSpace(value))
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, ValidateComparedCars(ids)
+}
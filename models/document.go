@@ -0,0 +1,70 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DocumentTitle            = "title"
+	DocumentRegistration     = "registration"
+	DocumentInspectionReport = "inspection_report"
+	DocumentServiceRecord    = "service_record"
+	DocumentOther            = "other"
+)
+
+// DocumentCategories are the kinds of document that can be attached to a
+// car.
+var DocumentCategories = []string{
+	DocumentTitle,
+	DocumentRegistration,
+	DocumentInspectionReport,
+	DocumentServiceRecord,
+	DocumentOther,
+}
+
+var (
+	ErrDocumentNotFound    = errors.New("document not found")
+	ErrTooManyDocuments    = errors.New("car has reached its document limit")
+	ErrDocumentTooLarge    = errors.New("document is too large")
+	ErrUnsupportedDocument = errors.New("document must be a PDF, JPEG or PNG")
+)
+
+// CarDocument is a file such as a title or service invoice attached to a
+// car. Files are stored once per checksum, so the same file attached to
+// several cars shares its blob.
+type CarDocument struct {
+	ID          uuid.UUID `json:"id"`
+	CarID       uuid.UUID `json:"car_id"`
+	Category    string    `json:"category"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	// Checksum is the hex SHA-256 of the file.
+	Checksum   string    `json:"sha256"`
+	UploadedBy string    `json:"uploaded_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	BlobKey string `json:"-"`
+}
+
+// DocumentUpload describes a file being attached to a car.
+type DocumentUpload struct {
+	Category   string
+	FileName   string
+	UploadedBy string
+}
+
+func ValidateDocumentCategory(category string) error {
+	for _, c := range DocumentCategories {
+		if c == category {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("category must be one of %s", strings.Join(DocumentCategories, ", "))
+}
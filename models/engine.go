@@ -2,29 +2,96 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrEngineNotFound  = errors.New("engine not found")
+	ErrDuplicateEngine = errors.New("an engine with the same specs already exists")
+)
+
+// Engine catalogue sort keys. A leading "-" sorts in descending order.
+const (
+	EngineSortCreated      = "created"
+	EngineSortDisplacement = "displacement"
+	EngineSortCylinders    = "cylinders"
+	EngineSortRange        = "range"
+)
+
+const (
+	DefaultEnginePageSize = 20
+	MaxEnginePageSize     = 100
+)
+
+// Engine is a car's powertrain. Displacement and cylinders describe its
+// combustion engine, if any; Motor, Battery and Combined are only set for the
+// powertrains that have them (see ValidateEngineRequest).
 type Engine struct {
-	EngineID      uuid.UUID `json:"engine_id"`
-	Displacement  int64     `json:"displacement"`
-	NoOfCylinders int64     `json:"noOfCylinders"`
-	CarRange      int64     `json:"carRange"`
+	EngineID      uuid.UUID       `json:"engine_id"`
+	Powertrain    string          `json:"powertrain"`
+	Displacement  int64           `json:"displacement"`
+	NoOfCylinders int64           `json:"noOfCylinders"`
+	CarRange      int64           `json:"carRange"`
+	Motor         *Motor          `json:"motor,omitempty"`
+	Battery       *Battery        `json:"battery,omitempty"`
+	Combined      *CombinedRating `json:"combined,omitempty"`
 }
 
+// Request returns the engine's specs.
+func (e Engine) Request() EngineRequest {
+	return EngineRequest{
+		Powertrain:    e.Powertrain,
+		Displacement:  e.Displacement,
+		NoOfCylinders: e.NoOfCylinders,
+		CarRange:      e.CarRange,
+		Motor:         e.Motor,
+		Battery:       e.Battery,
+		Combined:      e.Combined,
+	}
+}
+
+// EngineRequest is the payload for creating or updating an engine. An empty
+// Powertrain means PowertrainCombustion.
 type EngineRequest struct {
-	Displacement  int64 `json:"displacement"`
-	NoOfCylinders int64 `json:"noOfCylinders"`
-	CarRange      int64 `json:"carRange"`
+	Powertrain    string          `json:"powertrain,omitempty"`
+	Displacement  int64           `json:"displacement"`
+	NoOfCylinders int64           `json:"noOfCylinders"`
+	CarRange      int64           `json:"carRange"`
+	Motor         *Motor          `json:"motor,omitempty"`
+	Battery       *Battery        `json:"battery,omitempty"`
+	Combined      *CombinedRating `json:"combined,omitempty"`
+}
+
+// Engine returns an engine with id and the requested specs.
+func (r EngineRequest) Engine(id uuid.UUID) Engine {
+	return Engine{
+		EngineID:      id,
+		Powertrain:    r.Powertrain,
+		Displacement:  r.Displacement,
+		NoOfCylinders: r.NoOfCylinders,
+		CarRange:      r.CarRange,
+		Motor:         r.Motor,
+		Battery:       r.Battery,
+		Combined:      r.Combined,
+	}
+}
+
+// Normalize fills in the default powertrain.
+func (r *EngineRequest) Normalize() {
+	if r.Powertrain == "" {
+		r.Powertrain = PowertrainCombustion
+	}
 }
 
 func ValidateEngineRequest(EngineReq EngineRequest) error {
-	if err := validateDisplacement(EngineReq.Displacement); err != nil {
+	if err := ValidatePowertrain(EngineReq.Powertrain); err != nil {
 		return err
 	}
 
-	if err := validateNoOfCylinders(EngineReq.NoOfCylinders); err != nil {
+	if err := validatePowertrainSpecs(EngineReq); err != nil {
 		return err
 	}
 
@@ -55,3 +122,83 @@ func validateCarRange(carRange int64) error {
 	}
 	return nil
 }
+
+// DuplicateEngineError is returned instead of creating an engine whose specs
+// match an existing one, which should be used instead.
+type DuplicateEngineError struct {
+	Existing Engine
+}
+
+func (e *DuplicateEngineError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrDuplicateEngine, e.Existing.EngineID)
+}
+
+func (e *DuplicateEngineError) Unwrap() error {
+	return ErrDuplicateEngine
+}
+
+// EngineFilter selects a page of the engine catalogue. Zero bounds are not
+// applied.
+type EngineFilter struct {
+	Powertrain      string
+	MinDisplacement int64
+	MaxDisplacement int64
+	Cylinders       int64
+	MinRange        int64
+	MaxRange        int64
+	Sort            string
+	Limit           int
+	Offset          int
+}
+
+// SortKey splits Sort into its key and direction.
+func (f EngineFilter) SortKey() (key string, desc bool) {
+	if f.Sort == "" {
+		return EngineSortCreated, false
+	}
+	return strings.TrimPrefix(f.Sort, "-"), strings.HasPrefix(f.Sort, "-")
+}
+
+func (f EngineFilter) Validate() error {
+	if f.MinDisplacement < 0 || f.MaxDisplacement < 0 || f.Cylinders < 0 || f.MinRange < 0 || f.MaxRange < 0 {
+		return errors.New("engine filters must not be negative")
+	}
+
+	if err := ValidatePowertrain(f.Powertrain); err != nil {
+		return err
+	}
+
+	if f.MaxDisplacement > 0 && f.MinDisplacement > f.MaxDisplacement {
+		return errors.New("min_displacement must not exceed max_displacement")
+	}
+
+	if f.MaxRange > 0 && f.MinRange > f.MaxRange {
+		return errors.New("min_range must not exceed max_range")
+	}
+
+	switch key, _ := f.SortKey(); key {
+	case EngineSortCreated, EngineSortDisplacement, EngineSortCylinders, EngineSortRange:
+	default:
+		return fmt.Errorf("sort must be one of %s, %s, %s or %s, optionally prefixed with -",
+			EngineSortCreated, EngineSortDisplacement, EngineSortCylinders, EngineSortRange)
+	}
+
+	if f.Limit <= 0 || f.Limit > MaxEnginePageSize {
+		return fmt.Errorf("limit must be between 1 and %d", MaxEnginePageSize)
+	}
+
+	if f.Offset < 0 {
+		return errors.New("offset must not be negative")
+	}
+
+	return nil
+}
+
+// EnginePage is one page of the engine catalogue. Total counts every engine
+// matching the filter.
+type EnginePage struct {
+	Engines []Engine `json:"engines"`
+	Total   int      `json:"total"`
+	Limit   int      `json:"limit"`
+	Offset  int      `json:"offset"`
+}
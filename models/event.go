@@ -0,0 +1,72 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/money"
+)
+
+const (
+	AggregateCar    = "car"
+	AggregateEngine = "engine"
+)
+
+const (
+	EventCarCreated      = "CarCreated"
+	EventCarUpdated      = "CarUpdated"
+	EventCarPriceChanged = "CarPriceChanged"
+	EventCarDeleted      = "CarDeleted"
+
+	EventEngineCreated = "EngineCreated"
+	EventEngineUpdated = "EngineUpdated"
+	EventEngineDeleted = "EngineDeleted"
+)
+
+// Event is a domain event as stored in the outbox and handed to the sinks.
+// Sequence is assigned by the outbox and orders events globally; events for
+// the same aggregate are always published in that order.
+type Event struct {
+	ID            uuid.UUID       `json:"id"`
+	Sequence      int64           `json:"sequence"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   uuid.UUID       `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Payload       json.RawMessage `json:"payload"`
+	// Attempts is how many times the relay has failed to publish the event.
+	Attempts int `json:"-"`
+}
+
+// CarPriceChange is the payload of a CarPriceChanged event.
+type CarPriceChange struct {
+	CarID    uuid.UUID   `json:"car_id"`
+	Brand    string      `json:"brand"`
+	FuelType string      `json:"fuel_type"`
+	OldPrice money.Money `json:"old_price"`
+	NewPrice money.Money `json:"new_price"`
+}
+
+// NewEvent builds an event with payload encoded as JSON.
+func NewEvent(eventType, aggregateType string, aggregateID uuid.UUID, payload interface{}) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ID:            uuid.New(),
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		OccurredAt:    time.Now().UTC(),
+		Payload:       data,
+	}, nil
+}
+
+// OrderingKey identifies the aggregate; sinks use it to keep that
+// aggregate's events in order.
+func (e Event) OrderingKey() string {
+	return e.AggregateType + ":" + e.AggregateID.String()
+}
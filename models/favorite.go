@@ -0,0 +1,17 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrTooManyFavorites = errors.New("too many favorites")
+
+// MaxFavorites is the most cars a user can favorite.
+const MaxFavorites = 200
+
+// Favorite is a car a user has favorited.
+type Favorite struct {
+	Car         Car       `json:"car"`
+	FavoritedAt time.Time `json:"favorited_at"`
+}
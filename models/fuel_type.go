@@ -0,0 +1,157 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrFuelTypeNotFound   = errors.New("fuel type not found")
+	ErrFuelTypeExists     = errors.New("fuel type code or synonym already exists")
+	ErrFuelTypeInUse      = errors.New("fuel type is used by cars")
+	ErrFuelTypeDeprecated = errors.New("fuel type is deprecated")
+)
+
+// Units of a fuel type's emission factor: kilograms of CO2 equivalent per
+// litre of fuel or per kilowatt-hour of electricity.
+const (
+	EmissionPerLitre = "kg_co2e_per_litre"
+	EmissionPerKWh   = "kg_co2e_per_kwh"
+)
+
+var EmissionUnits = []string{EmissionPerLitre, EmissionPerKWh}
+
+var fuelTypeCode = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,19}$`)
+
+// FuelType is an entry of the fuel type registry. Cars carry its Code, and
+// the code or any of its Synonyms, in any case, resolves to it. A
+// deprecated fuel type stays on the cars that have it but cannot be given
+// to any other.
+type FuelType struct {
+	Code     string   `json:"code"`
+	Name     string   `json:"name"`
+	Synonyms []string `json:"synonyms"`
+	// Powertrains are those a car of this fuel type may have.
+	Powertrains []string `json:"powertrains"`
+	Deprecated  bool     `json:"deprecated"`
+	// EmissionFactor is the tailpipe emissions of burning one EmissionUnit
+	// of the fuel.
+	EmissionFactor float64   `json:"emission_factor"`
+	EmissionUnit   string    `json:"emission_unit"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Names returns the code followed by the synonyms.
+func (f FuelType) Names() []string {
+	return append([]string{f.Code}, f.Synonyms...)
+}
+
+// FuelTypeRequest is the payload for creating or updating a fuel type.
+// Updating replaces every field but the code, which cars refer to.
+type FuelTypeRequest struct {
+	Code           string   `json:"code"`
+	Name           string   `json:"name"`
+	Synonyms       []string `json:"synonyms"`
+	Powertrains    []string `json:"powertrains"`
+	Deprecated     bool     `json:"deprecated"`
+	EmissionFactor float64  `json:"emission_factor"`
+	EmissionUnit   string   `json:"emission_unit"`
+}
+
+// FuelType returns the fuel type the request describes.
+func (r FuelTypeRequest) FuelType() FuelType {
+	return FuelType{
+		Code:           r.Code,
+		Name:           r.Name,
+		Synonyms:       r.Synonyms,
+		Powertrains:    r.Powertrains,
+		Deprecated:     r.Deprecated,
+		EmissionFactor: r.EmissionFactor,
+		EmissionUnit:   r.EmissionUnit,
+	}
+}
+
+// Normalize trims the name and tidies the synonyms like catalogue names.
+func (r *FuelTypeRequest) Normalize() {
+	r.Code = strings.TrimSpace(r.Code)
+	r.Name = strings.TrimSpace(r.Name)
+
+	for i, synonym := range r.Synonyms {
+		r.Synonyms[i] = CatalogName(synonym)
+	}
+
+	if r.Synonyms == nil {
+		r.Synonyms = []string{}
+	}
+}
+
+func ValidateFuelTypeCode(code string) error {
+	if !fuelTypeCode.MatchString(code) {
+		return errors.New("code must be 1 to 20 letters, digits, dashes or underscores, starting with a letter")
+	}
+	return nil
+}
+
+// ValidateFuelTypeRequest checks a normalised request. No two of its code
+// and synonyms may differ only in case, as they would resolve to the same
+// fuel type.
+func ValidateFuelTypeRequest(req FuelTypeRequest) error {
+	if err := ValidateFuelTypeCode(req.Code); err != nil {
+		return err
+	}
+
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(req.Name) > 100 {
+		return errors.New("name must be at most 100 characters")
+	}
+
+	seen := map[string]bool{strings.ToLower(req.Code): true}
+
+	for _, synonym := range req.Synonyms {
+		if synonym == "" {
+			return errors.New("synonyms must not be empty")
+		}
+
+		if len(synonym) > 50 {
+			return fmt.Errorf("synonym %q must be at most 50 characters", synonym)
+		}
+
+		if seen[strings.ToLower(synonym)] {
+			return fmt.Errorf("synonym %q repeats the code or another synonym", synonym)
+		}
+		seen[strings.ToLower(synonym)] = true
+	}
+
+	if len(req.Powertrains) == 0 {
+		return errors.New("powertrains must list at least one powertrain")
+	}
+
+	for _, powertrain := range req.Powertrains {
+		if powertrain == "" {
+			return errors.New("powertrains must not be empty")
+		}
+
+		if err := ValidatePowertrain(powertrain); err != nil {
+			return err
+		}
+	}
+
+	if req.EmissionFactor < 0 {
+		return errors.New("emission_factor must not be negative")
+	}
+
+	for _, unit := range EmissionUnits {
+		if req.EmissionUnit == unit {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("emission_unit must be one of %s", strings.Join(EmissionUnits, ", "))
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var ErrMileageDecreased = errors.New("mileage must not decrease")
+
+// Units an odometer reading can be given in.
+const (
+	MileageKilometres = "km"
+	MileageMiles      = "mi"
+)
+
+var MileageUnits = []string{MileageKilometres, MileageMiles}
+
+// MaxMileage bounds an odometer reading, in whatever unit it is given.
+const MaxMileage = 9_999_999
+
+const kilometresPerMile = 1.609344
+
+// ConditionGrades grade a car's condition, best first.
+var ConditionGrades = []string{"new", "excellent", "good", "fair", "poor"}
+
+// ServiceHistories describe how much of a car's service record is known.
+var ServiceHistories = []string{"full", "partial", "none"}
+
+// Mileage is an odometer reading in whole kilometres or miles.
+type Mileage struct {
+	Value int64  `json:"value"`
+	Unit  string `json:"unit"`
+}
+
+// Kilometres returns the reading in kilometres.
+func (m Mileage) Kilometres() float64 {
+	if m.Unit == MileageMiles {
+		return float64(m.Value) * kilometresPerMile
+	}
+	return float64(m.Value)
+}
+
+// RoundedKilometres returns the reading in kilometres to two decimal
+// places, as cars store it for filtering.
+func (m Mileage) RoundedKilometres() float64 {
+	return math.Round(m.Kilometres()*100) / 100
+}
+
+func (m Mileage) String() string {
+	return fmt.Sprintf("%d %s", m.Value, m.Unit)
+}
+
+// CarHistory is what is known of a used car's past. Fields that are not
+// known are left out; PreviousOwners and Accidents are pointers as zero is
+// a meaningful count.
+type CarHistory struct {
+	Mileage        *Mileage `json:"mileage,omitempty"`
+	Condition      string   `json:"condition,omitempty"`
+	PreviousOwners *int     `json:"previous_owners,omitempty"`
+	// Accidents counts the accidents the car is known to have had.
+	Accidents      *int   `json:"accidents,omitempty"`
+	ServiceHistory string `json:"service_history,omitempty"`
+}
+
+func validateCarHistory(history CarHistory) error {
+	if m := history.Mileage; m != nil {
+		if m.Value < 0 || m.Value > MaxMileage {
+			return fmt.Errorf("mileage.value must be between 0 and %d", MaxMileage)
+		}
+
+		if err := ValidateMileageUnit(m.Unit); err != nil {
+			return err
+		}
+	}
+
+	if history.Condition != "" && conditionRank(history.Condition) < 0 {
+		return fmt.Errorf("condition must be one of %s", strings.Join(ConditionGrades, ", "))
+	}
+
+	if n := history.PreviousOwners; n != nil && (*n < 0 || *n > 99) {
+		return errors.New("previous_owners must be between 0 and 99")
+	}
+
+	if n := history.Accidents; n != nil && (*n < 0 || *n > 99) {
+		return errors.New("accidents must be between 0 and 99")
+	}
+
+	if history.ServiceHistory != "" && !contains(ServiceHistories, history.ServiceHistory) {
+		return fmt.Errorf("service_history must be one of %s", strings.Join(ServiceHistories, ", "))
+	}
+
+	return nil
+}
+
+func ValidateMileageUnit(unit string) error {
+	if !contains(MileageUnits, unit) {
+		return fmt.Errorf("mileage unit must be one of %s", strings.Join(MileageUnits, ", "))
+	}
+	return nil
+}
+
+// ValidateMileageChange checks that an update does not wind the odometer
+// back or drop a recorded reading. As readings are whole units, the new one
+// may fall short of the current one by less than one of its units, which
+// allows a reading to be restated in the other unit.
+func ValidateMileageChange(current, next *Mileage) error {
+	if current == nil {
+		return nil
+	}
+
+	if next == nil {
+		return fmt.Errorf("%w: the car has a recorded mileage of %s", ErrMileageDecreased, current)
+	}
+
+	unit := Mileage{Value: 1, Unit: next.Unit}.Kilometres()
+	if next.Kilometres()+unit <= current.Kilometres() {
+		return fmt.Errorf("%w: %s is less than the recorded %s", ErrMileageDecreased, next, current)
+	}
+
+	return nil
+}
+
+// ConditionsAtLeast returns the grades as good as grade or better.
+func ConditionsAtLeast(grade string) []string {
+	rank := conditionRank(grade)
+	if rank < 0 {
+		return nil
+	}
+	return ConditionGrades[:rank+1]
+}
+
+func conditionRank(grade string) int {
+	for i, g := range ConditionGrades {
+		if g == grade {
+			return i
+		}
+	}
+	return -1
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// CarFilter narrows a car listing by history. Zero values are not applied.
+// The mileage bounds are in MileageUnit and include cars whose reading
+// converts to within them; cars without the filtered fields are left out.
+type CarFilter struct {
+	MinMileage     int64
+	MaxMileage     int64
+	MileageUnit    string
+	MinCondition   string
+	MaxOwners      *int
+	AccidentFree   bool
+	ServiceHistory string
+}
+
+func (f CarFilter) Validate() error {
+	if f.MinMileage < 0 || f.MaxMileage < 0 {
+		return errors.New("mileage filters must not be negative")
+	}
+
+	if f.MaxMileage > 0 && f.MinMileage > f.MaxMileage {
+		return errors.New("min_mileage must not exceed max_mileage")
+	}
+
+	if err := ValidateMileageUnit(f.MileageUnit); err != nil {
+		return err
+	}
+
+	if f.MinCondition != "" && conditionRank(f.MinCondition) < 0 {
+		return fmt.Errorf("min_condition must be one of %s", strings.Join(ConditionGrades, ", "))
+	}
+
+	if f.MaxOwners != nil && *f.MaxOwners < 0 {
+		return errors.New("max_owners must not be negative")
+	}
+
+	if f.ServiceHistory != "" && !contains(ServiceHistories, f.ServiceHistory) {
+		return fmt.Errorf("service_history must be one of %s", strings.Join(ServiceHistories, ", "))
+	}
+
+	return nil
+}
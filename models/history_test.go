@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+func TestMileageRoundedKilometres(t *testing.T) {
+	tests := []struct {
+		mileage Mileage
+		want    float64
+	}{
+		{Mileage{Value: 0, Unit: MileageKilometres}, 0},
+		{Mileage{Value: 100, Unit: MileageKilometres}, 100},
+		{Mileage{Value: 1, Unit: MileageMiles}, 1.61},
+		{Mileage{Value: 3, Unit: MileageMiles}, 4.83},
+		{Mileage{Value: 5, Unit: MileageMiles}, 8.05},
+		{Mileage{Value: 7, Unit: MileageMiles}, 11.27},
+		{Mileage{Value: 100, Unit: MileageMiles}, 160.93},
+		{Mileage{Value: MaxMileage, Unit: MileageMiles}, 16093438.39},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mileage.String(), func(t *testing.T) {
+			if got := tt.mileage.RoundedKilometres(); got != tt.want {
+				t.Fatalf("%v.RoundedKilometres() = %v, want %v", tt.mileage, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ImageOriginal  = "original"
+	ImageThumbnail = "thumbnail"
+)
+
+var (
+	ErrCarNotFound        = errors.New("car not found")
+	ErrImageNotFound      = errors.New("image not found")
+	ErrTooManyImages      = errors.New("car has reached its image limit")
+	ErrImageTooLarge      = errors.New("image is too large")
+	ErrUnsupportedImage   = errors.New("image must be a JPEG, PNG, GIF or WebP")
+	ErrImageOrderMismatch = errors.New("image_ids must list each of the car's images exactly once")
+)
+
+// CarImage is a photo of a car. Images are shown in Position order, and
+// exactly one of a car's images is its cover.
+type CarImage struct {
+	ID          uuid.UUID `json:"id"`
+	CarID       uuid.UUID `json:"car_id"`
+	Position    int       `json:"position"`
+	IsCover     bool      `json:"is_cover"`
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// URL and ThumbnailURL are signed and expire; clients should fetch
+	// the image list again rather than store them.
+	URL          string `json:"url,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+
+	OriginalKey  string `json:"-"`
+	ThumbnailKey string `json:"-"`
+}
+
+// ImageOrderRequest lists all of a car's images in their new order.
+type ImageOrderRequest struct {
+	ImageIDs []uuid.UUID `json:"image_ids"`
+}
+
+func ValidateImageOrderRequest(req ImageOrderRequest) error {
+	if len(req.ImageIDs) == 0 {
+		return errors.New("image_ids is required")
+	}
+
+	seen := make(map[uuid.UUID]bool, len(req.ImageIDs))
+	for _, id := range req.ImageIDs {
+		if seen[id] {
+			return ErrImageOrderMismatch
+		}
+		seen[id] = true
+	}
+
+	return nil
+}
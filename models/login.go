@@ -1,6 +1,31 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+)
+
+// Roles carried in API tokens. The admin login is always RoleAdmin; tokens
+// for the other roles are issued with `carzone token issue -role`.
+const (
+	RoleAdmin  = "admin"
+	RoleDealer = "dealer"
+	RoleViewer = "viewer"
+)
+
+var Roles = []string{RoleAdmin, RoleDealer, RoleViewer}
+
 type Credentials struct {
 	UserName string `json:"userName"`
 	Password string `json:"password"`
 }
+
+func ValidateRole(role string) error {
+	for _, r := range Roles {
+		if r == role {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("role must be one of %s", strings.Join(Roles, ", "))
+}
@@ -0,0 +1,225 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	PowertrainCombustion   = "combustion"
+	PowertrainElectric     = "electric"
+	PowertrainHybrid       = "hybrid"
+	PowertrainPluginHybrid = "plug_in_hybrid"
+)
+
+// ErrPowertrainMismatch is returned for a car whose fuel type its engine's
+// powertrain cannot run on.
+var ErrPowertrainMismatch = errors.New("powertrain does not suit the fuel type")
+
+var Powertrains = []string{PowertrainCombustion, PowertrainElectric, PowertrainHybrid, PowertrainPluginHybrid}
+
+// ChargingStandards are the connectors a battery can be charged through.
+var ChargingStandards = []string{"Type1", "Type2", "CCS1", "CCS2", "CHAdeMO", "NACS", "GB/T"}
+
+// Motor is the electric drive motor of an electric or hybrid powertrain.
+type Motor struct {
+	PowerKW  int64 `json:"power_kw"`
+	TorqueNm int64 `json:"torque_nm"`
+}
+
+// Battery is the traction battery of an electric or hybrid powertrain. Only
+// batteries that can be plugged in have charging standards and power.
+type Battery struct {
+	CapacityKWh       float64  `json:"capacity_kwh"`
+	ChargingStandards []string `json:"charging_standards,omitempty"`
+	MaxACChargeKW     float64  `json:"max_ac_charge_kw,omitempty"`
+	MaxDCChargeKW     float64  `json:"max_dc_charge_kw,omitempty"`
+}
+
+// CombinedRating is the output of a hybrid's engine and motor together.
+type CombinedRating struct {
+	PowerKW  int64 `json:"power_kw"`
+	TorqueNm int64 `json:"torque_nm"`
+}
+
+// ValidatePowertrain accepts the known powertrains. An empty one stands for
+// PowertrainCombustion, which is what every engine was before powertrains
+// existed.
+func ValidatePowertrain(powertrain string) error {
+	if powertrain == "" {
+		return nil
+	}
+
+	for _, p := range Powertrains {
+		if powertrain == p {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("powertrain must be one of %s", strings.Join(Powertrains, ", "))
+}
+
+// ValidatePowertrainFuelType checks that a car of fuelType can be driven by
+// powertrain.
+func ValidatePowertrainFuelType(fuelType FuelType, powertrain string) error {
+	if powertrain == "" {
+		powertrain = PowertrainCombustion
+	}
+
+	for _, p := range fuelType.Powertrains {
+		if powertrain == p {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: a %s car needs a %s powertrain, not %s", ErrPowertrainMismatch, fuelType.Code, strings.Join(fuelType.Powertrains, " or "), powertrain)
+}
+
+// validatePowertrainSpecs checks the specs that depend on the powertrain.
+// Combustion engines have neither motor nor battery, electric ones have no
+// displacement or cylinders, and hybrids have both plus a combined rating.
+func validatePowertrainSpecs(req EngineRequest) error {
+	powertrain := req.Powertrain
+	if powertrain == "" {
+		powertrain = PowertrainCombustion
+	}
+
+	combustion := powertrain != PowertrainElectric
+	hybrid := powertrain == PowertrainHybrid || powertrain == PowertrainPluginHybrid
+
+	if combustion {
+		if err := validateDisplacement(req.Displacement); err != nil {
+			return err
+		}
+
+		if err := validateNoOfCylinders(req.NoOfCylinders); err != nil {
+			return err
+		}
+	} else if req.Displacement != 0 || req.NoOfCylinders != 0 {
+		return errors.New("an electric powertrain has no displacement or cylinders")
+	}
+
+	if powertrain == PowertrainCombustion {
+		if req.Motor != nil || req.Battery != nil || req.Combined != nil {
+			return errors.New("a combustion powertrain has no motor, battery or combined rating")
+		}
+		return nil
+	}
+
+	if err := validateMotor(req.Motor); err != nil {
+		return err
+	}
+
+	switch powertrain {
+	case PowertrainElectric, PowertrainPluginHybrid:
+		if err := validateBattery(req.Battery, true); err != nil {
+			return err
+		}
+	case PowertrainHybrid:
+		if req.Battery != nil {
+			if err := validateBattery(req.Battery, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !hybrid {
+		if req.Combined != nil {
+			return errors.New("only hybrid powertrains have a combined rating")
+		}
+		return nil
+	}
+
+	return validateCombined(req.Combined, req.Motor)
+}
+
+func validateMotor(motor *Motor) error {
+	if motor == nil {
+		return errors.New("motor is required for electric and hybrid powertrains")
+	}
+
+	if motor.PowerKW <= 0 {
+		return errors.New("motor power_kw must be greater than zero")
+	}
+
+	if motor.TorqueNm <= 0 {
+		return errors.New("motor torque_nm must be greater than zero")
+	}
+
+	return nil
+}
+
+// validateBattery checks a battery. A pluggable battery needs at least one
+// charging standard and an AC or DC charge power; any other battery must
+// have neither.
+func validateBattery(battery *Battery, pluggable bool) error {
+	if battery == nil {
+		return errors.New("battery is required for electric and plug-in hybrid powertrains")
+	}
+
+	if battery.CapacityKWh <= 0 {
+		return errors.New("battery capacity_kwh must be greater than zero")
+	}
+
+	if battery.MaxACChargeKW < 0 || battery.MaxDCChargeKW < 0 {
+		return errors.New("battery charge power must not be negative")
+	}
+
+	if !pluggable {
+		if len(battery.ChargingStandards) > 0 || battery.MaxACChargeKW > 0 || battery.MaxDCChargeKW > 0 {
+			return errors.New("only electric and plug-in hybrid batteries can be charged")
+		}
+		return nil
+	}
+
+	if len(battery.ChargingStandards) == 0 {
+		return errors.New("battery charging_standards must list at least one standard")
+	}
+
+	seen := make(map[string]bool, len(battery.ChargingStandards))
+	for _, standard := range battery.ChargingStandards {
+		if !isChargingStandard(standard) {
+			return fmt.Errorf("charging standard %q must be one of %s", standard, strings.Join(ChargingStandards, ", "))
+		}
+		if seen[standard] {
+			return fmt.Errorf("charging standard %q is listed twice", standard)
+		}
+		seen[standard] = true
+	}
+
+	if battery.MaxACChargeKW == 0 && battery.MaxDCChargeKW == 0 {
+		return errors.New("battery needs a max_ac_charge_kw or max_dc_charge_kw")
+	}
+
+	return nil
+}
+
+func isChargingStandard(standard string) bool {
+	for _, s := range ChargingStandards {
+		if standard == s {
+			return true
+		}
+	}
+	return false
+}
+
+func validateCombined(combined *CombinedRating, motor *Motor) error {
+	if combined == nil {
+		return errors.New("combined rating is required for hybrid powertrains")
+	}
+
+	if combined.PowerKW <= 0 {
+		return errors.New("combined power_kw must be greater than zero")
+	}
+
+	if combined.TorqueNm <= 0 {
+		return errors.New("combined torque_nm must be greater than zero")
+	}
+
+	if combined.PowerKW < motor.PowerKW {
+		return errors.New("combined power_kw must be at least the motor's power_kw")
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/money"
+)
+
+// PricePoint is a price a car was listed at from ChangedAt until the next
+// point.
+type PricePoint struct {
+	Price     money.Money `json:"price"`
+	ChangedAt time.Time   `json:"changed_at"`
+	// ChangePercent is the change from the previous price. It is left out
+	// for the first price and when the currency changed.
+	ChangePercent *float64 `json:"change_percent,omitempty"`
+}
+
+// PriceHistory is the timeline of a car's prices, oldest first, with the
+// figures derived from it.
+type PriceHistory struct {
+	CarID         uuid.UUID   `json:"car_id"`
+	ListedAt      time.Time   `json:"listed_at"`
+	OriginalPrice money.Money `json:"original_price"`
+	CurrentPrice  money.Money `json:"current_price"`
+	// DaysOnMarket counts whole days since the car was listed, and
+	// DaysAtCurrentPrice those since its price last changed.
+	DaysOnMarket       int `json:"days_on_market"`
+	DaysAtCurrentPrice int `json:"days_at_current_price"`
+	// Reductions and Increases count the changes within a currency.
+	Reductions int `json:"reductions"`
+	Increases  int `json:"increases"`
+	// ChangePercent is the change from the original price to the current
+	// one. It is left out when the currency changed in between.
+	ChangePercent *float64     `json:"change_percent,omitempty"`
+	Prices        []PricePoint `json:"prices"`
+}
+
+// NewPriceHistory derives the history of car from its price points as of
+// now. A car without points, such as one listed before prices were
+// recorded, gets one for its current price.
+func NewPriceHistory(car Car, points []PricePoint, now time.Time) PriceHistory {
+	if len(points) == 0 {
+		points = []PricePoint{{Price: car.Price, ChangedAt: car.CreatedAt}}
+	}
+
+	history := PriceHistory{
+		CarID:              car.ID,
+		ListedAt:           car.CreatedAt,
+		OriginalPrice:      points[0].Price,
+		CurrentPrice:       points[len(points)-1].Price,
+		DaysOnMarket:       daysBetween(car.CreatedAt, now),
+		DaysAtCurrentPrice: daysBetween(points[len(points)-1].ChangedAt, now),
+		Prices:             points,
+	}
+
+	for i := 1; i < len(points); i++ {
+		previous, current := points[i-1].Price, points[i].Price
+		if previous.Currency != current.Currency {
+			continue
+		}
+
+		points[i].ChangePercent = changePercent(previous, current)
+
+		switch {
+		case current.Minor < previous.Minor:
+			history.Reductions++
+		case current.Minor > previous.Minor:
+			history.Increases++
+		}
+	}
+
+	if history.OriginalPrice.Currency == history.CurrentPrice.Currency {
+		history.ChangePercent = changePercent(history.OriginalPrice, history.CurrentPrice)
+	}
+
+	return history
+}
+
+// changePercent returns the change from one price to another of the same
+// currency, in percent rounded to two decimal places.
+func changePercent(from, to money.Money) *float64 {
+	if from.IsZero() {
+		return nil
+	}
+
+	change := math.Round(float64(to.Minor-from.Minor)/float64(from.Minor)*10000) / 100
+	return &change
+}
+
+func daysBetween(from, to time.Time) int {
+	if to.Before(from) {
+		return 0
+	}
+	return int(to.Sub(from).Hours() / 24)
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/money"
+)
+
+func TestNewPriceHistory(t *testing.T) {
+	listed := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := listed.Add(30*24*time.Hour + 12*time.Hour)
+	day := func(n int) time.Time { return listed.Add(time.Duration(n) * 24 * time.Hour) }
+	eur := func(minor int64) money.Money { return money.Money{Minor: minor, Currency: "EUR"} }
+	usd := func(minor int64) money.Money { return money.Money{Minor: minor, Currency: "USD"} }
+	percent := func(v float64) *float64 { return &v }
+
+	car := Car{ID: uuid.New(), Price: eur(2000000), CreatedAt: listed}
+
+	tests := []struct {
+		name   string
+		points []PricePoint
+		now    time.Time
+		want   PriceHistory
+	}{
+		{
+			name: "no points starts at the current price",
+			now:  now,
+			want: PriceHistory{
+				OriginalPrice: eur(2000000), CurrentPrice: eur(2000000),
+				DaysOnMarket: 30, DaysAtCurrentPrice: 30,
+				ChangePercent: percent(0),
+				Prices:        []PricePoint{{Price: eur(2000000), ChangedAt: listed}},
+			},
+		},
+		{
+			name: "reductions",
+			points: []PricePoint{
+				{Price: eur(2000000), ChangedAt: listed},
+				{Price: eur(1900000), ChangedAt: day(10)},
+				{Price: eur(1850000), ChangedAt: day(20)},
+			},
+			now: now,
+			want: PriceHistory{
+				OriginalPrice: eur(2000000), CurrentPrice: eur(1850000),
+				DaysOnMarket: 30, DaysAtCurrentPrice: 10,
+				Reductions:    2,
+				ChangePercent: percent(-7.5),
+				Prices: []PricePoint{
+					{Price: eur(2000000), ChangedAt: listed},
+					{Price: eur(1900000), ChangedAt: day(10), ChangePercent: percent(-5)},
+					{Price: eur(1850000), ChangedAt: day(20), ChangePercent: percent(-2.63)},
+				},
+			},
+		},
+		{
+			name: "increase, unchanged and reduction",
+			points: []PricePoint{
+				{Price: eur(10000), ChangedAt: listed},
+				{Price: eur(11000), ChangedAt: day(1)},
+				{Price: eur(11000), ChangedAt: day(2)},
+				{Price: eur(9900), ChangedAt: day(3)},
+			},
+			now: now,
+			want: PriceHistory{
+				OriginalPrice: eur(10000), CurrentPrice: eur(9900),
+				DaysOnMarket: 30, DaysAtCurrentPrice: 27,
+				Reductions: 1, Increases: 1,
+				ChangePercent: percent(-1),
+				Prices: []PricePoint{
+					{Price: eur(10000), ChangedAt: listed},
+					{Price: eur(11000), ChangedAt: day(1), ChangePercent: percent(10)},
+					{Price: eur(11000), ChangedAt: day(2), ChangePercent: percent(0)},
+					{Price: eur(9900), ChangedAt: day(3), ChangePercent: percent(-10)},
+				},
+			},
+		},
+		{
+			name: "currency change is not counted",
+			points: []PricePoint{
+				{Price: eur(2000000), ChangedAt: listed},
+				{Price: usd(2100000), ChangedAt: day(5)},
+				{Price: usd(2000000), ChangedAt: day(15)},
+			},
+			now: now,
+			want: PriceHistory{
+				OriginalPrice: eur(2000000), CurrentPrice: usd(2000000),
+				DaysOnMarket: 30, DaysAtCurrentPrice: 15,
+				Reductions: 1,
+				Prices: []PricePoint{
+					{Price: eur(2000000), ChangedAt: listed},
+					{Price: usd(2100000), ChangedAt: day(5)},
+					{Price: usd(2000000), ChangedAt: day(15), ChangePercent: percent(-4.76)},
+				},
+			},
+		},
+		{
+			name: "clock behind the listing",
+			now:  listed.Add(-time.Hour),
+			want: PriceHistory{
+				OriginalPrice: eur(2000000), CurrentPrice: eur(2000000),
+				ChangePercent: percent(0),
+				Prices:        []PricePoint{{Price: eur(2000000), ChangedAt: listed}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.CarID = car.ID
+			tt.want.ListedAt = listed
+
+			got := NewPriceHistory(car, tt.points, tt.now)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("NewPriceHistory() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+package models
+
+import "errors"
+
+var ErrRateBaseMismatch = errors.New("exchange rates must be given against the configured base currency")
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Reference kinds: the tables of values a car's specs are chosen from.
+const (
+	ReferenceTransmissions = "transmissions"
+	ReferenceDrivetrains   = "drivetrains"
+	ReferenceBodyStyles    = "body_styles"
+	ReferenceColours       = "colours"
+)
+
+var ReferenceKinds = []string{ReferenceTransmissions, ReferenceDrivetrains, ReferenceBodyStyles, ReferenceColours}
+
+var (
+	ErrReferenceNotFound = errors.New("reference value not found")
+	ErrReferenceExists   = errors.New("reference value already exists")
+	ErrReferenceInUse    = errors.New("reference value is used by cars")
+)
+
+var referenceCode = regexp.MustCompile(`^[a-z0-9][a-z0-9_]{0,29}$`)
+
+// ReferenceValue is one of the values of a reference kind, such as the
+// "cvt" transmission. Cars refer to it by Code, which never changes.
+type ReferenceValue struct {
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ReferenceValueRequest struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+func ValidateReferenceKind(kind string) error {
+	for _, k := range ReferenceKinds {
+		if kind == k {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown reference kind %q", kind)
+}
+
+func ValidateReferenceCode(code string) error {
+	if !referenceCode.MatchString(code) {
+		return errors.New("code must be 1 to 30 lowercase letters, digits or underscores")
+	}
+	return nil
+}
+
+func ValidateReferenceName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("name is required")
+	}
+	if len(name) > 100 {
+		return errors.New("name must be at most 100 characters")
+	}
+	return nil
+}
+
+// CarSpecs are the optional trim and configuration details of a car. The
+// transmission, drivetrain, body style and colour are codes of the
+// corresponding reference values.
+type CarSpecs struct {
+	Trim         string `json:"trim,omitempty"`
+	Transmission string `json:"transmission,omitempty"`
+	Drivetrain   string `json:"drivetrain,omitempty"`
+	BodyStyle    string `json:"body_style,omitempty"`
+	Colour       string `json:"colour,omitempty"`
+	Doors        int    `json:"doors,omitempty"`
+	Seats        int    `json:"seats,omitempty"`
+}
+
+// References maps each reference kind to the code the specs use, leaving
+// out the ones that are not set.
+func (s CarSpecs) References() map[string]string {
+	refs := make(map[string]string)
+
+	for kind, code := range map[string]string{
+		ReferenceTransmissions: s.Transmission,
+		ReferenceDrivetrains:   s.Drivetrain,
+		ReferenceBodyStyles:    s.BodyStyle,
+		ReferenceColours:       s.Colour,
+	} {
+		if code != "" {
+			refs[kind] = code
+		}
+	}
+
+	return refs
+}
+
+func validateCarSpecs(specs CarSpecs) error {
+	if len(specs.Trim) > 50 {
+		return errors.New("trim must be at most 50 characters")
+	}
+
+	if specs.Doors < 0 || specs.Doors > 7 {
+		return errors.New("doors must be between 1 and 7")
+	}
+
+	if specs.Seats < 0 || specs.Seats > 12 {
+		return errors.New("seats must be between 1 and 12")
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/money"
+)
+
+var (
+	ErrSearchNotFound  = errors.New("saved search not found")
+	ErrTooManySearches = errors.New("too many saved searches")
+	// ErrEmailRequired is reported for a search without an email while
+	// alerts are sent by email.
+	ErrEmailRequired = errors.New("email is required as alerts are sent by email")
+)
+
+// How often a saved search's alerts are sent. Instant alerts go out as soon
+// as the digester next runs.
+const (
+	DigestInstant = "instant"
+	DigestHourly  = "hourly"
+	DigestDaily   = "daily"
+	DigestWeekly  = "weekly"
+)
+
+var DigestFrequencies = []string{DigestInstant, DigestHourly, DigestDaily, DigestWeekly}
+
+// DigestInterval returns the least time between two digests of a search.
+func DigestInterval(frequency string) time.Duration {
+	switch frequency {
+	case DigestHourly:
+		return time.Hour
+	case DigestDaily:
+		return 24 * time.Hour
+	case DigestWeekly:
+		return 7 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// Kinds of saved search alert.
+const (
+	AlertNewMatch  = "new_match"
+	AlertPriceDrop = "price_drop"
+)
+
+// MaxSearchNameLength is the longest name of a saved search, in bytes.
+const MaxSearchNameLength = 100
+
+// SavedSearch is a car listing query a user is alerted about when a car
+// starts to match it or a matching car gets cheaper. Criteria left empty
+// match any car; the price bounds share a currency and cars listed in
+// another are converted to it.
+type SavedSearch struct {
+	ID        uuid.UUID    `json:"id"`
+	Owner     string       `json:"owner"`
+	Name      string       `json:"name"`
+	Brand     string       `json:"brand,omitempty"`
+	FuelType  string       `json:"fuel_type,omitempty"`
+	MinPrice  *money.Money `json:"min_price,omitempty"`
+	MaxPrice  *money.Money `json:"max_price,omitempty"`
+	MinYear   int          `json:"min_year,omitempty"`
+	MaxYear   int          `json:"max_year,omitempty"`
+	Frequency string       `json:"frequency"`
+	// Email is where the smtp notifier sends the digests.
+	Email          string     `json:"email,omitempty"`
+	LastNotifiedAt *time.Time `json:"last_notified_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// BrandID is the catalogue brand Brand names.
+	BrandID *uuid.UUID `json:"-"`
+}
+
+// Currency returns the currency of the price bounds, or "" when there are
+// none.
+func (s SavedSearch) Currency() string {
+	switch {
+	case s.MinPrice != nil:
+		return s.MinPrice.Currency
+	case s.MaxPrice != nil:
+		return s.MaxPrice.Currency
+	default:
+		return ""
+	}
+}
+
+// MatchesYear reports whether a car of the given model year is within the
+// search's years.
+func (s SavedSearch) MatchesYear(year string) bool {
+	if s.MinYear == 0 && s.MaxYear == 0 {
+		return true
+	}
+
+	y, err := strconv.Atoi(year)
+	if err != nil {
+		return false
+	}
+
+	return (s.MinYear == 0 || y >= s.MinYear) && (s.MaxYear == 0 || y <= s.MaxYear)
+}
+
+// MatchesPrice reports whether price, in the currency of the search, is
+// within its bounds.
+func (s SavedSearch) MatchesPrice(price money.Money) bool {
+	if s.MinPrice != nil && price.Minor < s.MinPrice.Minor {
+		return false
+	}
+	return s.MaxPrice == nil || price.Minor <= s.MaxPrice.Minor
+}
+
+// SavedSearchRequest is the payload for saving or updating a search. Brand
+// and FuelType take any name or alias the catalogue and the fuel type
+// registry know.
+type SavedSearchRequest struct {
+	Name      string       `json:"name"`
+	Brand     string       `json:"brand,omitempty"`
+	FuelType  string       `json:"fuel_type,omitempty"`
+	MinPrice  *money.Money `json:"min_price,omitempty"`
+	MaxPrice  *money.Money `json:"max_price,omitempty"`
+	MinYear   int          `json:"min_year,omitempty"`
+	MaxYear   int          `json:"max_year,omitempty"`
+	Frequency string       `json:"frequency,omitempty"`
+	Email     string       `json:"email,omitempty"`
+}
+
+// Normalize tidies the request; searches are sent daily unless told
+// otherwise.
+func (r *SavedSearchRequest) Normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Brand = CatalogName(r.Brand)
+	r.FuelType = strings.TrimSpace(r.FuelType)
+	r.Email = strings.TrimSpace(r.Email)
+
+	if r.Frequency == "" {
+		r.Frequency = DigestDaily
+	}
+}
+
+// ValidateSavedSearchRequest checks a normalised request. A search needs at
+// least one criterion, or it would match every car.
+func ValidateSavedSearchRequest(req SavedSearchRequest) error {
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(req.Name) > MaxSearchNameLength {
+		return fmt.Errorf("name must be at most %d characters", MaxSearchNameLength)
+	}
+
+	if req.Brand == "" && req.FuelType == "" && req.MinPrice == nil && req.MaxPrice == nil && req.MinYear == 0 && req.MaxYear == 0 {
+		return errors.New("at least one of brand, fuel_type, min_price, max_price, min_year and max_year is required")
+	}
+
+	for _, price := range []*money.Money{req.MinPrice, req.MaxPrice} {
+		if price == nil {
+			continue
+		}
+
+		if err := price.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if req.MinPrice != nil && req.MaxPrice != nil {
+		if req.MinPrice.Currency != req.MaxPrice.Currency {
+			return errors.New("min_price and max_price must be in the same currency")
+		}
+
+		if req.MinPrice.Minor > req.MaxPrice.Minor {
+			return errors.New("min_price must not exceed max_price")
+		}
+	}
+
+	currentYear := time.Now().Year()
+	for _, year := range []int{req.MinYear, req.MaxYear} {
+		if year != 0 && (year < 1886 || year > currentYear+1) {
+			return fmt.Errorf("years must be between 1886 and %d", currentYear+1)
+		}
+	}
+
+	if req.MinYear != 0 && req.MaxYear != 0 && req.MinYear > req.MaxYear {
+		return errors.New("min_year must not exceed max_year")
+	}
+
+	if !contains(DigestFrequencies, req.Frequency) {
+		return fmt.Errorf("frequency must be one of %s", strings.Join(DigestFrequencies, ", "))
+	}
+
+	if req.Email != "" {
+		if addr, err := mail.ParseAddress(req.Email); err != nil || addr.Address != req.Email {
+			return errors.New("email must be a plain email address")
+		}
+	}
+
+	return nil
+}
+
+// SearchAlert is a car that newly matched a saved search, or a matching
+// car's price drop from OldPrice. Car is the car as it was when the alert
+// was raised.
+type SearchAlert struct {
+	ID         int64        `json:"id"`
+	SearchID   uuid.UUID    `json:"search_id"`
+	Kind       string       `json:"kind"`
+	Car        Car          `json:"car"`
+	OldPrice   *money.Money `json:"old_price,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	NotifiedAt *time.Time   `json:"notified_at,omitempty"`
+}
+
+// SearchDigest is a saved search's alerts that are due to be sent, oldest
+// first.
+type SearchDigest struct {
+	Search SavedSearch   `json:"search"`
+	Alerts []SearchAlert `json:"alerts"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "github.com/google/uuid"
+
+// MaxSimilarCars is the most similar cars returned for a car.
+const MaxSimilarCars = 50
+
+// SimilarCar is a car found to be like another. Distance runs from 0 for a
+// car that matches on everything compared to 1 for one that matches on
+// nothing.
+type SimilarCar struct {
+	Car      Car     `json:"car"`
+	Distance float64 `json:"distance"`
+}
+
+// SimilarityWeights say how much each part of the distance between two cars
+// counts. A zero weight leaves that part out.
+type SimilarityWeights struct {
+	Brand    float64
+	FuelType float64
+	Year     float64
+	Price    float64
+	Engine   float64
+}
+
+// Total is the sum of the weights, which the weighted distance is divided by
+// so that it stays between 0 and 1.
+func (w SimilarityWeights) Total() float64 {
+	return w.Brand + w.FuelType + w.Year + w.Price + w.Engine
+}
+
+// Similarity is how cars are ranked against each other. Cars YearSpan or
+// more years apart are as unlike in year as they can be, as are cars whose
+// prices differ by PriceBand of the car's price or more.
+type Similarity struct {
+	Weights   SimilarityWeights
+	YearSpan  int
+	PriceBand float64
+	// Currency is the base of the exchange rates prices are compared in.
+	Currency string
+}
+
+// RankedCar is a car's place in a ranking, before the car itself is
+// loaded.
+type RankedCar struct {
+	CarID    uuid.UUID `json:"car_id"`
+	Distance float64   `json:"distance"`
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	// DeliveryDead marks a delivery that ran out of attempts. It stays in
+	// the log until redelivered by hand.
+	DeliveryDead = "dead"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+)
+
+// WebhookEventTypes are the events partners can subscribe to.
+var WebhookEventTypes = []string{
+	EventCarCreated,
+	EventCarUpdated,
+	EventCarPriceChanged,
+	EventCarDeleted,
+	EventEngineCreated,
+	EventEngineUpdated,
+	EventEngineDeleted,
+}
+
+type WebhookSubscription struct {
+	ID         uuid.UUID `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	// Secret signs the deliveries. It is only returned when the
+	// subscription is created.
+	Secret    string    `json:"secret,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WebhookSubscriptionRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Secret is optional; one is generated when empty.
+	Secret string `json:"secret,omitempty"`
+}
+
+// WebhookDelivery is one event sent to one subscription, with the outcome of
+// its latest attempt.
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// DueDelivery is a delivery claimed by the dispatcher together with where
+// to send it.
+type DueDelivery struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+func ValidateWebhookSubscriptionRequest(req WebhookSubscriptionRequest) error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+
+	if len(req.EventTypes) == 0 {
+		return errors.New("at least one event type is required")
+	}
+
+	for _, eventType := range req.EventTypes {
+		if !isWebhookEventType(eventType) {
+			return fmt.Errorf("unknown event type %q, must be one of %v", eventType, WebhookEventTypes)
+		}
+	}
+
+	if req.Secret != "" && len(req.Secret) < 16 {
+		return errors.New("secret must be at least 16 characters")
+	}
+
+	return nil
+}
+
+func isWebhookEventType(eventType string) bool {
+	for _, known := range WebhookEventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+// Package money handles prices as exact amounts in an ISO 4217 currency,
+// and converts them between currencies with exchange rates.
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount held as a whole number of the currency's minor unit,
+// e.g. cents, so that it never picks up floating-point rounding errors.
+//
+// In JSON it is {"amount": "24000.00", "currency": "EUR"}. A bare number is
+// also read, as US dollars, for clients written before prices had a
+// currency.
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+// Parse reads a decimal amount such as "24000.50" in currency. Digits past
+// the currency's minor unit are only accepted when they are zero, as in the
+// "24000.5000" returned by the database.
+func Parse(amount, currency string) (Money, error) {
+	digits, ok := MinorUnits(currency)
+	if !ok {
+		return Money{}, ValidateCurrency(currency)
+	}
+
+	s := strings.TrimSpace(amount)
+
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if (whole == "" && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("invalid amount %q", amount)
+	}
+
+	if len(frac) > digits {
+		if strings.Trim(frac[digits:], "0") != "" {
+			return Money{}, fmt.Errorf("%s amounts have at most %d decimal places", currency, digits)
+		}
+		frac = frac[:digits]
+	}
+	frac += strings.Repeat("0", digits-len(frac))
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("amount %q is out of range", amount)
+	}
+
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Amount formats the amount with exactly the currency's decimal places.
+func (m Money) Amount() string {
+	digits, _ := MinorUnits(m.Currency)
+
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+	}
+
+	s := strconv.FormatUint(absUint(minor), 10)
+	if digits == 0 {
+		return sign + s
+	}
+
+	if len(s) <= digits {
+		s = strings.Repeat("0", digits-len(s)+1) + s
+	}
+
+	return sign + s[:len(s)-digits] + "." + s[len(s)-digits:]
+}
+
+func absUint(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+// String formats m as "24000.00 EUR".
+func (m Money) String() string {
+	return m.Amount() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Minor > 0
+}
+
+// Validate reports whether m has a known currency and a positive amount.
+func (m Money) Validate() error {
+	if m.Currency == "" {
+		return errors.New("price is required")
+	}
+	if err := ValidateCurrency(m.Currency); err != nil {
+		return err
+	}
+	if !m.IsPositive() {
+		return errors.New("price must be greater than zero")
+	}
+	return nil
+}
+
+type jsonMoney struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	amount, err := json.Marshal(m.Amount())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonMoney{Amount: amount, Currency: m.Currency})
+}
+
+// MarshalYAML writes the same shape as the JSON encoding.
+func (m Money) MarshalYAML() (interface{}, error) {
+	return struct {
+		Amount   string `yaml:"amount"`
+		Currency string `yaml:"currency"`
+	}{m.Amount(), m.Currency}, nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	if bytes.Equal(data, []byte("null")) {
+		*m = Money{}
+		return nil
+	}
+
+	if len(data) == 0 || data[0] != '{' {
+		parsed, err := Parse(string(data), USD)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	}
+
+	var raw jsonMoney
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	if len(raw.Amount) == 0 {
+		return errors.New("amount is required")
+	}
+
+	// The amount may be given as a string or a number.
+	amount := string(raw.Amount)
+	if raw.Amount[0] == '"' {
+		if err := json.Unmarshal(raw.Amount, &amount); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := Parse(amount, strings.ToUpper(raw.Currency))
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,151 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		currency string
+		want     int64
+		wantErr  string
+	}{
+		{name: "two decimals", amount: "24000.50", currency: "EUR", want: 2400050},
+		{name: "one decimal", amount: "24000.5", currency: "EUR", want: 2400050},
+		{name: "whole", amount: "24000", currency: "EUR", want: 2400000},
+		{name: "trailing dot", amount: "5.", currency: "USD", want: 500},
+		{name: "leading dot", amount: ".5", currency: "USD", want: 50},
+		{name: "surrounding space", amount: " 12.30 ", currency: "USD", want: 1230},
+		{name: "plus sign", amount: "+5", currency: "USD", want: 500},
+		{name: "negative", amount: "-5.25", currency: "USD", want: -525},
+		{name: "zero padded from the database", amount: "24000.5000", currency: "EUR", want: 2400050},
+		{name: "excess decimals", amount: "24000.505", currency: "EUR", wantErr: "at most 2 decimal places"},
+		{name: "no minor unit", amount: "1500", currency: "JPY", want: 1500},
+		{name: "no minor unit zero padded", amount: "1500.0", currency: "JPY", want: 1500},
+		{name: "no minor unit with decimals", amount: "1500.5", currency: "JPY", wantErr: "at most 0 decimal places"},
+		{name: "three decimals", amount: "1.234", currency: "KWD", want: 1234},
+		{name: "three decimals exceeded", amount: "1.2345", currency: "KWD", wantErr: "at most 3 decimal places"},
+		{name: "empty", amount: "", currency: "USD", wantErr: "invalid amount"},
+		{name: "dot only", amount: ".", currency: "USD", wantErr: "invalid amount"},
+		{name: "letters", amount: "abc", currency: "USD", wantErr: "invalid amount"},
+		{name: "exponent", amount: "1e3", currency: "USD", wantErr: "invalid amount"},
+		{name: "two dots", amount: "1.2.3", currency: "USD", wantErr: "invalid amount"},
+		{name: "out of range", amount: "99999999999999999999", currency: "USD", wantErr: "out of range"},
+		{name: "unknown currency", amount: "10", currency: "XYZ", wantErr: "not an ISO 4217 code"},
+		{name: "no currency", amount: "10", currency: "", wantErr: "currency is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.amount, tt.currency)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse(%q, %q) = %v, %v; want error containing %q", tt.amount, tt.currency, got, err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse(%q, %q) = %v", tt.amount, tt.currency, err)
+			}
+			if want := (Money{Minor: tt.want, Currency: tt.currency}); got != want {
+				t.Fatalf("Parse(%q, %q) = %+v, want %+v", tt.amount, tt.currency, got, want)
+			}
+		})
+	}
+}
+
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{Minor: 2400050, Currency: "EUR"}, "24000.50"},
+		{Money{Minor: 5, Currency: "USD"}, "0.05"},
+		{Money{Minor: -5, Currency: "USD"}, "-0.05"},
+		{Money{Minor: 0, Currency: "USD"}, "0.00"},
+		{Money{Minor: 1500, Currency: "JPY"}, "1500"},
+		{Money{Minor: -1500, Currency: "JPY"}, "-1500"},
+		{Money{Minor: 1234, Currency: "KWD"}, "1.234"},
+		{Money{Minor: 7, Currency: "KWD"}, "0.007"},
+		{Money{Minor: math.MinInt64, Currency: "USD"}, "-92233720368547758.08"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want+" "+tt.money.Currency, func(t *testing.T) {
+			if got := tt.money.Amount(); got != tt.want {
+				t.Fatalf("%+v.Amount() = %q, want %q", tt.money, got, tt.want)
+			}
+
+			// Parse reads the magnitude before the sign, so the most
+			// negative amount is out of its range.
+			if tt.money.Minor == math.MinInt64 {
+				return
+			}
+
+			parsed, err := Parse(tt.want, tt.money.Currency)
+			if err != nil || parsed != tt.money {
+				t.Fatalf("Parse(%q) = %+v, %v; want %+v", tt.want, parsed, err, tt.money)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Money
+		wantErr string
+	}{
+		{name: "string amount", data: `{"amount": "24000.50", "currency": "EUR"}`, want: Money{Minor: 2400050, Currency: "EUR"}},
+		{name: "number amount", data: `{"amount": 24000.5, "currency": "EUR"}`, want: Money{Minor: 2400050, Currency: "EUR"}},
+		{name: "lower case currency", data: `{"amount": "10", "currency": "jpy"}`, want: Money{Minor: 10, Currency: "JPY"}},
+		{name: "bare number is dollars", data: `24000`, want: Money{Minor: 2400000, Currency: "USD"}},
+		{name: "null", data: `null`, want: Money{}},
+		{name: "missing amount", data: `{"currency": "EUR"}`, wantErr: "amount is required"},
+		{name: "excess decimals", data: `{"amount": "1.005", "currency": "USD"}`, wantErr: "at most 2 decimal places"},
+		{name: "excess decimals as a number", data: `{"amount": 1.005, "currency": "USD"}`, wantErr: "at most 2 decimal places"},
+		{name: "exponent", data: `{"amount": 1e3, "currency": "USD"}`, wantErr: "invalid amount"},
+		{name: "unknown currency", data: `{"amount": "10", "currency": "ABC"}`, wantErr: "not an ISO 4217 code"},
+		{name: "bare number with excess decimals", data: `1.005`, wantErr: "at most 2 decimal places"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := json.Unmarshal([]byte(tt.data), &got)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Unmarshal(%s) = %+v, %v; want error containing %q", tt.data, got, err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unmarshal(%s) = %v", tt.data, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Unmarshal(%s) = %+v, want %+v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(Money{Minor: 2400050, Currency: "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"amount":"24000.50","currency":"EUR"}`; string(data) != want {
+		t.Fatalf("Marshal = %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,186 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// rateDecimals is how many decimal places rates are written with.
+const rateDecimals = 12
+
+var ErrNoRate = errors.New("no exchange rate for currency")
+
+// Rates are exchange rates against a base currency: one unit of Base buys
+// Rates[c] units of c. The base itself always has a rate of one.
+//
+// In JSON the rates are decimal strings, so they keep their precision:
+// {"base": "USD", "rates": {"EUR": "0.92"}}.
+type Rates struct {
+	Base      string
+	Rates     map[string]*big.Rat
+	UpdatedAt time.Time
+}
+
+// ParseRate reads a positive decimal rate such as "0.9215".
+func ParseRate(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+
+	rate, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "/eE") {
+		return nil, fmt.Errorf("invalid rate %q", s)
+	}
+	if rate.Sign() <= 0 {
+		return nil, fmt.Errorf("rate %q must be positive", s)
+	}
+
+	return rate, nil
+}
+
+// FormatRate writes a rate as a decimal without trailing zeros.
+func FormatRate(rate *big.Rat) string {
+	s := rate.FloatString(rateDecimals)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Validate reports unknown currencies and missing or non-positive rates.
+func (r Rates) Validate() error {
+	if err := ValidateCurrency(r.Base); err != nil {
+		return fmt.Errorf("base: %w", err)
+	}
+
+	for currency, rate := range r.Rates {
+		if err := ValidateCurrency(currency); err != nil {
+			return err
+		}
+		if rate == nil || rate.Sign() <= 0 {
+			return fmt.Errorf("rate for %s must be positive", currency)
+		}
+	}
+
+	return nil
+}
+
+func (r Rates) rate(currency string) (*big.Rat, error) {
+	if currency == r.Base {
+		return big.NewRat(1, 1), nil
+	}
+
+	rate, ok := r.Rates[currency]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrNoRate, currency)
+	}
+
+	return rate, nil
+}
+
+// Convert expresses m in currency to. The exact result is rounded to the
+// target currency's minor unit, with halves going to the even neighbour
+// (banker's rounding) so repeated conversions do not drift upwards.
+func (r Rates) Convert(m Money, to string) (Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+
+	toDigits, ok := MinorUnits(to)
+	if !ok {
+		return Money{}, ValidateCurrency(to)
+	}
+	fromDigits, _ := MinorUnits(m.Currency)
+
+	fromRate, err := r.rate(m.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	toRate, err := r.rate(to)
+	if err != nil {
+		return Money{}, err
+	}
+
+	// minor * 10^toDigits * toRate / (10^fromDigits * fromRate)
+	value := new(big.Rat).SetInt64(m.Minor)
+	value.Mul(value, new(big.Rat).SetInt(pow10(toDigits)))
+	value.Mul(value, toRate)
+	value.Quo(value, new(big.Rat).SetInt(pow10(fromDigits)))
+	value.Quo(value, fromRate)
+
+	minor := roundHalfEven(value)
+	if !minor.IsInt64() {
+		return Money{}, fmt.Errorf("%s in %s is out of range", m, to)
+	}
+
+	return Money{Minor: minor.Int64(), Currency: to}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// roundHalfEven rounds r to the nearest integer, taking the even one on a
+// tie.
+func roundHalfEven(r *big.Rat) *big.Int {
+	quo, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+
+	twiceRem := new(big.Int).Abs(rem)
+	twiceRem.Lsh(twiceRem, 1)
+
+	switch twiceRem.Cmp(r.Denom()) {
+	case 1:
+		return quo.Add(quo, big.NewInt(int64(r.Sign())))
+	case 0:
+		if quo.Bit(0) == 1 {
+			return quo.Add(quo, big.NewInt(int64(r.Sign())))
+		}
+	}
+
+	return quo
+}
+
+type jsonRates struct {
+	Base      string            `json:"base"`
+	Rates     map[string]string `json:"rates"`
+	UpdatedAt *time.Time        `json:"updated_at,omitempty"`
+}
+
+func (r Rates) MarshalJSON() ([]byte, error) {
+	out := jsonRates{Base: r.Base, Rates: make(map[string]string, len(r.Rates))}
+
+	for currency, rate := range r.Rates {
+		out.Rates[currency] = FormatRate(rate)
+	}
+
+	if !r.UpdatedAt.IsZero() {
+		out.UpdatedAt = &r.UpdatedAt
+	}
+
+	return json.Marshal(out)
+}
+
+func (r *Rates) UnmarshalJSON(data []byte) error {
+	var in jsonRates
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	rates := Rates{Base: strings.ToUpper(in.Base), Rates: make(map[string]*big.Rat, len(in.Rates))}
+
+	for currency, s := range in.Rates {
+		rate, err := ParseRate(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", currency, err)
+		}
+		rates.Rates[strings.ToUpper(currency)] = rate
+	}
+
+	if in.UpdatedAt != nil {
+		rates.UpdatedAt = *in.UpdatedAt
+	}
+
+	*r = rates
+	return nil
+}
@@ -0,0 +1,78 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	rates := Rates{
+		Base: "USD",
+		Rates: map[string]*big.Rat{
+			"EUR": big.NewRat(1, 2),
+			"GBP": big.NewRat(92, 100),
+			"JPY": big.NewRat(150, 1),
+			"KWD": big.NewRat(3, 10),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		from    Money
+		to      string
+		want    int64
+		wantErr string
+	}{
+		{name: "same currency", from: Money{Minor: 1234, Currency: "EUR"}, to: "EUR", want: 1234},
+		{name: "exact", from: Money{Minor: 100, Currency: "USD"}, to: "EUR", want: 50},
+		{name: "rounds down", from: Money{Minor: 1999, Currency: "USD"}, to: "GBP", want: 1839},
+		{name: "rounds up", from: Money{Minor: 1001, Currency: "USD"}, to: "GBP", want: 921},
+		{name: "tie to even below", from: Money{Minor: 1, Currency: "USD"}, to: "EUR", want: 0},
+		{name: "tie to even above", from: Money{Minor: 3, Currency: "USD"}, to: "EUR", want: 2},
+		{name: "tie to even stays", from: Money{Minor: 5, Currency: "USD"}, to: "EUR", want: 2},
+		{name: "tie to even rises", from: Money{Minor: 7, Currency: "USD"}, to: "EUR", want: 4},
+		{name: "negative tie", from: Money{Minor: -3, Currency: "USD"}, to: "EUR", want: -2},
+		{name: "negative tie stays", from: Money{Minor: -5, Currency: "USD"}, to: "EUR", want: -2},
+		{name: "to no minor unit", from: Money{Minor: 100, Currency: "USD"}, to: "JPY", want: 150},
+		{name: "to no minor unit tie", from: Money{Minor: 3, Currency: "USD"}, to: "JPY", want: 4},
+		{name: "to no minor unit tie up", from: Money{Minor: 1, Currency: "USD"}, to: "JPY", want: 2},
+		{name: "from no minor unit", from: Money{Minor: 150, Currency: "JPY"}, to: "USD", want: 100},
+		{name: "cross rate", from: Money{Minor: 100, Currency: "EUR"}, to: "JPY", want: 300},
+		{name: "to three decimals", from: Money{Minor: 1, Currency: "JPY"}, to: "KWD", want: 2},
+		{name: "no rate for target", from: Money{Minor: 100, Currency: "USD"}, to: "CHF", wantErr: "no exchange rate"},
+		{name: "no rate for source", from: Money{Minor: 100, Currency: "CHF"}, to: "USD", wantErr: "no exchange rate"},
+		{name: "unknown currency", from: Money{Minor: 100, Currency: "USD"}, to: "XYZ", wantErr: "not an ISO 4217 code"},
+		{name: "out of range", from: Money{Minor: math.MaxInt64, Currency: "USD"}, to: "JPY", wantErr: "out of range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rates.Convert(tt.from, tt.to)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Convert(%v, %s) = %v, %v; want error containing %q", tt.from, tt.to, got, err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Convert(%v, %s) = %v", tt.from, tt.to, err)
+			}
+			if want := (Money{Minor: tt.want, Currency: tt.to}); got != want {
+				t.Fatalf("Convert(%v, %s) = %v, want %v", tt.from, tt.to, got, want)
+			}
+		})
+	}
+}
+
+func TestConvertMissingRateIsErrNoRate(t *testing.T) {
+	rates := Rates{Base: "USD", Rates: map[string]*big.Rat{}}
+
+	if _, err := rates.Convert(Money{Minor: 100, Currency: "USD"}, "EUR"); !errors.Is(err, ErrNoRate) {
+		t.Fatalf("Convert without a rate = %v, want ErrNoRate", err)
+	}
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/michgboxy2/carzone/alerts"
+	"github.com/michgboxy2/carzone/blob"
+	"github.com/michgboxy2/carzone/config"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/events"
+	carHandler "github.com/michgboxy2/carzone/handler/car"
+	catalogHandler "github.com/michgboxy2/carzone/handler/catalog"
+	documentHandler "github.com/michgboxy2/carzone/handler/document"
+	engineHandler "github.com/michgboxy2/carzone/handler/engine"
+	favoriteHandler "github.com/michgboxy2/carzone/handler/favorite"
+	fuelTypeHandler "github.com/michgboxy2/carzone/handler/fueltype"
+	healthHandler "github.com/michgboxy2/carzone/handler/health"
+	imageHandler "github.com/michgboxy2/carzone/handler/image"
+	loginHandler "github.com/michgboxy2/carzone/handler/login"
+	priceHandler "github.com/michgboxy2/carzone/handler/price"
+	rateHandler "github.com/michgboxy2/carzone/handler/rate"
+	referenceHandler "github.com/michgboxy2/carzone/handler/reference"
+	searchHandler "github.com/michgboxy2/carzone/handler/search"
+	similarHandler "github.com/michgboxy2/carzone/handler/similar"
+	streamHandler "github.com/michgboxy2/carzone/handler/stream"
+	webhookHandler "github.com/michgboxy2/carzone/handler/webhook"
+	"github.com/michgboxy2/carzone/health"
+	middleware "github.com/michgboxy2/carzone/middleware"
+	"github.com/michgboxy2/carzone/models"
+	carService "github.com/michgboxy2/carzone/service/car"
+	catalogService "github.com/michgboxy2/carzone/service/catalog"
+	documentService "github.com/michgboxy2/carzone/service/document"
+	engineService "github.com/michgboxy2/carzone/service/engine"
+	favoriteService "github.com/michgboxy2/carzone/service/favorite"
+	fuelTypeService "github.com/michgboxy2/carzone/service/fueltype"
+	imageService "github.com/michgboxy2/carzone/service/image"
+	priceService "github.com/michgboxy2/carzone/service/price"
+	rateService "github.com/michgboxy2/carzone/service/rate"
+	referenceService "github.com/michgboxy2/carzone/service/reference"
+	searchService "github.com/michgboxy2/carzone/service/search"
+	similarService "github.com/michgboxy2/carzone/service/similar"
+	webhookService "github.com/michgboxy2/carzone/service/webhook"
+	"github.com/michgboxy2/carzone/store"
+	"github.com/michgboxy2/carzone/store/cache"
+	carStore "github.com/michgboxy2/carzone/store/car"
+	catalogStore "github.com/michgboxy2/carzone/store/catalog"
+	documentStore "github.com/michgboxy2/carzone/store/document"
+	engineStore "github.com/michgboxy2/carzone/store/engine"
+	favoriteStore "github.com/michgboxy2/carzone/store/favorite"
+	fuelTypeStore "github.com/michgboxy2/carzone/store/fueltype"
+	imageStore "github.com/michgboxy2/carzone/store/image"
+	"github.com/michgboxy2/carzone/store/outbox"
+	priceStore "github.com/michgboxy2/carzone/store/price"
+	rateStore "github.com/michgboxy2/carzone/store/rate"
+	referenceStore "github.com/michgboxy2/carzone/store/reference"
+	"github.com/michgboxy2/carzone/store/retry"
+	searchStore "github.com/michgboxy2/carzone/store/search"
+	similarStore "github.com/michgboxy2/carzone/store/similar"
+	webhookStore "github.com/michgboxy2/carzone/store/webhook"
+	"github.com/michgboxy2/carzone/stream"
+	"github.com/michgboxy2/carzone/tracing"
+	"github.com/michgboxy2/carzone/worker"
+
+	// "github.com/prometheus/client_golang/promhttp"
+	"github.com/m3db/prometheus_client_golang/prometheus/promhttp"
+	otelmux "go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("starting with configuration:\n%s", cfg.Redacted())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	traceProvider, traceExporter, err := tracing.Start(cfg.Tracing)
+
+	if err != nil {
+		return fmt.Errorf("failed to start tracing: %w", err)
+	}
+
+	otel.SetTracerProvider(traceProvider)
+
+	db, err := openDB(ctx, cfg)
+	if err != nil {
+		traceProvider.Shutdown(context.Background())
+		return err
+	}
+
+	retrier := driver.NewRetrier(cfg.Database)
+
+	cacheBackend, err := cache.NewBackend(cfg.Cache)
+	if err != nil {
+		db.Close()
+		traceProvider.Shutdown(context.Background())
+		return err
+	}
+
+	sink, err := events.NewSink(cfg.Events)
+	if err != nil {
+		if cacheBackend != nil {
+			cacheBackend.Close()
+		}
+		db.Close()
+		traceProvider.Shutdown(context.Background())
+		return err
+	}
+
+	// Closed after the shutdown below, once the relay has stopped.
+	defer sink.Close()
+
+	workers := worker.NewGroup()
+
+	checker := health.NewChecker(version, cfg.Health.CheckTimeout)
+	checker.Register(
+		health.DatabasePing(db.DB, cfg.Health.MaxPingLatency),
+		health.TablesExist(db.DB, "engines", "cars", "outbox", "webhook_subscriptions", "webhook_deliveries", "car_images", "car_documents", "car_prices", "exchange_rates",
+			"transmissions", "drivetrains", "body_styles", "colours", "fuel_types", "brands", "brand_aliases", "models", "model_aliases",
+			"saved_searches", "search_matches", "search_alerts", "favorites"),
+		health.TraceExporter(traceExporter),
+	)
+
+	if len(cfg.Database.Replicas) > 0 {
+		checker.Register(health.Replicas(db))
+		workers.Go("replica-monitor", db.MonitorReplicas)
+	}
+
+	if cacheBackend != nil {
+		checker.Register(health.Cache(cacheBackend))
+
+		// Registered before the shutdown below, so it runs after it.
+		defer cacheBackend.Close()
+	}
+
+	// Everything started from here on is torn down in order when serve
+	// returns, whether that is because of a signal or an error.
+	var server *http.Server
+
+	defer func() {
+		shutdown(cfg.Server, server, checker, workers, traceProvider, db)
+	}()
+
+	txManager := driver.NewTxManager(db, retrier)
+
+	var carStore store.CarStoreInterface = retry.NewCarStore(carStore.New(db), retrier)
+	var engineStore store.EngineStoreInterface = retry.NewEngineStore(engineStore.New(db), retrier)
+
+	if cacheBackend != nil {
+		engineStore = cache.NewEngineStore(engineStore, cacheBackend)
+		carStore = cache.NewCarStore(carStore, engineStore, cacheBackend)
+	}
+
+	outboxStore := outbox.New(db)
+	webhookStore := webhookStore.New(db)
+	searchStore := searchStore.New(db)
+
+	notifier, err := alerts.NewNotifier(cfg.Alerts)
+	if err != nil {
+		return err
+	}
+
+	blobStore, err := blob.NewStore(cfg.Blob)
+	if err != nil {
+		return err
+	}
+
+	signingKey := cfg.Images.URLSigningKey
+	if signingKey == "" {
+		signingKey = cfg.Auth.JWTKey
+	}
+	imageSigner := blob.NewURLSigner(signingKey.Value(), cfg.Images.URLTTL)
+
+	imageService := imageService.NewImageService(imageStore.New(db), blobStore, imageSigner, txManager, cfg.Images)
+	documentService := documentService.NewDocumentService(documentStore.New(db), blobStore, txManager, cfg.Documents)
+	referenceService := referenceService.NewReferenceService(referenceStore.New(db))
+	catalogStore := catalogStore.New(db)
+	catalogService := catalogService.NewCatalogService(catalogStore, carStore, outboxStore, txManager)
+	fuelTypeService := fuelTypeService.NewFuelTypeService(fuelTypeStore.New(db), txManager, cfg.FuelTypes)
+	priceStore := priceStore.New(db)
+	carService := carService.NewCarService(carStore, engineStore, imageService, documentService, referenceService, catalogService, fuelTypeService, priceStore, outboxStore, txManager)
+	engineService := engineService.NewEngineService(engineStore, carStore, fuelTypeService, outboxStore, txManager)
+	rateService := rateService.NewExchangeRateService(rateStore.New(db), cfg.Currency.Base)
+
+	carHandler := carHandler.NewCarHandler(carService, rateService)
+	engineHandler := engineHandler.NewEngineHandler(engineService)
+	webhookHandler := webhookHandler.NewWebhookHandler(webhookService.NewWebhookService(webhookStore))
+	imageHandler := imageHandler.NewImageHandler(imageService, imageSigner, cfg.Images.MaxUploadSize)
+	documentHandler := documentHandler.NewDocumentHandler(documentService, cfg.Documents.MaxUploadSize)
+	rateHandler := rateHandler.NewExchangeRateHandler(rateService)
+	catalogHandler := catalogHandler.NewCatalogHandler(catalogService)
+	fuelTypeHandler := fuelTypeHandler.NewFuelTypeHandler(fuelTypeService)
+	priceHandler := priceHandler.NewPriceHandler(priceService.NewPriceService(priceStore, carStore))
+	similarHandler := similarHandler.NewSimilarHandler(similarService.NewSimilarService(similarStore.New(db), carStore, cfg.Similar, cfg.Currency.Base), rateService)
+	favoriteHandler := favoriteHandler.NewFavoriteHandler(favoriteService.NewFavoriteService(favoriteStore.New(db), carStore, txManager))
+	searchHandler := searchHandler.NewSearchHandler(searchService.NewSearchService(searchStore, catalogStore, fuelTypeService, txManager, cfg.Alerts))
+
+	router := mux.NewRouter()
+
+	router.Use(otelmux.Middleware("carzone"))
+	router.Use(middleware.MetricMiddleware)
+
+	if len(cfg.Database.Replicas) > 0 {
+		// A replica is read from until it is found lagging, so a write may
+		// take up to the tolerated lag plus one check to reach every replica.
+		router.Use(middleware.ReadYourWrites(cfg.Database.MaxReplicationLag + cfg.Database.ReplicaCheckInterval))
+	}
+
+	if err := executeSchema(db.DB, store.Schema); err != nil {
+		return fmt.Errorf("error while executing the schema: %w", err)
+	}
+
+	localSinks := events.Multi(events.NewSubscriptionFanout(webhookStore), alerts.NewMatcher(searchStore, rateService))
+	workers.Go("outbox-relay", events.NewRelay(txManager, outboxStore, sink, localSinks, cfg.Events).Run)
+	workers.Go("webhook-dispatcher", events.NewDispatcher(webhookStore, nil, cfg.Webhooks).Run)
+	workers.Go("search-alerts", alerts.NewDigester(searchStore, notifier, cfg.Alerts).Run)
+
+	hub := stream.NewHub(cfg.Stream.ClientBuffer)
+	workers.Go("event-listener", stream.NewListener(cfg.Database.DSN(), outboxStore, hub).Run)
+
+	streamHandler := streamHandler.NewStreamHandler(
+		stream.NewBroker(hub, outboxStore, cfg.Stream.ReplayLimit), cfg.Stream.HeartbeatInterval, cfg.Stream.AllowedOrigins)
+
+	loginHandler := loginHandler.NewLoginHandler(cfg.Auth)
+
+	healthHandler := healthHandler.NewHealthHandler(checker)
+
+	router.HandleFunc("/login", loginHandler.Login).Methods("POST")
+	router.HandleFunc("/healthz", healthHandler.Liveness).Methods("GET")
+	router.HandleFunc("/readyz", healthHandler.Readiness).Methods("GET")
+
+	// Image URLs carry their own signature instead of a bearer token.
+	router.HandleFunc("/images/{imageId}/{variant}", imageHandler.ServeImage).Methods("GET", "HEAD")
+
+	// Browsers cannot set headers on EventSource or WebSocket requests, so
+	// the live feed also takes its token from the query string. It must be
+	// registered before the catch-all protected subrouter below.
+	live := router.PathPrefix("/cars").Subrouter()
+	live.Use(middleware.TokenFromQuery, middleware.AuthMiddleware([]byte(cfg.Auth.JWTKey.Value())))
+
+	live.HandleFunc("/stream", streamHandler.SSE).Methods("GET")
+	live.HandleFunc("/ws", streamHandler.WebSocket).Methods("GET")
+
+	//Middleware
+	protected := router.PathPrefix("/").Subrouter()
+	protected.Use(middleware.AuthMiddleware([]byte(cfg.Auth.JWTKey.Value())))
+
+	adminOnly := middleware.RequireRole(models.RoleAdmin)
+
+	// The full report names internal dependencies and the running version.
+	protected.Handle("/health", adminOnly(http.HandlerFunc(healthHandler.Health))).Methods("GET")
+
+	protected.HandleFunc("/car/{id}", carHandler.GetCarById).Methods("GET")
+	// Registered before /cars/{brand}, which would take "compare" for a
+	// brand.
+	protected.HandleFunc("/cars/compare", carHandler.CompareCars).Methods("GET")
+	protected.HandleFunc("/cars/{brand}", carHandler.GetCarByBrand).Methods("GET")
+	protected.HandleFunc("/cars", carHandler.CreateCar).Methods("POST")
+	protected.HandleFunc("/cars/{id}", carHandler.UpdateCar).Methods("PUT")
+	protected.HandleFunc("/cars/{id}", carHandler.DeleteCar).Methods("DELETE")
+
+	protected.HandleFunc("/cars/{id}/prices", priceHandler.GetPriceHistory).Methods("GET")
+	protected.HandleFunc("/cars/{id}/similar", similarHandler.GetSimilarCars).Methods("GET")
+
+	protected.HandleFunc("/cars/{id}/images", imageHandler.UploadImage).Methods("POST")
+	protected.HandleFunc("/cars/{id}/images", imageHandler.ListImages).Methods("GET")
+	protected.HandleFunc("/cars/{id}/images/order", imageHandler.ReorderImages).Methods("PUT")
+	protected.HandleFunc("/cars/{id}/images/{imageId}/cover", imageHandler.SetCover).Methods("PUT")
+	protected.HandleFunc("/cars/{id}/images/{imageId}", imageHandler.DeleteImage).Methods("DELETE")
+
+	// Titles and other documents hold personal details, so they are limited
+	// to the roles configured for them.
+	readDocuments := middleware.RequireRole(cfg.Documents.ReadRoles...)
+	writeDocuments := middleware.RequireRole(cfg.Documents.WriteRoles...)
+
+	protected.Handle("/cars/{id}/documents", writeDocuments(http.HandlerFunc(documentHandler.UploadDocument))).Methods("POST")
+	protected.Handle("/cars/{id}/documents", readDocuments(http.HandlerFunc(documentHandler.ListDocuments))).Methods("GET")
+	protected.Handle("/cars/{id}/documents/{documentId}", readDocuments(http.HandlerFunc(documentHandler.GetDocument))).Methods("GET")
+	protected.Handle("/cars/{id}/documents/{documentId}/content", readDocuments(http.HandlerFunc(documentHandler.DownloadDocument))).Methods("GET", "HEAD")
+	protected.Handle("/cars/{id}/documents/{documentId}", writeDocuments(http.HandlerFunc(documentHandler.DeleteDocument))).Methods("DELETE")
+
+	protected.HandleFunc("/engines", engineHandler.ListEngines).Methods("GET")
+	protected.HandleFunc("/engine/{id}", engineHandler.GetEngineById).Methods("GET")
+	protected.HandleFunc("/engine/{id}/cars", engineHandler.GetCarsByEngine).Methods("GET")
+	protected.HandleFunc("/engine", engineHandler.CreateEngine).Methods("POST")
+	protected.HandleFunc("/engine/{id}", engineHandler.UpdateEngine).Methods("PUT")
+	protected.HandleFunc("/engine/{id}", engineHandler.DeleteEngine).Methods("DELETE")
+
+	// Reference values are served at /transmissions, /body-styles and so on;
+	// only admins can change them.
+	for _, kind := range models.ReferenceKinds {
+		h := referenceHandler.NewReferenceHandler(referenceService, kind)
+		path := "/" + strings.ReplaceAll(kind, "_", "-")
+
+		protected.HandleFunc(path, h.ListValues).Methods("GET")
+		protected.HandleFunc(path+"/{code}", h.GetValue).Methods("GET")
+		protected.Handle(path, adminOnly(http.HandlerFunc(h.CreateValue))).Methods("POST")
+		protected.Handle(path+"/{code}", adminOnly(http.HandlerFunc(h.UpdateValue))).Methods("PUT")
+		protected.Handle(path+"/{code}", adminOnly(http.HandlerFunc(h.DeleteValue))).Methods("DELETE")
+	}
+
+	// The brand and model catalogue cars are filed under; only admins can
+	// change it.
+	protected.HandleFunc("/brands", catalogHandler.ListBrands).Methods("GET")
+	protected.HandleFunc("/brands/{id}", catalogHandler.GetBrand).Methods("GET")
+	protected.Handle("/brands", adminOnly(http.HandlerFunc(catalogHandler.CreateBrand))).Methods("POST")
+	protected.Handle("/brands/{id}", adminOnly(http.HandlerFunc(catalogHandler.UpdateBrand))).Methods("PUT")
+	protected.Handle("/brands/{id}", adminOnly(http.HandlerFunc(catalogHandler.DeleteBrand))).Methods("DELETE")
+	protected.HandleFunc("/brands/{id}/models", catalogHandler.ListModels).Methods("GET")
+	protected.Handle("/brands/{id}/models", adminOnly(http.HandlerFunc(catalogHandler.CreateModel))).Methods("POST")
+	protected.Handle("/brands/{id}/models/{modelId}", adminOnly(http.HandlerFunc(catalogHandler.UpdateModel))).Methods("PUT")
+	protected.Handle("/brands/{id}/models/{modelId}", adminOnly(http.HandlerFunc(catalogHandler.DeleteModel))).Methods("DELETE")
+
+	// The fuel type registry car fuel types are checked against; only admins
+	// can change it.
+	protected.HandleFunc("/fuel-types", fuelTypeHandler.ListFuelTypes).Methods("GET")
+	protected.HandleFunc("/fuel-types/{code}", fuelTypeHandler.GetFuelType).Methods("GET")
+	protected.Handle("/fuel-types", adminOnly(http.HandlerFunc(fuelTypeHandler.CreateFuelType))).Methods("POST")
+	protected.Handle("/fuel-types/{code}", adminOnly(http.HandlerFunc(fuelTypeHandler.UpdateFuelType))).Methods("PUT")
+	protected.Handle("/fuel-types/{code}", adminOnly(http.HandlerFunc(fuelTypeHandler.DeleteFuelType))).Methods("DELETE")
+
+	// Favorites belong to the user the token was issued to.
+	protected.HandleFunc("/favorites", favoriteHandler.ListFavorites).Methods("GET")
+	protected.HandleFunc("/favorites/{id}", favoriteHandler.AddFavorite).Methods("PUT")
+	protected.HandleFunc("/favorites/{id}", favoriteHandler.RemoveFavorite).Methods("DELETE")
+
+	// Saved searches belong to the user the token was issued to, who is
+	// alerted to new matches and price drops.
+	protected.HandleFunc("/searches", searchHandler.ListSearches).Methods("GET")
+	protected.HandleFunc("/searches", searchHandler.CreateSearch).Methods("POST")
+	protected.HandleFunc("/searches/{id}", searchHandler.GetSearch).Methods("GET")
+	protected.HandleFunc("/searches/{id}", searchHandler.UpdateSearch).Methods("PUT")
+	protected.HandleFunc("/searches/{id}", searchHandler.DeleteSearch).Methods("DELETE")
+	protected.HandleFunc("/searches/{id}/alerts", searchHandler.ListAlerts).Methods("GET")
+
+	protected.HandleFunc("/exchange-rates", rateHandler.GetRates).Methods("GET")
+	protected.Handle("/admin/exchange-rates", adminOnly(http.HandlerFunc(rateHandler.ReplaceRates))).Methods("PUT")
+
+	// Subscriptions make the dispatcher post signed payloads to any URL, so
+	// only admins may manage them.
+	protected.Handle("/admin/webhooks", adminOnly(http.HandlerFunc(webhookHandler.CreateSubscription))).Methods("POST")
+	protected.Handle("/admin/webhooks", adminOnly(http.HandlerFunc(webhookHandler.ListSubscriptions))).Methods("GET")
+	protected.Handle("/admin/webhooks/{id}", adminOnly(http.HandlerFunc(webhookHandler.GetSubscription))).Methods("GET")
+	protected.Handle("/admin/webhooks/{id}", adminOnly(http.HandlerFunc(webhookHandler.DeleteSubscription))).Methods("DELETE")
+	protected.Handle("/admin/webhooks/{id}/deliveries", adminOnly(http.HandlerFunc(webhookHandler.ListDeliveries))).Methods("GET")
+	protected.Handle("/admin/webhooks/{id}/deliveries/{deliveryId}/redeliver", adminOnly(http.HandlerFunc(webhookHandler.Redeliver))).Methods("POST")
+
+	router.Handle("/metrics", promhttp.Handler())
+
+	server = &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	// Shutdown does not wait for hijacked WebSockets and would wait out its
+	// timeout on open event streams, so end them as soon as it starts.
+	server.RegisterOnShutdown(hub.Close)
+
+	serverErr := make(chan error, 1)
+
+	go func() {
+		log.Printf("server listening on %s", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	checker.SetReady(true)
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("http server: %w", err)
+		}
+	case <-ctx.Done():
+		// Restore default signal handling so a second signal kills the
+		// process straight away.
+		stop()
+		log.Println("shutdown signal received")
+	}
+
+	return nil
+}
+
+// shutdown stops the service in dependency order: readiness is withdrawn,
+// the HTTP server stops accepting connections and drains in-flight requests,
+// then the background workers stop, pending spans are flushed and finally
+// the database pool is closed. All steps after the delay share one timeout.
+func shutdown(cfg config.Server, server *http.Server, checker *health.Checker, workers *worker.Group, traceProvider *trace.TracerProvider, db *driver.DB) {
+	checker.SetReady(false)
+
+	if server != nil && cfg.ShutdownDelay > 0 {
+		log.Printf("not ready, waiting %s for load balancers to catch up", cfg.ShutdownDelay)
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if server != nil {
+		log.Println("draining HTTP connections")
+
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("HTTP server did not drain in time: %v", err)
+			server.Close()
+		}
+	}
+
+	if err := workers.Shutdown(ctx); err != nil {
+		log.Printf("background workers did not stop in time: %v", err)
+	}
+
+	if err := traceProvider.Shutdown(ctx); err != nil {
+		log.Printf("failed to shut down tracing: %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		log.Println(err)
+	}
+
+	log.Println("shutdown complete")
+}
+
+// openDB connects using the database configuration, waiting for the
+// database to come up. Callers are responsible for closing the pool.
+func openDB(ctx context.Context, cfg *config.Config) (*driver.DB, error) {
+	return driver.Open(ctx, cfg.Database)
+}
+
+func executeSchema(db *sql.DB, schema string) error {
+	_, err := db.Exec(schema)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
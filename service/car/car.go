@@ -2,23 +2,54 @@ package car
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/service"
 	"github.com/michgboxy2/carzone/store"
 	"go.opentelemetry.io/otel"
 )
 
 type CarService struct {
-	store store.CarStoreInterface
+	store       store.CarStoreInterface
+	engineStore store.EngineStoreInterface
+	images      service.ImageServiceInterface
+	documents   service.DocumentServiceInterface
+	references  service.ReferenceServiceInterface
+	catalog     service.CatalogServiceInterface
+	fuelTypes   service.FuelTypeServiceInterface
+	prices      store.PriceStoreInterface
+	outbox      store.OutboxStoreInterface
+	tx          store.Transactor
 }
 
-func NewCarService(store store.CarStoreInterface) *CarService {
+func NewCarService(store store.CarStoreInterface, engineStore store.EngineStoreInterface, images service.ImageServiceInterface, documents service.DocumentServiceInterface, references service.ReferenceServiceInterface, catalog service.CatalogServiceInterface, fuelTypes service.FuelTypeServiceInterface, prices store.PriceStoreInterface, outbox store.OutboxStoreInterface, tx store.Transactor) *CarService {
 	return &CarService{
-		store: store,
+		store:       store,
+		engineStore: engineStore,
+		images:      images,
+		documents:   documents,
+		references:  references,
+		catalog:     catalog,
+		fuelTypes:   fuelTypes,
+		prices:      prices,
+		outbox:      outbox,
+		tx:          tx,
 	}
 }
 
+// record writes an event to the outbox. It must be called with the context
+// of the transaction making the change.
+func (s *CarService) record(ctx context.Context, eventType, aggregateType string, id uuid.UUID, payload interface{}) error {
+	event, err := models.NewEvent(eventType, aggregateType, id, payload)
+	if err != nil {
+		return err
+	}
+	return s.outbox.Append(ctx, event)
+}
+
 func (s *CarService) GetCarById(ctx context.Context, id string) (*models.Car, error) {
 	tracer := otel.Tracer("CarService")
 
@@ -36,14 +67,15 @@ func (s *CarService) GetCarById(ctx context.Context, id string) (*models.Car, er
 	return &car, nil
 }
 
-func (s *CarService) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
+func (s *CarService) GetCarByBrand(ctx context.Context, brand string, isEngine bool, filter models.CarFilter) ([]models.Car, error) {
 	tracer := otel.Tracer("CarService")
 
 	ctx, span := tracer.Start(ctx, "GetCarByBrand-Service")
 
 	defer span.End()
 
-	cars, err := s.store.GetCarByBrand(ctx, brand, isEngine)
+	// The store matches the brand's name and aliases whatever their case.
+	cars, err := s.store.GetCarByBrand(ctx, models.CatalogName(brand), isEngine, filter)
 
 	if err != nil {
 		span.RecordError(err)
@@ -53,6 +85,31 @@ func (s *CarService) GetCarByBrand(ctx context.Context, brand string, isEngine b
 	return cars, nil
 }
 
+func (s *CarService) GetCarsByIds(ctx context.Context, ids []uuid.UUID) ([]models.Car, error) {
+	tracer := otel.Tracer("CarService")
+
+	ctx, span := tracer.Start(ctx, "GetCarsByIds-Service")
+
+	defer span.End()
+
+	cars := make([]models.Car, 0, len(ids))
+
+	for _, id := range ids {
+		car, err := s.store.GetCarById(ctx, id.String())
+		if errors.Is(err, models.ErrCarNotFound) {
+			err = fmt.Errorf("%w: %s", models.ErrCarNotFound, id)
+		}
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		cars = append(cars, car)
+	}
+
+	return cars, nil
+}
+
 func (s *CarService) CreateCar(ctx context.Context, car *models.CarRequest) (*models.Car, error) {
 	tracer := otel.Tracer("CarService")
 
@@ -64,7 +121,69 @@ func (s *CarService) CreateCar(ctx context.Context, car *models.CarRequest) (*mo
 		span.RecordError(err)
 		return nil, err
 	}
-	createdCar, err := s.store.CreateCar(ctx, car)
+
+	if err := s.references.CheckCarSpecs(ctx, car.CarSpecs); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if err := s.catalog.ResolveCar(ctx, car); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	fuelType, err := s.resolveFuelType(ctx, car)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if fuelType.Deprecated {
+		err := fmt.Errorf("%w: %s cannot be given to new cars", models.ErrFuelTypeDeprecated, fuelType.Code)
+		span.RecordError(err)
+		return nil, err
+	}
+
+	var createdCar models.Car
+
+	// The engine and the car are created together or not at all.
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		carReq := *car
+
+		if carReq.Engine.EngineID == uuid.Nil {
+			if err := models.ValidatePowertrainFuelType(*fuelType, carReq.Engine.Powertrain); err != nil {
+				return err
+			}
+
+			engineReq := carReq.Engine.Request()
+			engine, err := s.engineFor(ctx, &engineReq)
+			if err != nil {
+				return err
+			}
+			carReq.Engine = engine
+		} else {
+			engine, err := s.engineStore.GetEngineById(ctx, carReq.Engine.EngineID.String())
+			if err != nil {
+				return err
+			}
+
+			if err := models.ValidatePowertrainFuelType(*fuelType, engine.Powertrain); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		createdCar, err = s.store.CreateCar(ctx, &carReq)
+		if err != nil {
+			return err
+		}
+
+		if err := s.prices.RecordPrice(ctx, createdCar.ID, createdCar.Price, createdCar.CreatedAt); err != nil {
+			return err
+		}
+
+		return s.record(ctx, models.EventCarCreated, models.AggregateCar, createdCar.ID, createdCar)
+	})
 
 	if err != nil {
 		span.RecordError(err)
@@ -74,6 +193,40 @@ func (s *CarService) CreateCar(ctx context.Context, car *models.CarRequest) (*mo
 	return &createdCar, nil
 }
 
+// resolveFuelType looks the car's fuel type up in the registry and replaces
+// it with the fuel type's code.
+func (s *CarService) resolveFuelType(ctx context.Context, carReq *models.CarRequest) (*models.FuelType, error) {
+	fuelType, err := s.fuelTypes.ResolveFuelType(ctx, carReq.FuelType)
+	if err != nil {
+		return nil, err
+	}
+
+	carReq.FuelType = fuelType.Code
+	return fuelType, nil
+}
+
+// engineFor returns the engine with the given specs, creating it if there is
+// none yet. It must be called within a transaction.
+func (s *CarService) engineFor(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+	engineReq.Normalize()
+
+	if err := s.engineStore.LockSpecs(ctx, engineReq); err != nil {
+		return models.Engine{}, err
+	}
+
+	engine, err := s.engineStore.FindEngineBySpecs(ctx, engineReq)
+	if !errors.Is(err, models.ErrEngineNotFound) {
+		return engine, err
+	}
+
+	engine, err = s.engineStore.CreateEngine(ctx, engineReq)
+	if err != nil {
+		return models.Engine{}, err
+	}
+
+	return engine, s.record(ctx, models.EventEngineCreated, models.AggregateEngine, engine.EngineID, engine)
+}
+
 func (s *CarService) UpdateCar(ctx context.Context, id uuid.UUID, carReq *models.CarRequest) (*models.Car, error) {
 	tracer := otel.Tracer("CarService")
 
@@ -86,7 +239,75 @@ func (s *CarService) UpdateCar(ctx context.Context, id uuid.UUID, carReq *models
 		return nil, err
 	}
 
-	updatedcar, err := s.store.UpdateCar(ctx, id, carReq)
+	if err := s.references.CheckCarSpecs(ctx, carReq.CarSpecs); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if err := s.catalog.ResolveCar(ctx, carReq); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	fuelType, err := s.resolveFuelType(ctx, carReq)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	var updatedcar models.Car
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// Lock the car before reading it, so concurrent updates check the
+		// mileage and price against what the one before them wrote.
+		if err := s.store.LockCar(ctx, id); err != nil {
+			return err
+		}
+
+		current, err := s.store.GetCarById(ctx, id.String())
+		if err != nil {
+			return err
+		}
+
+		// A car may keep a deprecated fuel type, but not switch to one.
+		if fuelType.Deprecated && current.FuelType != fuelType.Code {
+			return fmt.Errorf("%w: %s cannot be given to other cars", models.ErrFuelTypeDeprecated, fuelType.Code)
+		}
+
+		// The car keeps its engine, which must suit a new fuel type.
+		if err := models.ValidatePowertrainFuelType(*fuelType, current.Engine.Powertrain); err != nil {
+			return err
+		}
+
+		if err := models.ValidateMileageChange(current.Mileage, carReq.Mileage); err != nil {
+			return err
+		}
+
+		updatedcar, err = s.store.UpdateCar(ctx, id, carReq)
+		if err != nil {
+			return err
+		}
+
+		if err := s.record(ctx, models.EventCarUpdated, models.AggregateCar, id, updatedcar); err != nil {
+			return err
+		}
+
+		if current.Price != updatedcar.Price {
+			if err := s.prices.RecordPrice(ctx, id, updatedcar.Price, updatedcar.UpdatedAt); err != nil {
+				return err
+			}
+
+			return s.record(ctx, models.EventCarPriceChanged, models.AggregateCar, id, models.CarPriceChange{
+				CarID:    id,
+				Brand:    updatedcar.Brand,
+				FuelType: updatedcar.FuelType,
+				OldPrice: current.Price,
+				NewPrice: updatedcar.Price,
+			})
+		}
+
+		return nil
+	})
 
 	if err != nil {
 		span.RecordError(err)
@@ -103,7 +324,29 @@ func (s *CarService) DeleteCar(ctx context.Context, id string) (*models.Car, err
 
 	defer span.End()
 
-	deletedCar, err := s.store.DeleteCar(ctx, id)
+	var deletedCar models.Car
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// The images and documents go first, while their rows still say
+		// where their files are.
+		if carID, err := uuid.Parse(id); err == nil {
+			if err := s.images.DeleteCarImages(ctx, carID); err != nil {
+				return err
+			}
+
+			if err := s.documents.DeleteCarDocuments(ctx, carID); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		deletedCar, err = s.store.DeleteCar(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return s.record(ctx, models.EventCarDeleted, models.AggregateCar, deletedCar.ID, deletedCar)
+	})
 
 	if err != nil {
 		span.RecordError(err)
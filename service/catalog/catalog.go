@@ -0,0 +1,387 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+// CatalogService manages the brand and model catalogue. Renaming a brand or
+// model renames it on its cars as well, recording a CarUpdated event for
+// each car in the same transaction.
+type CatalogService struct {
+	store    store.CatalogStoreInterface
+	carStore store.CarStoreInterface
+	outbox   store.OutboxStoreInterface
+	tx       store.Transactor
+}
+
+func NewCatalogService(store store.CatalogStoreInterface, carStore store.CarStoreInterface, outbox store.OutboxStoreInterface, tx store.Transactor) *CatalogService {
+	return &CatalogService{
+		store:    store,
+		carStore: carStore,
+		outbox:   outbox,
+		tx:       tx,
+	}
+}
+
+// recordCarUpdates writes a CarUpdated event for each of the cars a rename
+// changed. It must be called with the context of the transaction making
+// the change.
+func (s *CatalogService) recordCarUpdates(ctx context.Context, ids []uuid.UUID) error {
+	for _, id := range ids {
+		car, err := s.carStore.GetCarById(ctx, id.String())
+		if err != nil {
+			return err
+		}
+
+		event, err := models.NewEvent(models.EventCarUpdated, models.AggregateCar, id, car)
+		if err != nil {
+			return err
+		}
+
+		if err := s.outbox.Append(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *CatalogService) ListBrands(ctx context.Context) ([]models.Brand, error) {
+	tracer := otel.Tracer("CatalogService")
+
+	ctx, span := tracer.Start(ctx, "ListBrands-Service")
+
+	defer span.End()
+
+	brands, err := s.store.ListBrands(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return brands, nil
+}
+
+func (s *CatalogService) GetBrand(ctx context.Context, id uuid.UUID) (*models.Brand, error) {
+	tracer := otel.Tracer("CatalogService")
+
+	ctx, span := tracer.Start(ctx, "GetBrand-Service")
+
+	defer span.End()
+
+	brand, err := s.store.GetBrand(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	brand.Models, err = s.store.ListModels(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &brand, nil
+}
+
+func (s *CatalogService) CreateBrand(ctx context.Context, req *models.CatalogRequest) (*models.Brand, error) {
+	tracer := otel.Tracer("CatalogService")
+
+	ctx, span := tracer.Start(ctx, "CreateBrand-Service")
+
+	defer span.End()
+
+	req.Normalize()
+
+	if err := models.ValidateBrandRequest(*req); err != nil {
+		return nil, err
+	}
+
+	var brand models.Brand
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.checkBrandNames(ctx, uuid.Nil, req); err != nil {
+			return err
+		}
+
+		var err error
+		brand, err = s.store.CreateBrand(ctx, *req)
+		return err
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &brand, nil
+}
+
+// UpdateBrand renames the brand and replaces its aliases.
+func (s *CatalogService) UpdateBrand(ctx context.Context, id uuid.UUID, req *models.CatalogRequest) (*models.Brand, error) {
+	tracer := otel.Tracer("CatalogService")
+
+	ctx, span := tracer.Start(ctx, "UpdateBrand-Service")
+
+	defer span.End()
+
+	req.Normalize()
+
+	if err := models.ValidateBrandRequest(*req); err != nil {
+		return nil, err
+	}
+
+	var brand models.Brand
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.checkBrandNames(ctx, id, req); err != nil {
+			return err
+		}
+
+		var err error
+		brand, err = s.store.UpdateBrand(ctx, id, *req)
+		if err != nil {
+			return err
+		}
+
+		renamed, err := s.carStore.RenameBrand(ctx, id, brand.Name)
+		if err != nil {
+			return err
+		}
+
+		return s.recordCarUpdates(ctx, renamed)
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &brand, nil
+}
+
+// checkBrandNames locks the catalogue and makes sure none of the names in
+// req belongs to a brand other than id. It must be called within a
+// transaction.
+func (s *CatalogService) checkBrandNames(ctx context.Context, id uuid.UUID, req *models.CatalogRequest) error {
+	if err := s.store.LockCatalog(ctx); err != nil {
+		return err
+	}
+
+	for _, name := range req.Names() {
+		brand, err := s.store.FindBrand(ctx, name)
+		if errors.Is(err, models.ErrBrandNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if brand.ID != id {
+			return fmt.Errorf("%w: %q is taken by %s", models.ErrBrandExists, name, brand.Name)
+		}
+	}
+
+	return nil
+}
+
+func (s *CatalogService) DeleteBrand(ctx context.Context, id uuid.UUID) error {
+	tracer := otel.Tracer("CatalogService")
+
+	ctx, span := tracer.Start(ctx, "DeleteBrand-Service")
+
+	defer span.End()
+
+	if err := s.store.DeleteBrand(ctx, id); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *CatalogService) ListModels(ctx context.Context, brandID uuid.UUID) ([]models.CarModel, error) {
+	tracer := otel.Tracer("CatalogService")
+
+	ctx, span := tracer.Start(ctx, "ListModels-Service")
+
+	defer span.End()
+
+	if _, err := s.store.GetBrand(ctx, brandID); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	carModels, err := s.store.ListModels(ctx, brandID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return carModels, nil
+}
+
+func (s *CatalogService) CreateModel(ctx context.Context, brandID uuid.UUID, req *models.CatalogRequest) (*models.CarModel, error) {
+	tracer := otel.Tracer("CatalogService")
+
+	ctx, span := tracer.Start(ctx, "CreateModel-Service")
+
+	defer span.End()
+
+	req.Normalize()
+
+	if err := models.ValidateModelRequest(*req); err != nil {
+		return nil, err
+	}
+
+	var model models.CarModel
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.checkModelNames(ctx, brandID, uuid.Nil, req); err != nil {
+			return err
+		}
+
+		var err error
+		model, err = s.store.CreateModel(ctx, brandID, *req)
+		return err
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+// UpdateModel renames the model and replaces its aliases.
+func (s *CatalogService) UpdateModel(ctx context.Context, brandID, id uuid.UUID, req *models.CatalogRequest) (*models.CarModel, error) {
+	tracer := otel.Tracer("CatalogService")
+
+	ctx, span := tracer.Start(ctx, "UpdateModel-Service")
+
+	defer span.End()
+
+	req.Normalize()
+
+	if err := models.ValidateModelRequest(*req); err != nil {
+		return nil, err
+	}
+
+	var model models.CarModel
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.checkModelNames(ctx, brandID, id, req); err != nil {
+			return err
+		}
+
+		var err error
+		model, err = s.store.UpdateModel(ctx, brandID, id, *req)
+		if err != nil {
+			return err
+		}
+
+		renamed, err := s.carStore.RenameModel(ctx, id, model.Name)
+		if err != nil {
+			return err
+		}
+
+		return s.recordCarUpdates(ctx, renamed)
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+// checkModelNames locks the catalogue and makes sure the brand exists and
+// none of the names in req belongs to another of its models than id. It
+// must be called within a transaction.
+func (s *CatalogService) checkModelNames(ctx context.Context, brandID, id uuid.UUID, req *models.CatalogRequest) error {
+	if err := s.store.LockCatalog(ctx); err != nil {
+		return err
+	}
+
+	if _, err := s.store.GetBrand(ctx, brandID); err != nil {
+		return err
+	}
+
+	for _, name := range req.Names() {
+		model, err := s.store.FindModel(ctx, brandID, name)
+		if errors.Is(err, models.ErrModelNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if model.ID != id {
+			return fmt.Errorf("%w: %q is taken by %s", models.ErrModelExists, name, model.Name)
+		}
+	}
+
+	return nil
+}
+
+func (s *CatalogService) DeleteModel(ctx context.Context, brandID, id uuid.UUID) error {
+	tracer := otel.Tracer("CatalogService")
+
+	ctx, span := tracer.Start(ctx, "DeleteModel-Service")
+
+	defer span.End()
+
+	if err := s.store.DeleteModel(ctx, brandID, id); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *CatalogService) ResolveCar(ctx context.Context, carReq *models.CarRequest) error {
+	tracer := otel.Tracer("CatalogService")
+
+	ctx, span := tracer.Start(ctx, "ResolveCar-Service")
+
+	defer span.End()
+
+	name := models.CatalogName(carReq.Brand)
+
+	brand, err := s.store.FindBrand(ctx, name)
+	if err != nil {
+		if errors.Is(err, models.ErrBrandNotFound) {
+			return fmt.Errorf("%w: %q", err, name)
+		}
+		span.RecordError(err)
+		return err
+	}
+	carReq.Brand = brand.Name
+
+	name = models.CatalogName(carReq.Model)
+	if name == "" {
+		carReq.Model = ""
+		return nil
+	}
+
+	model, err := s.store.FindModel(ctx, brand.ID, name)
+	if err != nil {
+		if errors.Is(err, models.ErrModelNotFound) {
+			return fmt.Errorf("%w: %s has no model %q", err, brand.Name, name)
+		}
+		span.RecordError(err)
+		return err
+	}
+	carReq.Model = model.Name
+
+	return nil
+}
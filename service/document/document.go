@@ -0,0 +1,313 @@
+package document
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/blob"
+	"github.com/michgboxy2/carzone/config"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+// maxFileNameLength matches the file_name column.
+const maxFileNameLength = 255
+
+// allowedTypes are the sniffed content types accepted for upload, with the
+// extension given to files uploaded without a name.
+var allowedTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+}
+
+type DocumentService struct {
+	store store.DocumentStoreInterface
+	blobs blob.Store
+	tx    store.Transactor
+	cfg   config.Documents
+}
+
+func NewDocumentService(store store.DocumentStoreInterface, blobs blob.Store, tx store.Transactor, cfg config.Documents) *DocumentService {
+	return &DocumentService{
+		store: store,
+		blobs: blobs,
+		tx:    tx,
+		cfg:   cfg,
+	}
+}
+
+// UploadDocument stores the file under its SHA-256 and attaches it to the
+// car. The type is sniffed from the content, whatever the client claims it
+// is.
+func (s *DocumentService) UploadDocument(ctx context.Context, carID uuid.UUID, upload models.DocumentUpload, r io.Reader) (*models.CarDocument, bool, error) {
+	tracer := otel.Tracer("DocumentService")
+
+	ctx, span := tracer.Start(ctx, "UploadDocument-Service")
+
+	defer span.End()
+
+	if err := models.ValidateDocumentCategory(upload.Category); err != nil {
+		return nil, false, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, int64(s.cfg.MaxUploadSize)+1))
+	if err != nil {
+		span.RecordError(err)
+		return nil, false, err
+	}
+
+	if len(data) > s.cfg.MaxUploadSize {
+		return nil, false, models.ErrDocumentTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := allowedTypes[contentType]
+	if !ok {
+		return nil, false, models.ErrUnsupportedDocument
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	fileName := cleanFileName(upload.FileName)
+	if fileName == "" {
+		fileName = upload.Category + ext
+	}
+
+	doc := models.CarDocument{
+		ID:          uuid.New(),
+		CarID:       carID,
+		Category:    upload.Category,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Checksum:    checksum,
+		UploadedBy:  upload.UploadedBy,
+		BlobKey:     blobKey(checksum),
+	}
+
+	var (
+		result  models.CarDocument
+		created bool
+	)
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.store.LockCar(ctx, carID); err != nil {
+			return err
+		}
+
+		existing, err := s.store.FindByChecksum(ctx, carID, checksum)
+		if err == nil {
+			result = existing
+			return nil
+		}
+		if !errors.Is(err, models.ErrDocumentNotFound) {
+			return err
+		}
+
+		count, err := s.store.CountDocuments(ctx, carID)
+		if err != nil {
+			return err
+		}
+
+		if count >= s.cfg.MaxPerCar {
+			return models.ErrTooManyDocuments
+		}
+
+		if err := s.store.LockChecksum(ctx, checksum); err != nil {
+			return err
+		}
+
+		// The blob may already be there for another car; writing it again
+		// leaves the same content in place.
+		if err := s.blobs.Put(ctx, doc.BlobKey, bytes.NewReader(data)); err != nil {
+			return err
+		}
+
+		result, err = s.store.CreateDocument(ctx, doc)
+		created = err == nil
+		return err
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		s.releaseBlob(checksum)
+		return nil, false, err
+	}
+
+	return &result, created, nil
+}
+
+func (s *DocumentService) GetDocument(ctx context.Context, carID, id uuid.UUID) (*models.CarDocument, error) {
+	tracer := otel.Tracer("DocumentService")
+
+	ctx, span := tracer.Start(ctx, "GetDocument-Service")
+
+	defer span.End()
+
+	doc, err := s.store.GetDocument(ctx, carID, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+func (s *DocumentService) ListDocuments(ctx context.Context, carID uuid.UUID, category string) ([]models.CarDocument, error) {
+	tracer := otel.Tracer("DocumentService")
+
+	ctx, span := tracer.Start(ctx, "ListDocuments-Service")
+
+	defer span.End()
+
+	if category != "" {
+		if err := models.ValidateDocumentCategory(category); err != nil {
+			return nil, err
+		}
+	}
+
+	docs, err := s.store.ListDocuments(ctx, carID, category)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+func (s *DocumentService) OpenDocument(ctx context.Context, carID, id uuid.UUID) (io.ReadCloser, *models.CarDocument, error) {
+	tracer := otel.Tracer("DocumentService")
+
+	ctx, span := tracer.Start(ctx, "OpenDocument-Service")
+
+	defer span.End()
+
+	doc, err := s.store.GetDocument(ctx, carID, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, nil, err
+	}
+
+	rc, err := s.blobs.Open(ctx, doc.BlobKey)
+	if err != nil {
+		span.RecordError(err)
+		return nil, nil, err
+	}
+
+	return rc, &doc, nil
+}
+
+func (s *DocumentService) DeleteDocument(ctx context.Context, carID, id uuid.UUID) error {
+	tracer := otel.Tracer("DocumentService")
+
+	ctx, span := tracer.Start(ctx, "DeleteDocument-Service")
+
+	defer span.End()
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		deleted, err := s.store.DeleteDocument(ctx, carID, id)
+		if err != nil {
+			return err
+		}
+
+		driver.AfterCommit(ctx, func() { s.releaseBlob(deleted.Checksum) })
+		return nil
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *DocumentService) DeleteCarDocuments(ctx context.Context, carID uuid.UUID) error {
+	tracer := otel.Tracer("DocumentService")
+
+	ctx, span := tracer.Start(ctx, "DeleteCarDocuments-Service")
+
+	defer span.End()
+
+	deleted, err := s.store.DeleteCarDocuments(ctx, carID)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	driver.AfterCommit(ctx, func() {
+		for _, doc := range deleted {
+			s.releaseBlob(doc.Checksum)
+		}
+	})
+
+	return nil
+}
+
+// releaseBlob removes the blob of checksum once no document refers to it.
+// It holds the checksum lock, so an upload of the same file either finishes
+// first and keeps the blob, or waits and writes it again. Failures only
+// leave an unreferenced file behind, so they are logged rather than
+// returned.
+func (s *DocumentService) releaseBlob(checksum string) {
+	err := s.tx.WithinTx(context.Background(), func(ctx context.Context) error {
+		if err := s.store.LockChecksum(ctx, checksum); err != nil {
+			return err
+		}
+
+		count, err := s.store.CountByChecksum(ctx, checksum)
+		if err != nil || count > 0 {
+			return err
+		}
+
+		return s.blobs.Delete(ctx, blobKey(checksum))
+	})
+
+	if err != nil {
+		log.Printf("failed to release document blob %s: %v", checksum, err)
+	}
+}
+
+// blobKey is where the file with checksum is stored, fanned out over
+// directories by its first byte.
+func blobKey(checksum string) string {
+	return "documents/sha256/" + checksum[:2] + "/" + checksum
+}
+
+// cleanFileName keeps the base name of a client-supplied file name, without
+// control characters and cut to fit the column.
+func cleanFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == ".." || name == "/" {
+		return ""
+	}
+
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+
+	for len(name) > maxFileNameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+
+	return strings.TrimSpace(name)
+}
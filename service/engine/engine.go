@@ -2,23 +2,44 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/service"
 	"github.com/michgboxy2/carzone/store"
 	"go.opentelemetry.io/otel"
 )
 
 type EngineService struct {
-	store store.EngineStoreInterface
+	store     store.EngineStoreInterface
+	carStore  store.CarStoreInterface
+	fuelTypes service.FuelTypeServiceInterface
+	outbox    store.OutboxStoreInterface
+	tx        store.Transactor
 }
 
-func NewEngineService(store store.EngineStoreInterface) *EngineService {
+func NewEngineService(store store.EngineStoreInterface, carStore store.CarStoreInterface, fuelTypes service.FuelTypeServiceInterface, outbox store.OutboxStoreInterface, tx store.Transactor) *EngineService {
 	return &EngineService{
-		store: store,
+		store:     store,
+		carStore:  carStore,
+		fuelTypes: fuelTypes,
+		outbox:    outbox,
+		tx:        tx,
 	}
 }
 
+// record writes an event to the outbox. It must be called with the context
+// of the transaction making the change.
+func (s *EngineService) record(ctx context.Context, eventType string, id uuid.UUID, payload interface{}) error {
+	event, err := models.NewEvent(eventType, models.AggregateEngine, id, payload)
+	if err != nil {
+		return err
+	}
+	return s.outbox.Append(ctx, event)
+}
+
 func (s *EngineService) GetEngineById(ctx context.Context, id string) (*models.Engine, error) {
 	tracer := otel.Tracer("EngineService")
 
@@ -36,6 +57,8 @@ func (s *EngineService) GetEngineById(ctx context.Context, id string) (*models.E
 	return &engine, nil
 }
 
+// CreateEngine refuses to create an engine whose specs match an existing one
+// and returns a *models.DuplicateEngineError naming it instead.
 func (s *EngineService) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (*models.Engine, error) {
 	tracer := otel.Tracer("EngineService")
 
@@ -43,12 +66,35 @@ func (s *EngineService) CreateEngine(ctx context.Context, engineReq *models.Engi
 
 	defer span.End()
 
+	engineReq.Normalize()
+
 	if err := models.ValidateEngineRequest(*engineReq); err != nil {
 		span.RecordError(err)
 		return nil, err
 	}
 
-	createdEngine, err := s.store.CreateEngine(ctx, engineReq)
+	var createdEngine models.Engine
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.store.LockSpecs(ctx, engineReq); err != nil {
+			return err
+		}
+
+		existing, err := s.store.FindEngineBySpecs(ctx, engineReq)
+		if err == nil {
+			return &models.DuplicateEngineError{Existing: existing}
+		}
+		if !errors.Is(err, models.ErrEngineNotFound) {
+			return err
+		}
+
+		createdEngine, err = s.store.CreateEngine(ctx, engineReq)
+		if err != nil {
+			return err
+		}
+
+		return s.record(ctx, models.EventEngineCreated, createdEngine.EngineID, createdEngine)
+	})
 
 	if err != nil {
 		span.RecordError(err)
@@ -58,18 +104,96 @@ func (s *EngineService) CreateEngine(ctx context.Context, engineReq *models.Engi
 	return &createdEngine, nil
 }
 
+func (s *EngineService) ListEngines(ctx context.Context, filter models.EngineFilter) (*models.EnginePage, error) {
+	tracer := otel.Tracer("EngineService")
+
+	ctx, span := tracer.Start(ctx, "ListEngines-Service")
+
+	defer span.End()
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	engines, total, err := s.store.ListEngines(ctx, filter)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &models.EnginePage{
+		Engines: engines,
+		Total:   total,
+		Limit:   filter.Limit,
+		Offset:  filter.Offset,
+	}, nil
+}
+
+// GetCarsByEngine lists the cars using an engine, reporting
+// models.ErrEngineNotFound for an unknown one.
+func (s *EngineService) GetCarsByEngine(ctx context.Context, id uuid.UUID) ([]models.Car, error) {
+	tracer := otel.Tracer("EngineService")
+
+	ctx, span := tracer.Start(ctx, "GetCarsByEngine-Service")
+
+	defer span.End()
+
+	if _, err := s.store.GetEngineById(ctx, id.String()); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	cars, err := s.carStore.GetCarsByEngine(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return cars, nil
+}
+
 func (s *EngineService) UpdateEngine(ctx context.Context, id uuid.UUID, engineReq *models.EngineRequest) (*models.Engine, error) {
 	tracer := otel.Tracer("EngineService")
 
 	ctx, span := tracer.Start(ctx, "UpdateEngine-Service")
 
 	defer span.End()
+
+	engineReq.Normalize()
+
 	if err := models.ValidateEngineRequest(*engineReq); err != nil {
 		span.RecordError(err)
 		return nil, err
 	}
 
-	updatedEngine, err := s.store.EngineUpdate(ctx, id, engineReq)
+	var updatedEngine models.Engine
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		updatedEngine, err = s.store.EngineUpdate(ctx, id, engineReq)
+		if err != nil {
+			return err
+		}
+
+		// A new powertrain must still suit every car using the engine.
+		cars, err := s.carStore.GetCarsByEngine(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		for _, car := range cars {
+			fuelType, err := s.fuelTypes.ResolveFuelType(ctx, car.FuelType)
+			if err != nil {
+				return fmt.Errorf("car %s: %w", car.ID, err)
+			}
+
+			if err := models.ValidatePowertrainFuelType(*fuelType, updatedEngine.Powertrain); err != nil {
+				return fmt.Errorf("car %s: %w", car.ID, err)
+			}
+		}
+
+		return s.record(ctx, models.EventEngineUpdated, id, updatedEngine)
+	})
 
 	if err != nil {
 		span.RecordError(err)
@@ -86,7 +210,17 @@ func (s *EngineService) DeleteEngine(ctx context.Context, id string) (*models.En
 
 	defer span.End()
 
-	deletedEngine, err := s.store.EngineDelete(ctx, id)
+	var deletedEngine models.Engine
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		deletedEngine, err = s.store.EngineDelete(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return s.record(ctx, models.EventEngineDeleted, deletedEngine.EngineID, deletedEngine)
+	})
 
 	if err != nil {
 		span.RecordError(err)
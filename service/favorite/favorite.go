@@ -0,0 +1,118 @@
+package favorite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+// FavoriteService manages the cars users have favorited. Every method is
+// scoped to the owner given.
+type FavoriteService struct {
+	store    store.FavoriteStoreInterface
+	carStore store.CarStoreInterface
+	tx       store.Transactor
+}
+
+func NewFavoriteService(store store.FavoriteStoreInterface, carStore store.CarStoreInterface, tx store.Transactor) *FavoriteService {
+	return &FavoriteService{
+		store:    store,
+		carStore: carStore,
+		tx:       tx,
+	}
+}
+
+func (s *FavoriteService) ListFavorites(ctx context.Context, owner string) ([]models.Favorite, error) {
+	tracer := otel.Tracer("FavoriteService")
+
+	ctx, span := tracer.Start(ctx, "ListFavorites-Service")
+
+	defer span.End()
+
+	favorites, err := s.store.ListFavorites(ctx, owner)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// The cars come through the car store, so they are served from its
+	// cache where there is one.
+	cars := favorites[:0]
+	for _, favorite := range favorites {
+		car, err := s.carStore.GetCarById(ctx, favorite.Car.ID.String())
+		// The car was deleted since it was listed.
+		if errors.Is(err, models.ErrCarNotFound) {
+			continue
+		}
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		favorite.Car = car
+		cars = append(cars, favorite)
+	}
+
+	return cars, nil
+}
+
+func (s *FavoriteService) AddFavorite(ctx context.Context, owner string, carID uuid.UUID) error {
+	tracer := otel.Tracer("FavoriteService")
+
+	ctx, span := tracer.Start(ctx, "AddFavorite-Service")
+
+	defer span.End()
+
+	// The owner is locked so that concurrent requests cannot each count
+	// below the limit and together go over it.
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.store.LockOwner(ctx, owner); err != nil {
+			return err
+		}
+
+		// Favoriting a car again changes nothing, so it is allowed at the
+		// limit.
+		exists, err := s.store.IsFavorite(ctx, owner, carID)
+		if err != nil || exists {
+			return err
+		}
+
+		n, err := s.store.CountFavorites(ctx, owner)
+		if err != nil {
+			return err
+		}
+
+		if n >= models.MaxFavorites {
+			return fmt.Errorf("%w: at most %d are allowed", models.ErrTooManyFavorites, models.MaxFavorites)
+		}
+
+		return s.store.AddFavorite(ctx, owner, carID)
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *FavoriteService) RemoveFavorite(ctx context.Context, owner string, carID uuid.UUID) error {
+	tracer := otel.Tracer("FavoriteService")
+
+	ctx, span := tracer.Start(ctx, "RemoveFavorite-Service")
+
+	defer span.End()
+
+	if err := s.store.RemoveFavorite(ctx, owner, carID); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
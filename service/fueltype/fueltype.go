@@ -0,0 +1,277 @@
+package fueltype
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michgboxy2/carzone/config"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+// FuelTypeService manages the fuel type registry. Lookups go through a copy
+// of the whole registry, which is reloaded after the configured TTL and
+// dropped as soon as a change made through this service commits.
+type FuelTypeService struct {
+	store store.FuelTypeStoreInterface
+	tx    store.Transactor
+	ttl   time.Duration
+
+	mu       sync.Mutex
+	registry map[string]models.FuelType
+	loadedAt time.Time
+}
+
+func NewFuelTypeService(store store.FuelTypeStoreInterface, tx store.Transactor, cfg config.FuelTypes) *FuelTypeService {
+	return &FuelTypeService{
+		store: store,
+		tx:    tx,
+		ttl:   cfg.CacheTTL,
+	}
+}
+
+func (s *FuelTypeService) ListFuelTypes(ctx context.Context) ([]models.FuelType, error) {
+	tracer := otel.Tracer("FuelTypeService")
+
+	ctx, span := tracer.Start(ctx, "ListFuelTypes-Service")
+
+	defer span.End()
+
+	fuelTypes, err := s.store.ListFuelTypes(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return fuelTypes, nil
+}
+
+func (s *FuelTypeService) GetFuelType(ctx context.Context, code string) (*models.FuelType, error) {
+	tracer := otel.Tracer("FuelTypeService")
+
+	ctx, span := tracer.Start(ctx, "GetFuelType-Service")
+
+	defer span.End()
+
+	fuelType, err := s.store.GetFuelType(ctx, code)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &fuelType, nil
+}
+
+func (s *FuelTypeService) CreateFuelType(ctx context.Context, req *models.FuelTypeRequest) (*models.FuelType, error) {
+	tracer := otel.Tracer("FuelTypeService")
+
+	ctx, span := tracer.Start(ctx, "CreateFuelType-Service")
+
+	defer span.End()
+
+	req.Normalize()
+
+	if err := models.ValidateFuelTypeRequest(*req); err != nil {
+		return nil, err
+	}
+
+	var fuelType models.FuelType
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.checkNames(ctx, "", req); err != nil {
+			return err
+		}
+
+		var err error
+		fuelType, err = s.store.CreateFuelType(ctx, *req)
+		if err != nil {
+			return err
+		}
+
+		s.invalidate(ctx)
+		return nil
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &fuelType, nil
+}
+
+// UpdateFuelType replaces everything but the code of a fuel type.
+// Deprecating it leaves the cars that have it alone.
+func (s *FuelTypeService) UpdateFuelType(ctx context.Context, code string, req *models.FuelTypeRequest) (*models.FuelType, error) {
+	tracer := otel.Tracer("FuelTypeService")
+
+	ctx, span := tracer.Start(ctx, "UpdateFuelType-Service")
+
+	defer span.End()
+
+	if req.Code == "" {
+		req.Code = code
+	}
+
+	req.Normalize()
+
+	if req.Code != code {
+		return nil, errors.New("code cannot be changed")
+	}
+
+	if err := models.ValidateFuelTypeRequest(*req); err != nil {
+		return nil, err
+	}
+
+	var fuelType models.FuelType
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.checkNames(ctx, code, req); err != nil {
+			return err
+		}
+
+		var err error
+		fuelType, err = s.store.UpdateFuelType(ctx, code, *req)
+		if err != nil {
+			return err
+		}
+
+		s.invalidate(ctx)
+		return nil
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &fuelType, nil
+}
+
+// checkNames locks the registry and makes sure neither the code nor any
+// synonym in req belongs to a fuel type other than code. It must be called
+// within a transaction.
+func (s *FuelTypeService) checkNames(ctx context.Context, code string, req *models.FuelTypeRequest) error {
+	if err := s.store.LockRegistry(ctx); err != nil {
+		return err
+	}
+
+	// Read the registry as it stands, not the cached copy.
+	fuelTypes, err := s.store.ListFuelTypes(ctx)
+	if err != nil {
+		return err
+	}
+
+	registry := index(fuelTypes)
+
+	for _, name := range append([]string{req.Code}, req.Synonyms...) {
+		if other, ok := registry[strings.ToLower(name)]; ok && other.Code != code {
+			return fmt.Errorf("%w: %q is taken by %s", models.ErrFuelTypeExists, name, other.Code)
+		}
+	}
+
+	return nil
+}
+
+func (s *FuelTypeService) DeleteFuelType(ctx context.Context, code string) error {
+	tracer := otel.Tracer("FuelTypeService")
+
+	ctx, span := tracer.Start(ctx, "DeleteFuelType-Service")
+
+	defer span.End()
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.store.DeleteFuelType(ctx, code); err != nil {
+			return err
+		}
+
+		s.invalidate(ctx)
+		return nil
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *FuelTypeService) ResolveFuelType(ctx context.Context, name string) (*models.FuelType, error) {
+	tracer := otel.Tracer("FuelTypeService")
+
+	ctx, span := tracer.Start(ctx, "ResolveFuelType-Service")
+
+	defer span.End()
+
+	registry, err := s.lookup(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	name = models.CatalogName(name)
+
+	fuelType, ok := registry[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", models.ErrFuelTypeNotFound, name)
+	}
+
+	return &fuelType, nil
+}
+
+// lookup returns the cached registry, loading it if there is none or it is
+// older than the TTL.
+func (s *FuelTypeService) lookup(ctx context.Context) (map[string]models.FuelType, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.registry != nil && time.Since(s.loadedAt) < s.ttl {
+		return s.registry, nil
+	}
+
+	loadedAt := time.Now()
+
+	fuelTypes, err := s.store.ListFuelTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Inside a transaction the registry read may include changes that
+	// could still be rolled back, so it is not kept.
+	registry := index(fuelTypes)
+	if !driver.InTx(ctx) {
+		s.registry, s.loadedAt = registry, loadedAt
+	}
+
+	return registry, nil
+}
+
+// invalidate drops the cached registry once the surrounding transaction
+// commits.
+func (s *FuelTypeService) invalidate(ctx context.Context) {
+	driver.AfterCommit(ctx, func() {
+		s.mu.Lock()
+		s.registry = nil
+		s.mu.Unlock()
+	})
+}
+
+// index maps the lowercased code and synonyms of each fuel type to it.
+func index(fuelTypes []models.FuelType) map[string]models.FuelType {
+	registry := make(map[string]models.FuelType)
+
+	for _, fuelType := range fuelTypes {
+		for _, name := range fuelType.Names() {
+			registry[strings.ToLower(name)] = fuelType
+		}
+	}
+
+	return registry
+}
@@ -0,0 +1,394 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	// Decoders for the accepted upload formats.
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/blob"
+	"github.com/michgboxy2/carzone/config"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// maxPixels bounds the decoded size of an upload. A small compressed file
+// can otherwise claim dimensions that take gigabytes to decode.
+const maxPixels = 50_000_000
+
+// allowedTypes are the sniffed content types accepted for upload.
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+type ImageService struct {
+	store  store.ImageStoreInterface
+	blobs  blob.Store
+	signer *blob.URLSigner
+	tx     store.Transactor
+	cfg    config.Images
+}
+
+func NewImageService(store store.ImageStoreInterface, blobs blob.Store, signer *blob.URLSigner, tx store.Transactor, cfg config.Images) *ImageService {
+	return &ImageService{
+		store:  store,
+		blobs:  blobs,
+		signer: signer,
+		tx:     tx,
+		cfg:    cfg,
+	}
+}
+
+// UploadImage stores a photo of a car with a thumbnail and appends it to the
+// car's images. The type is sniffed from the content, whatever the client
+// claims it is.
+func (s *ImageService) UploadImage(ctx context.Context, carID uuid.UUID, r io.Reader) (*models.CarImage, error) {
+	tracer := otel.Tracer("ImageService")
+
+	ctx, span := tracer.Start(ctx, "UploadImage-Service")
+
+	defer span.End()
+
+	data, err := io.ReadAll(io.LimitReader(r, int64(s.cfg.MaxUploadSize)+1))
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if len(data) > s.cfg.MaxUploadSize {
+		return nil, models.ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !allowedTypes[contentType] {
+		return nil, models.ErrUnsupportedImage
+	}
+
+	header, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, models.ErrUnsupportedImage
+	}
+
+	if header.Width <= 0 || header.Height <= 0 || header.Width*header.Height > maxPixels {
+		return nil, models.ErrImageTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, models.ErrUnsupportedImage
+	}
+
+	var thumb bytes.Buffer
+	if err := jpeg.Encode(&thumb, thumbnail(src, s.cfg.ThumbnailWidth), &jpeg.Options{Quality: 85}); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	id := uuid.New()
+	img := models.CarImage{
+		ID:           id,
+		CarID:        carID,
+		ContentType:  contentType,
+		Width:        header.Width,
+		Height:       header.Height,
+		Size:         int64(len(data)),
+		OriginalKey:  fmt.Sprintf("cars/%s/%s/original", carID, id),
+		ThumbnailKey: fmt.Sprintf("cars/%s/%s/thumbnail", carID, id),
+	}
+
+	var created models.CarImage
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.store.LockCar(ctx, carID); err != nil {
+			return err
+		}
+
+		existing, err := s.store.ListImages(ctx, carID)
+		if err != nil {
+			return err
+		}
+
+		if len(existing) >= s.cfg.MaxPerCar {
+			return models.ErrTooManyImages
+		}
+
+		// Written before the row that points at them; removed again
+		// below if the transaction does not commit.
+		if err := s.blobs.Put(ctx, img.OriginalKey, bytes.NewReader(data)); err != nil {
+			return err
+		}
+
+		if err := s.blobs.Put(ctx, img.ThumbnailKey, bytes.NewReader(thumb.Bytes())); err != nil {
+			return err
+		}
+
+		created, err = s.store.CreateImage(ctx, img)
+		return err
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		s.deleteBlobs(img)
+		return nil, err
+	}
+
+	s.sign(&created)
+
+	return &created, nil
+}
+
+func (s *ImageService) ListImages(ctx context.Context, carID uuid.UUID) ([]models.CarImage, error) {
+	tracer := otel.Tracer("ImageService")
+
+	ctx, span := tracer.Start(ctx, "ListImages-Service")
+
+	defer span.End()
+
+	images, err := s.store.ListImages(ctx, carID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	for i := range images {
+		s.sign(&images[i])
+	}
+
+	return images, nil
+}
+
+func (s *ImageService) DeleteImage(ctx context.Context, carID, id uuid.UUID) error {
+	tracer := otel.Tracer("ImageService")
+
+	ctx, span := tracer.Start(ctx, "DeleteImage-Service")
+
+	defer span.End()
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.store.LockCar(ctx, carID); err != nil {
+			return err
+		}
+
+		deleted, err := s.store.DeleteImage(ctx, carID, id)
+		if err != nil {
+			return err
+		}
+
+		driver.AfterCommit(ctx, func() { s.deleteBlobs(deleted) })
+		return nil
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+// ReorderImages sets the display order of all of a car's images and
+// returns them in that order.
+func (s *ImageService) ReorderImages(ctx context.Context, carID uuid.UUID, req *models.ImageOrderRequest) ([]models.CarImage, error) {
+	tracer := otel.Tracer("ImageService")
+
+	ctx, span := tracer.Start(ctx, "ReorderImages-Service")
+
+	defer span.End()
+
+	if err := models.ValidateImageOrderRequest(*req); err != nil {
+		return nil, err
+	}
+
+	var images []models.CarImage
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.store.LockCar(ctx, carID); err != nil {
+			return err
+		}
+
+		existing, err := s.store.ListImages(ctx, carID)
+		if err != nil {
+			return err
+		}
+
+		if len(existing) != len(req.ImageIDs) {
+			return models.ErrImageOrderMismatch
+		}
+
+		if err := s.store.SetPositions(ctx, carID, req.ImageIDs); err != nil {
+			return err
+		}
+
+		images, err = s.store.ListImages(ctx, carID)
+		return err
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	for i := range images {
+		s.sign(&images[i])
+	}
+
+	return images, nil
+}
+
+// SetCover makes the image its car's cover and returns the car's images.
+func (s *ImageService) SetCover(ctx context.Context, carID, id uuid.UUID) ([]models.CarImage, error) {
+	tracer := otel.Tracer("ImageService")
+
+	ctx, span := tracer.Start(ctx, "SetCover-Service")
+
+	defer span.End()
+
+	var images []models.CarImage
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.store.LockCar(ctx, carID); err != nil {
+			return err
+		}
+
+		if err := s.store.SetCover(ctx, carID, id); err != nil {
+			return err
+		}
+
+		var err error
+		images, err = s.store.ListImages(ctx, carID)
+		return err
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	for i := range images {
+		s.sign(&images[i])
+	}
+
+	return images, nil
+}
+
+func (s *ImageService) OpenImage(ctx context.Context, id uuid.UUID, variant string) (io.ReadCloser, *models.CarImage, error) {
+	tracer := otel.Tracer("ImageService")
+
+	ctx, span := tracer.Start(ctx, "OpenImage-Service")
+
+	defer span.End()
+
+	img, err := s.store.GetImage(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, nil, err
+	}
+
+	var key string
+	switch variant {
+	case models.ImageOriginal:
+		key = img.OriginalKey
+	case models.ImageThumbnail:
+		key = img.ThumbnailKey
+		img.ContentType = "image/jpeg"
+	default:
+		return nil, nil, models.ErrImageNotFound
+	}
+
+	rc, err := s.blobs.Open(ctx, key)
+	if err != nil {
+		span.RecordError(err)
+		return nil, nil, err
+	}
+
+	return rc, &img, nil
+}
+
+func (s *ImageService) DeleteCarImages(ctx context.Context, carID uuid.UUID) error {
+	tracer := otel.Tracer("ImageService")
+
+	ctx, span := tracer.Start(ctx, "DeleteCarImages-Service")
+
+	defer span.End()
+
+	deleted, err := s.store.DeleteCarImages(ctx, carID)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	driver.AfterCommit(ctx, func() {
+		for _, img := range deleted {
+			s.deleteBlobs(img)
+		}
+	})
+
+	return nil
+}
+
+// sign fills in the image's expiring URLs.
+func (s *ImageService) sign(img *models.CarImage) {
+	now := time.Now()
+	img.URL = s.signer.Sign(ImagePath(img.ID, models.ImageOriginal), now)
+	img.ThumbnailURL = s.signer.Sign(ImagePath(img.ID, models.ImageThumbnail), now)
+}
+
+// deleteBlobs removes an image's files. Failures only leave unreferenced
+// files behind, so they are logged rather than returned.
+func (s *ImageService) deleteBlobs(img models.CarImage) {
+	ctx := context.Background()
+
+	for _, key := range []string{img.OriginalKey, img.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			log.Printf("failed to delete blob %s: %v", key, err)
+		}
+	}
+}
+
+// ImagePath is the path an image variant is served from, and what its URL
+// signature covers.
+func ImagePath(id uuid.UUID, variant string) string {
+	return "/images/" + id.String() + "/" + variant
+}
+
+// thumbnail scales src down to width, keeping its aspect ratio, onto a white
+// background since JPEG has no transparency. Narrower images keep their
+// size.
+func thumbnail(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+
+	if bounds.Dx() < width {
+		width = bounds.Dx()
+	}
+
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	return dst
+}
@@ -2,17 +2,22 @@ package service
 
 import (
 	"context"
+	"io"
 
 	"github.com/google/uuid"
 	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/money"
 )
 
 type CarServiceInterface interface {
 	GetCarById(ctx context.Context, id string) (*models.Car, error)
-	GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error)
+	GetCarByBrand(ctx context.Context, brand string, isEngine bool, filter models.CarFilter) ([]models.Car, error)
 	CreateCar(ctx context.Context, carReq *models.CarRequest) (*models.Car, error)
 	UpdateCar(ctx context.Context, id uuid.UUID, carReq *models.CarRequest) (*models.Car, error)
 	DeleteCar(ctx context.Context, id string) (*models.Car, error)
+	// GetCarsByIds returns the cars with their engines, in the order of ids.
+	// It reports models.ErrCarNotFound if any of them is unknown.
+	GetCarsByIds(ctx context.Context, ids []uuid.UUID) ([]models.Car, error)
 }
 
 type EngineServiceInterface interface {
@@ -20,4 +25,126 @@ type EngineServiceInterface interface {
 	CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (*models.Engine, error)
 	UpdateEngine(ctx context.Context, id uuid.UUID, engineReq *models.EngineRequest) (*models.Engine, error)
 	DeleteEngine(ctx context.Context, id string) (*models.Engine, error)
+	ListEngines(ctx context.Context, filter models.EngineFilter) (*models.EnginePage, error)
+	GetCarsByEngine(ctx context.Context, id uuid.UUID) ([]models.Car, error)
+}
+
+type WebhookServiceInterface interface {
+	CreateSubscription(ctx context.Context, req *models.WebhookSubscriptionRequest) (*models.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, subscriptionID, deliveryID uuid.UUID) (*models.WebhookDelivery, error)
+}
+
+type ImageServiceInterface interface {
+	UploadImage(ctx context.Context, carID uuid.UUID, r io.Reader) (*models.CarImage, error)
+	ListImages(ctx context.Context, carID uuid.UUID) ([]models.CarImage, error)
+	DeleteImage(ctx context.Context, carID, id uuid.UUID) error
+	ReorderImages(ctx context.Context, carID uuid.UUID, req *models.ImageOrderRequest) ([]models.CarImage, error)
+	SetCover(ctx context.Context, carID, id uuid.UUID) ([]models.CarImage, error)
+	// OpenImage returns the stored variant of an image, either
+	// models.ImageOriginal or models.ImageThumbnail.
+	OpenImage(ctx context.Context, id uuid.UUID, variant string) (io.ReadCloser, *models.CarImage, error)
+	// DeleteCarImages removes all of a car's images; it is called when the
+	// car is deleted.
+	DeleteCarImages(ctx context.Context, carID uuid.UUID) error
+}
+
+type DocumentServiceInterface interface {
+	// UploadDocument attaches the file to the car. If the car already has
+	// an identical file, that document is returned with created false.
+	UploadDocument(ctx context.Context, carID uuid.UUID, upload models.DocumentUpload, r io.Reader) (doc *models.CarDocument, created bool, err error)
+	GetDocument(ctx context.Context, carID, id uuid.UUID) (*models.CarDocument, error)
+	ListDocuments(ctx context.Context, carID uuid.UUID, category string) ([]models.CarDocument, error)
+	OpenDocument(ctx context.Context, carID, id uuid.UUID) (io.ReadCloser, *models.CarDocument, error)
+	DeleteDocument(ctx context.Context, carID, id uuid.UUID) error
+	// DeleteCarDocuments removes all of a car's documents; it is called
+	// when the car is deleted.
+	DeleteCarDocuments(ctx context.Context, carID uuid.UUID) error
+}
+
+type ExchangeRateServiceInterface interface {
+	GetRates(ctx context.Context) (*money.Rates, error)
+	ReplaceRates(ctx context.Context, rates *money.Rates) (*money.Rates, error)
+	// ConvertCars converts the price of each car to currency in place,
+	// keeping the listed price in OriginalPrice.
+	ConvertCars(ctx context.Context, currency string, cars []models.Car) error
+}
+
+type ReferenceServiceInterface interface {
+	ListValues(ctx context.Context, kind string) ([]models.ReferenceValue, error)
+	GetValue(ctx context.Context, kind, code string) (*models.ReferenceValue, error)
+	CreateValue(ctx context.Context, kind string, req *models.ReferenceValueRequest) (*models.ReferenceValue, error)
+	UpdateValue(ctx context.Context, kind, code string, req *models.ReferenceValueRequest) (*models.ReferenceValue, error)
+	DeleteValue(ctx context.Context, kind, code string) error
+	// CheckCarSpecs reports models.ErrReferenceNotFound for any reference
+	// value the specs use that does not exist.
+	CheckCarSpecs(ctx context.Context, specs models.CarSpecs) error
+}
+
+type CatalogServiceInterface interface {
+	ListBrands(ctx context.Context) ([]models.Brand, error)
+	// GetBrand returns the brand with its models.
+	GetBrand(ctx context.Context, id uuid.UUID) (*models.Brand, error)
+	CreateBrand(ctx context.Context, req *models.CatalogRequest) (*models.Brand, error)
+	UpdateBrand(ctx context.Context, id uuid.UUID, req *models.CatalogRequest) (*models.Brand, error)
+	DeleteBrand(ctx context.Context, id uuid.UUID) error
+	ListModels(ctx context.Context, brandID uuid.UUID) ([]models.CarModel, error)
+	CreateModel(ctx context.Context, brandID uuid.UUID, req *models.CatalogRequest) (*models.CarModel, error)
+	UpdateModel(ctx context.Context, brandID, id uuid.UUID, req *models.CatalogRequest) (*models.CarModel, error)
+	DeleteModel(ctx context.Context, brandID, id uuid.UUID) error
+	// ResolveCar replaces the brand and model of carReq with the canonical
+	// names they resolve to, reporting models.ErrBrandNotFound or
+	// models.ErrModelNotFound when there are none.
+	ResolveCar(ctx context.Context, carReq *models.CarRequest) error
+}
+
+type FuelTypeServiceInterface interface {
+	ListFuelTypes(ctx context.Context) ([]models.FuelType, error)
+	GetFuelType(ctx context.Context, code string) (*models.FuelType, error)
+	CreateFuelType(ctx context.Context, req *models.FuelTypeRequest) (*models.FuelType, error)
+	UpdateFuelType(ctx context.Context, code string, req *models.FuelTypeRequest) (*models.FuelType, error)
+	DeleteFuelType(ctx context.Context, code string) error
+	// ResolveFuelType returns the fuel type name is the code or a synonym
+	// of, whatever its case, or models.ErrFuelTypeNotFound. It reads a
+	// cached copy of the registry.
+	ResolveFuelType(ctx context.Context, name string) (*models.FuelType, error)
+}
+
+type PriceServiceInterface interface {
+	// GetPriceHistory reports models.ErrCarNotFound for an unknown car.
+	GetPriceHistory(ctx context.Context, carID uuid.UUID) (*models.PriceHistory, error)
+}
+
+// SearchServiceInterface manages saved searches on behalf of their owner,
+// the user name of the caller's token.
+type SearchServiceInterface interface {
+	ListSearches(ctx context.Context, owner string) ([]models.SavedSearch, error)
+	GetSearch(ctx context.Context, owner string, id uuid.UUID) (*models.SavedSearch, error)
+	// CreateSearch reports models.ErrTooManySearches once owner has as
+	// many as allowed.
+	CreateSearch(ctx context.Context, owner string, req *models.SavedSearchRequest) (*models.SavedSearch, error)
+	UpdateSearch(ctx context.Context, owner string, id uuid.UUID, req *models.SavedSearchRequest) (*models.SavedSearch, error)
+	DeleteSearch(ctx context.Context, owner string, id uuid.UUID) error
+	ListAlerts(ctx context.Context, owner string, id uuid.UUID, limit int) ([]models.SearchAlert, error)
+}
+
+// FavoriteServiceInterface manages the favorites of owner, the user name of
+// the caller's token.
+type FavoriteServiceInterface interface {
+	// ListFavorites returns owner's favorite cars, latest first.
+	ListFavorites(ctx context.Context, owner string) ([]models.Favorite, error)
+	// AddFavorite reports models.ErrCarNotFound for an unknown car and
+	// models.ErrTooManyFavorites once owner has as many as allowed.
+	AddFavorite(ctx context.Context, owner string, carID uuid.UUID) error
+	RemoveFavorite(ctx context.Context, owner string, carID uuid.UUID) error
+}
+
+// SimilarServiceInterface recommends cars like a given one.
+type SimilarServiceInterface interface {
+	// SimilarCars returns up to limit cars like carID, nearest first, or
+	// the configured number when limit is 0.
+	SimilarCars(ctx context.Context, carID uuid.UUID, limit int) ([]models.SimilarCar, error)
 }
@@ -0,0 +1,48 @@
+package price
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+// PriceService reports the price history of cars, which the car service
+// records as prices change.
+type PriceService struct {
+	store    store.PriceStoreInterface
+	carStore store.CarStoreInterface
+}
+
+func NewPriceService(store store.PriceStoreInterface, carStore store.CarStoreInterface) *PriceService {
+	return &PriceService{
+		store:    store,
+		carStore: carStore,
+	}
+}
+
+func (s *PriceService) GetPriceHistory(ctx context.Context, carID uuid.UUID) (*models.PriceHistory, error) {
+	tracer := otel.Tracer("PriceService")
+
+	ctx, span := tracer.Start(ctx, "GetPriceHistory-Service")
+
+	defer span.End()
+
+	car, err := s.carStore.GetCarById(ctx, carID.String())
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	points, err := s.store.ListPrices(ctx, carID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	history := models.NewPriceHistory(car, points, time.Now())
+	return &history, nil
+}
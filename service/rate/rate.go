@@ -0,0 +1,103 @@
+package rate
+
+import (
+	"context"
+
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/money"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+type ExchangeRateService struct {
+	store store.ExchangeRateStoreInterface
+	base  string
+}
+
+// NewExchangeRateService serves the rates against base, the only ones that
+// can be loaded.
+func NewExchangeRateService(store store.ExchangeRateStoreInterface, base string) *ExchangeRateService {
+	return &ExchangeRateService{
+		store: store,
+		base:  base,
+	}
+}
+
+func (s *ExchangeRateService) GetRates(ctx context.Context) (*money.Rates, error) {
+	tracer := otel.Tracer("ExchangeRateService")
+
+	ctx, span := tracer.Start(ctx, "GetRates-Service")
+
+	defer span.End()
+
+	rates, err := s.store.GetRates(ctx, s.base)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &rates, nil
+}
+
+// ReplaceRates replaces every rate with the given ones. Currencies left out
+// can no longer be converted to or from.
+func (s *ExchangeRateService) ReplaceRates(ctx context.Context, rates *money.Rates) (*money.Rates, error) {
+	tracer := otel.Tracer("ExchangeRateService")
+
+	ctx, span := tracer.Start(ctx, "ReplaceRates-Service")
+
+	defer span.End()
+
+	if rates.Base != s.base {
+		return nil, models.ErrRateBaseMismatch
+	}
+
+	if err := rates.Validate(); err != nil {
+		return nil, err
+	}
+
+	replaced, err := s.store.ReplaceRates(ctx, *rates)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &replaced, nil
+}
+
+func (s *ExchangeRateService) ConvertCars(ctx context.Context, currency string, cars []models.Car) error {
+	tracer := otel.Tracer("ExchangeRateService")
+
+	ctx, span := tracer.Start(ctx, "ConvertCars-Service")
+
+	defer span.End()
+
+	if err := money.ValidateCurrency(currency); err != nil {
+		return err
+	}
+
+	rates, err := s.store.GetRates(ctx, s.base)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	for i := range cars {
+		listed := cars[i].Price
+
+		if listed.Currency == currency {
+			continue
+		}
+
+		converted, err := rates.Convert(listed, currency)
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		cars[i].Price = converted
+		cars[i].OriginalPrice = &listed
+	}
+
+	return nil
+}
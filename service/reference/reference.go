@@ -0,0 +1,149 @@
+package reference
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+type ReferenceService struct {
+	store store.ReferenceStoreInterface
+}
+
+func NewReferenceService(store store.ReferenceStoreInterface) *ReferenceService {
+	return &ReferenceService{
+		store: store,
+	}
+}
+
+func (s *ReferenceService) ListValues(ctx context.Context, kind string) ([]models.ReferenceValue, error) {
+	tracer := otel.Tracer("ReferenceService")
+
+	ctx, span := tracer.Start(ctx, "ListValues-Service")
+
+	defer span.End()
+
+	values, err := s.store.ListValues(ctx, kind)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func (s *ReferenceService) GetValue(ctx context.Context, kind, code string) (*models.ReferenceValue, error) {
+	tracer := otel.Tracer("ReferenceService")
+
+	ctx, span := tracer.Start(ctx, "GetValue-Service")
+
+	defer span.End()
+
+	value, err := s.store.GetValue(ctx, kind, code)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+func (s *ReferenceService) CreateValue(ctx context.Context, kind string, req *models.ReferenceValueRequest) (*models.ReferenceValue, error) {
+	tracer := otel.Tracer("ReferenceService")
+
+	ctx, span := tracer.Start(ctx, "CreateValue-Service")
+
+	defer span.End()
+
+	req.Name = strings.TrimSpace(req.Name)
+
+	if err := models.ValidateReferenceCode(req.Code); err != nil {
+		return nil, err
+	}
+
+	if err := models.ValidateReferenceName(req.Name); err != nil {
+		return nil, err
+	}
+
+	value, err := s.store.CreateValue(ctx, kind, *req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+// UpdateValue renames a value. Its code cannot change, as cars refer to it.
+func (s *ReferenceService) UpdateValue(ctx context.Context, kind, code string, req *models.ReferenceValueRequest) (*models.ReferenceValue, error) {
+	tracer := otel.Tracer("ReferenceService")
+
+	ctx, span := tracer.Start(ctx, "UpdateValue-Service")
+
+	defer span.End()
+
+	if req.Code != "" && req.Code != code {
+		return nil, errors.New("code cannot be changed")
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+
+	if err := models.ValidateReferenceName(req.Name); err != nil {
+		return nil, err
+	}
+
+	value, err := s.store.UpdateValue(ctx, kind, code, req.Name)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+func (s *ReferenceService) DeleteValue(ctx context.Context, kind, code string) error {
+	tracer := otel.Tracer("ReferenceService")
+
+	ctx, span := tracer.Start(ctx, "DeleteValue-Service")
+
+	defer span.End()
+
+	if err := s.store.DeleteValue(ctx, kind, code); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *ReferenceService) CheckCarSpecs(ctx context.Context, specs models.CarSpecs) error {
+	tracer := otel.Tracer("ReferenceService")
+
+	ctx, span := tracer.Start(ctx, "CheckCarSpecs-Service")
+
+	defer span.End()
+
+	refs := specs.References()
+
+	for _, kind := range models.ReferenceKinds {
+		code, ok := refs[kind]
+		if !ok {
+			continue
+		}
+
+		if _, err := s.store.GetValue(ctx, kind, code); err != nil {
+			if errors.Is(err, models.ErrReferenceNotFound) {
+				return fmt.Errorf("%w: %s has no %q", err, kind, code)
+			}
+			span.RecordError(err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/config"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/service"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+// SearchService manages users' saved searches. Every method is scoped to
+// the owner given, so users only see and change their own.
+type SearchService struct {
+	store     store.SearchStoreInterface
+	catalog   store.CatalogStoreInterface
+	fuelTypes service.FuelTypeServiceInterface
+	tx        store.Transactor
+	cfg       config.Alerts
+}
+
+func NewSearchService(store store.SearchStoreInterface, catalog store.CatalogStoreInterface, fuelTypes service.FuelTypeServiceInterface, tx store.Transactor, cfg config.Alerts) *SearchService {
+	return &SearchService{
+		store:     store,
+		catalog:   catalog,
+		fuelTypes: fuelTypes,
+		tx:        tx,
+		cfg:       cfg,
+	}
+}
+
+func (s *SearchService) ListSearches(ctx context.Context, owner string) ([]models.SavedSearch, error) {
+	tracer := otel.Tracer("SearchService")
+
+	ctx, span := tracer.Start(ctx, "ListSearches-Service")
+
+	defer span.End()
+
+	searches, err := s.store.ListSearches(ctx, owner)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return searches, nil
+}
+
+func (s *SearchService) GetSearch(ctx context.Context, owner string, id uuid.UUID) (*models.SavedSearch, error) {
+	tracer := otel.Tracer("SearchService")
+
+	ctx, span := tracer.Start(ctx, "GetSearch-Service")
+
+	defer span.End()
+
+	search, err := s.store.GetSearch(ctx, owner, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &search, nil
+}
+
+// CreateSearch saves a search for owner, up to the configured number per
+// user.
+func (s *SearchService) CreateSearch(ctx context.Context, owner string, req *models.SavedSearchRequest) (*models.SavedSearch, error) {
+	tracer := otel.Tracer("SearchService")
+
+	ctx, span := tracer.Start(ctx, "CreateSearch-Service")
+
+	defer span.End()
+
+	search, err := s.resolve(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	search.Owner = owner
+
+	var created models.SavedSearch
+
+	// The owner is locked so that concurrent requests cannot each count
+	// below the limit and together go over it.
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.store.LockOwner(ctx, owner); err != nil {
+			return err
+		}
+
+		n, err := s.store.CountSearches(ctx, owner)
+		if err != nil {
+			return err
+		}
+
+		if n >= s.cfg.MaxSearchesPerUser {
+			return fmt.Errorf("%w: at most %d are allowed", models.ErrTooManySearches, s.cfg.MaxSearchesPerUser)
+		}
+
+		created, err = s.store.CreateSearch(ctx, search)
+		return err
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// UpdateSearch replaces the criteria and settings of one of owner's
+// searches. Cars it already matched are not alerted about again.
+func (s *SearchService) UpdateSearch(ctx context.Context, owner string, id uuid.UUID, req *models.SavedSearchRequest) (*models.SavedSearch, error) {
+	tracer := otel.Tracer("SearchService")
+
+	ctx, span := tracer.Start(ctx, "UpdateSearch-Service")
+
+	defer span.End()
+
+	search, err := s.resolve(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	search.ID = id
+	search.Owner = owner
+
+	updated, err := s.store.UpdateSearch(ctx, search)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+func (s *SearchService) DeleteSearch(ctx context.Context, owner string, id uuid.UUID) error {
+	tracer := otel.Tracer("SearchService")
+
+	ctx, span := tracer.Start(ctx, "DeleteSearch-Service")
+
+	defer span.End()
+
+	if err := s.store.DeleteSearch(ctx, owner, id); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+// ListAlerts returns the newest alerts of one of owner's searches first.
+func (s *SearchService) ListAlerts(ctx context.Context, owner string, id uuid.UUID, limit int) ([]models.SearchAlert, error) {
+	tracer := otel.Tracer("SearchService")
+
+	ctx, span := tracer.Start(ctx, "ListAlerts-Service")
+
+	defer span.End()
+
+	if _, err := s.store.GetSearch(ctx, owner, id); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	alerts, err := s.store.ListAlerts(ctx, id, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+// resolve checks req and looks its brand and fuel type up, so the search
+// holds the catalogue brand and the fuel type's code.
+func (s *SearchService) resolve(ctx context.Context, req *models.SavedSearchRequest) (models.SavedSearch, error) {
+	req.Normalize()
+
+	if err := models.ValidateSavedSearchRequest(*req); err != nil {
+		return models.SavedSearch{}, err
+	}
+
+	if s.cfg.Notifier == "smtp" && req.Email == "" {
+		return models.SavedSearch{}, models.ErrEmailRequired
+	}
+
+	search := models.SavedSearch{
+		Name:      req.Name,
+		MinPrice:  req.MinPrice,
+		MaxPrice:  req.MaxPrice,
+		MinYear:   req.MinYear,
+		MaxYear:   req.MaxYear,
+		Frequency: req.Frequency,
+		Email:     req.Email,
+	}
+
+	if req.Brand != "" {
+		brand, err := s.catalog.FindBrand(ctx, req.Brand)
+		if err != nil {
+			if errors.Is(err, models.ErrBrandNotFound) {
+				return models.SavedSearch{}, fmt.Errorf("%w: %q", err, req.Brand)
+			}
+			return models.SavedSearch{}, err
+		}
+
+		search.BrandID = &brand.ID
+		search.Brand = brand.Name
+	}
+
+	if req.FuelType != "" {
+		fuelType, err := s.fuelTypes.ResolveFuelType(ctx, req.FuelType)
+		if err != nil {
+			return models.SavedSearch{}, err
+		}
+
+		search.FuelType = fuelType.Code
+	}
+
+	return search, nil
+}
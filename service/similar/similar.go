@@ -0,0 +1,119 @@
+package similar
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/michgboxy2/carzone/config"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/sync/singleflight"
+)
+
+// SimilarService recommends cars like a given one. Each car's ranking is
+// worked out in the database once and kept for the configured TTL; the cars
+// in it are loaded on every read, so they are never staler than the car
+// store's own cache, and cars deleted since are left out.
+type SimilarService struct {
+	store      store.SimilarStoreInterface
+	carStore   store.CarStoreInterface
+	similarity models.Similarity
+	limit      int
+
+	rankings *expirable.LRU[uuid.UUID, []models.RankedCar]
+	group    singleflight.Group
+}
+
+// NewSimilarService compares prices in currency, the base of the exchange
+// rates.
+func NewSimilarService(store store.SimilarStoreInterface, carStore store.CarStoreInterface, cfg config.Similar, currency string) *SimilarService {
+	return &SimilarService{
+		store:    store,
+		carStore: carStore,
+		similarity: models.Similarity{
+			Weights: models.SimilarityWeights{
+				Brand:    cfg.Weights.Brand,
+				FuelType: cfg.Weights.FuelType,
+				Year:     cfg.Weights.Year,
+				Price:    cfg.Weights.Price,
+				Engine:   cfg.Weights.Engine,
+			},
+			YearSpan:  cfg.YearSpan,
+			PriceBand: cfg.PriceBand,
+			Currency:  currency,
+		},
+		limit:    min(cfg.Limit, models.MaxSimilarCars),
+		rankings: expirable.NewLRU[uuid.UUID, []models.RankedCar](cfg.CacheSize, nil, cfg.CacheTTL),
+	}
+}
+
+func (s *SimilarService) SimilarCars(ctx context.Context, carID uuid.UUID, limit int) ([]models.SimilarCar, error) {
+	tracer := otel.Tracer("SimilarService")
+
+	ctx, span := tracer.Start(ctx, "SimilarCars-Service")
+
+	defer span.End()
+
+	if limit <= 0 {
+		limit = s.limit
+	}
+
+	if _, err := s.carStore.GetCarById(ctx, carID.String()); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	ranking, err := s.ranking(ctx, carID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	similar := []models.SimilarCar{}
+	for _, ranked := range ranking {
+		if len(similar) == limit {
+			break
+		}
+
+		car, err := s.carStore.GetCarById(ctx, ranked.CarID.String())
+		// The car was deleted since it was ranked.
+		if errors.Is(err, models.ErrCarNotFound) {
+			continue
+		}
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		similar = append(similar, models.SimilarCar{Car: car, Distance: ranked.Distance})
+	}
+
+	return similar, nil
+}
+
+// ranking returns the cached ranking for the car, ranking it on a miss.
+// Rankings always hold as many cars as can be asked for, so one entry serves
+// every limit. Concurrent misses for the same car share a single query.
+func (s *SimilarService) ranking(ctx context.Context, carID uuid.UUID) ([]models.RankedCar, error) {
+	if ranking, ok := s.rankings.Get(carID); ok {
+		return ranking, nil
+	}
+
+	v, err, _ := s.group.Do(carID.String(), func() (interface{}, error) {
+		ranking, err := s.store.RankSimilar(ctx, carID, s.similarity, models.MaxSimilarCars)
+		if err != nil {
+			return nil, err
+		}
+
+		s.rankings.Add(carID, ranking)
+		return ranking, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]models.RankedCar), nil
+}
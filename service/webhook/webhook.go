@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+type WebhookService struct {
+	store store.WebhookStoreInterface
+}
+
+func NewWebhookService(store store.WebhookStoreInterface) *WebhookService {
+	return &WebhookService{
+		store: store,
+	}
+}
+
+// CreateSubscription registers a URL for the given event types. The secret
+// used to sign deliveries is returned here and never again.
+func (s *WebhookService) CreateSubscription(ctx context.Context, req *models.WebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	tracer := otel.Tracer("WebhookService")
+
+	ctx, span := tracer.Start(ctx, "CreateSubscription-Service")
+
+	defer span.End()
+
+	if err := models.ValidateWebhookSubscriptionRequest(*req); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		secret = "whsec_" + hex.EncodeToString(buf)
+	}
+
+	sub, err := s.store.CreateSubscription(ctx, models.WebhookSubscription{
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     secret,
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &sub, nil
+}
+
+func (s *WebhookService) GetSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error) {
+	tracer := otel.Tracer("WebhookService")
+
+	ctx, span := tracer.Start(ctx, "GetSubscription-Service")
+
+	defer span.End()
+
+	sub, err := s.store.GetSubscription(ctx, id)
+
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &sub, nil
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	tracer := otel.Tracer("WebhookService")
+
+	ctx, span := tracer.Start(ctx, "ListSubscriptions-Service")
+
+	defer span.End()
+
+	subs, err := s.store.ListSubscriptions(ctx)
+
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	tracer := otel.Tracer("WebhookService")
+
+	ctx, span := tracer.Start(ctx, "DeleteSubscription-Service")
+
+	defer span.End()
+
+	if err := s.store.DeleteSubscription(ctx, id); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *WebhookService) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error) {
+	tracer := otel.Tracer("WebhookService")
+
+	ctx, span := tracer.Start(ctx, "ListDeliveries-Service")
+
+	defer span.End()
+
+	// Listing the log of an unknown subscription is a 404, not an empty list.
+	if _, err := s.store.GetSubscription(ctx, subscriptionID); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	deliveries, err := s.store.ListDeliveries(ctx, subscriptionID, status, limit)
+
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (s *WebhookService) Redeliver(ctx context.Context, subscriptionID, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	tracer := otel.Tracer("WebhookService")
+
+	ctx, span := tracer.Start(ctx, "Redeliver-Service")
+
+	defer span.End()
+
+	delivery, err := s.store.Redeliver(ctx, subscriptionID, deliveryID)
+
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &delivery, nil
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/michgboxy2/carzone/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// Backend stores encoded entries. Get reports a miss with ok == false and a
+// nil error; errors are reserved for an unusable backend, in which case the
+// decorators fall through to the database.
+type Backend interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte) error
+	Delete(ctx context.Context, keys ...string) error
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// NewBackend builds the backend selected by cfg.Backend. It returns nil for
+// "none".
+func NewBackend(cfg config.Cache) (Backend, error) {
+	switch cfg.Backend {
+	case "none":
+		return nil, nil
+	case "memory":
+		return NewMemory(cfg.Size, cfg.TTL), nil
+	case "redis":
+		return NewRedis(redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword.Value(),
+			DB:       cfg.RedisDB,
+		}), cfg.TTL), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", cfg.Backend)
+	}
+}
+
+// Memory is a per-process LRU whose entries expire after a fixed TTL.
+type Memory struct {
+	lru *expirable.LRU[string, []byte]
+}
+
+func NewMemory(size int, ttl time.Duration) *Memory {
+	return &Memory{lru: expirable.NewLRU[string, []byte](size, nil, ttl)}
+}
+
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok := m.lru.Get(key)
+	return value, ok, nil
+}
+
+func (m *Memory) Set(ctx context.Context, key string, value []byte) error {
+	m.lru.Add(key, value)
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		m.lru.Remove(key)
+	}
+	return nil
+}
+
+func (m *Memory) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+// Redis stores entries in a Redis-compatible server so every instance sees
+// the same cache and invalidations.
+type Redis struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+const redisPrefix = "carzone:"
+
+func NewRedis(client *redis.Client, ttl time.Duration) *Redis {
+	return &Redis{client: client, ttl: ttl}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, redisPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte) error {
+	return r.client.Set(ctx, redisPrefix+key, value, r.ttl).Err()
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = redisPrefix + key
+	}
+	return r.client.Del(ctx, prefixed...).Err()
+}
+
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,236 @@
+// Package cache puts a read-through cache in front of the car and engine
+// stores.
+//
+// Lookups by ID are served from the backend when possible. Concurrent misses
+// for the same key share a single database query. Updates and deletes
+// invalidate the affected entry once their transaction has committed, so a
+// reader can never cache a row that is about to be rolled back. Reads made
+// inside a transaction bypass the cache entirely. A miss that races with a
+// write can still store the row it read before the write; the TTL bounds how
+// long such an entry lives.
+//
+// Cached cars do not trust the engine specs they were stored with: the
+// engine is looked up through the engine cache on every read, so updating an
+// engine never leaves stale specs on the cars that use it.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"golang.org/x/sync/singleflight"
+)
+
+type cache struct {
+	name    string
+	backend Backend
+	group   singleflight.Group
+}
+
+// readThrough returns the entry for key, calling load on a miss. load also
+// reports whether its result may be cached; errors never are.
+func readThrough[T any](ctx context.Context, c *cache, key string, load func(ctx context.Context) (T, bool, error)) (T, error) {
+	if driver.InTx(ctx) {
+		value, _, err := load(ctx)
+		return value, err
+	}
+
+	data, ok, err := c.backend.Get(ctx, key)
+	switch {
+	case err != nil:
+		lookupCounter.WithLabelValues(c.name, "error").Inc()
+		log.Printf("cache get %s: %v", key, err)
+	case ok:
+		var value T
+		if err := json.Unmarshal(data, &value); err == nil {
+			lookupCounter.WithLabelValues(c.name, "hit").Inc()
+			return value, nil
+		}
+		lookupCounter.WithLabelValues(c.name, "error").Inc()
+	default:
+		lookupCounter.WithLabelValues(c.name, "miss").Inc()
+	}
+
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		// Fill from the primary: a lagging replica could otherwise put back
+		// the row an update has just invalidated.
+		value, cacheable, err := load(driver.WithPrimaryReads(ctx))
+		if err != nil || !cacheable {
+			return value, err
+		}
+
+		data, err := json.Marshal(value)
+		if err == nil {
+			err = c.backend.Set(ctx, key, data)
+		}
+		if err != nil {
+			log.Printf("cache set %s: %v", key, err)
+		}
+
+		return value, nil
+	})
+
+	value, _ := v.(T)
+	return value, err
+}
+
+// invalidate drops keys once the surrounding transaction, if any, commits.
+func (c *cache) invalidate(ctx context.Context, keys ...string) {
+	driver.AfterCommit(ctx, func() {
+		if err := c.backend.Delete(context.WithoutCancel(ctx), keys...); err != nil {
+			log.Printf("cache invalidate %v: %v", keys, err)
+		}
+	})
+}
+
+// key builds a cache key from an ID, normalising UUIDs so that differently
+// formatted IDs for the same row share one entry.
+func key(kind, id string) string {
+	if parsed, err := uuid.Parse(id); err == nil {
+		id = parsed.String()
+	}
+	return kind + ":" + id
+}
+
+type CarStore struct {
+	next    store.CarStoreInterface
+	engines store.EngineStoreInterface
+	cache   *cache
+}
+
+// NewCarStore caches next. engines is used to fill in engine specs and
+// should itself be cached.
+func NewCarStore(next store.CarStoreInterface, engines store.EngineStoreInterface, backend Backend) *CarStore {
+	return &CarStore{next: next, engines: engines, cache: &cache{name: "car", backend: backend}}
+}
+
+func (s *CarStore) GetCarById(ctx context.Context, id string) (models.Car, error) {
+	car, err := readThrough(ctx, s.cache, key("car", id), func(ctx context.Context) (models.Car, bool, error) {
+		car, err := s.next.GetCarById(ctx, id)
+		return car, true, err
+	})
+
+	if err != nil || car.Engine.EngineID == uuid.Nil {
+		return car, err
+	}
+
+	// Keep the specs the car was cached with if the engine cannot be loaded.
+	if engine, err := s.engines.GetEngineById(ctx, car.Engine.EngineID.String()); err == nil {
+		car.Engine = engine
+	}
+
+	return car, nil
+}
+
+func (s *CarStore) GetCarByBrand(ctx context.Context, brand string, isEngine bool, filter models.CarFilter) ([]models.Car, error) {
+	return s.next.GetCarByBrand(ctx, brand, isEngine, filter)
+}
+
+func (s *CarStore) GetCarsByEngine(ctx context.Context, engineID uuid.UUID) ([]models.Car, error) {
+	return s.next.GetCarsByEngine(ctx, engineID)
+}
+
+func (s *CarStore) CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error) {
+	return s.next.CreateCar(ctx, carReq)
+}
+
+func (s *CarStore) LockCar(ctx context.Context, id uuid.UUID) error {
+	return s.next.LockCar(ctx, id)
+}
+
+func (s *CarStore) UpdateCar(ctx context.Context, id uuid.UUID, carReq *models.CarRequest) (models.Car, error) {
+	car, err := s.next.UpdateCar(ctx, id, carReq)
+	if err == nil {
+		s.cache.invalidate(ctx, key("car", id.String()))
+	}
+	return car, err
+}
+
+func (s *CarStore) DeleteCar(ctx context.Context, id string) (models.Car, error) {
+	car, err := s.next.DeleteCar(ctx, id)
+	if err == nil {
+		s.cache.invalidate(ctx, key("car", id))
+	}
+	return car, err
+}
+
+func (s *CarStore) RenameBrand(ctx context.Context, brandID uuid.UUID, name string) ([]uuid.UUID, error) {
+	ids, err := s.next.RenameBrand(ctx, brandID, name)
+	if err == nil {
+		s.invalidateCars(ctx, ids)
+	}
+	return ids, err
+}
+
+func (s *CarStore) RenameModel(ctx context.Context, modelID uuid.UUID, name string) ([]uuid.UUID, error) {
+	ids, err := s.next.RenameModel(ctx, modelID, name)
+	if err == nil {
+		s.invalidateCars(ctx, ids)
+	}
+	return ids, err
+}
+
+func (s *CarStore) invalidateCars(ctx context.Context, ids []uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = key("car", id.String())
+	}
+	s.cache.invalidate(ctx, keys...)
+}
+
+type EngineStore struct {
+	next  store.EngineStoreInterface
+	cache *cache
+}
+
+func NewEngineStore(next store.EngineStoreInterface, backend Backend) *EngineStore {
+	return &EngineStore{next: next, cache: &cache{name: "engine", backend: backend}}
+}
+
+func (s *EngineStore) GetEngineById(ctx context.Context, id string) (models.Engine, error) {
+	return readThrough(ctx, s.cache, key("engine", id), func(ctx context.Context) (models.Engine, bool, error) {
+		engine, err := s.next.GetEngineById(ctx, id)
+		return engine, true, err
+	})
+}
+
+func (s *EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+	return s.next.CreateEngine(ctx, engineReq)
+}
+
+func (s *EngineStore) EngineUpdate(ctx context.Context, id uuid.UUID, engineReq *models.EngineRequest) (models.Engine, error) {
+	engine, err := s.next.EngineUpdate(ctx, id, engineReq)
+	if err == nil {
+		s.cache.invalidate(ctx, key("engine", id.String()))
+	}
+	return engine, err
+}
+
+func (s *EngineStore) EngineDelete(ctx context.Context, id string) (models.Engine, error) {
+	engine, err := s.next.EngineDelete(ctx, id)
+	if err == nil {
+		s.cache.invalidate(ctx, key("engine", id))
+	}
+	return engine, err
+}
+
+func (s *EngineStore) ListEngines(ctx context.Context, filter models.EngineFilter) ([]models.Engine, int, error) {
+	return s.next.ListEngines(ctx, filter)
+}
+
+func (s *EngineStore) LockSpecs(ctx context.Context, engineReq *models.EngineRequest) error {
+	return s.next.LockSpecs(ctx, engineReq)
+}
+
+func (s *EngineStore) FindEngineBySpecs(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+	return s.next.FindEngineBySpecs(ctx, engineReq)
+}
@@ -0,0 +1,17 @@
+package cache
+
+import (
+	"github.com/m3db/prometheus_client_golang/prometheus"
+)
+
+var lookupCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cache_lookups_total",
+		Help: "Total number of cache lookups by result (hit, miss or error)",
+	},
+	[]string{"cache", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(lookupCounter)
+}
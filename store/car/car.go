@@ -4,19 +4,22 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/driver"
 	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/money"
+	"github.com/michgboxy2/carzone/store"
 	"go.opentelemetry.io/otel"
 )
 
 type Store struct {
-	db *sql.DB
+	db *driver.DB
 }
 
-func New(db *sql.DB) Store {
+func New(db *driver.DB) Store {
 	return Store{db: db}
 }
 
@@ -31,45 +34,59 @@ func (s Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
 
 	// Prepare the SQL query to select the car and its engine details by car ID
 	query := `
-    SELECT 
-        c.id, c.name, c.year, c.brand, c.fuel_type, e.engine_id,
-        e.displacement, e.no_of_cylinders, e.car_range, 
-        c.price, c.created_at, c.updated_at 
-    FROM 
-        cars c 
-    LEFT JOIN 
-        engines e ON c.engine_id = e.engine_id 
-    WHERE 
+    SELECT
+        c.id, c.name, c.year, c.brand, COALESCE(c.model, ''), c.fuel_type,
+        c.price, c.currency, c.created_at, c.updated_at,
+        ` + store.CarSpecColumns("c") + `,
+        ` + store.CarHistoryColumns("c") + `,
+        ` + store.EngineColumns("e") + `
+    FROM
+        cars c
+    LEFT JOIN
+        engines e ON c.engine_id = e.engine_id
+    WHERE
         c.id = $1`
 
-	// Execute the query
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
+	var price priceColumns
+	var specs store.CarSpecsRow
+	var history store.CarHistoryRow
+	var engine store.EngineRow
+
+	dest := append([]interface{}{
 		&car.ID,
 		&car.Name,
 		&car.Year,
 		&car.Brand,
+		&car.Model,
 		&car.FuelType,
-		&car.Engine.EngineID,
-		&car.Engine.Displacement,
-		&car.Engine.NoOfCylinders,
-		&car.Engine.CarRange,
-		&car.Price,
+		&price.amount,
+		&price.currency,
 		&car.CreatedAt,
 		&car.UpdatedAt,
-	)
+	}, specs.Dest()...)
+	dest = append(dest, history.Dest()...)
+
+	// Execute the query
+	err := s.db.Reader(ctx).QueryRowContext(ctx, query, id).Scan(append(dest, engine.Dest()...)...)
 
 	if err != nil {
+		span.RecordError(err)
 		if err == sql.ErrNoRows {
-			span.RecordError(err)
-			return car, nil
+			return car, models.ErrCarNotFound
 		}
 		return car, err
 	}
 
-	return car, nil
+	car.CarSpecs = specs.Specs()
+	car.CarHistory = history.History()
+	car.Engine = engine.Engine()
+	car.Price, err = price.money()
+
+	return car, err
 }
 
-func (s Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
+// GetCarByBrand lists the cars of a brand that match filter.
+func (s Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool, filter models.CarFilter) ([]models.Car, error) {
 	tracer := otel.Tracer("CarStore")
 
 	ctx, span := tracer.Start(ctx, "GetCarByBrand-Store")
@@ -80,30 +97,35 @@ func (s Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) (
 
 	// Prepare the SQL query to select cars by brand
 	query := `
-		SELECT 
-			c.id, c.name, c.year, c.brand, c.fuel_type, 
-			c.price, c.created_at, c.updated_at`
+		SELECT
+			c.id, c.name, c.year, c.brand, COALESCE(c.model, ''), c.fuel_type,
+			c.price, c.currency, c.created_at, c.updated_at,
+			` + store.CarSpecColumns("c") + `,
+			` + store.CarHistoryColumns("c")
 
 	// If isEngine is true, include engine details in the query
 	if isEngine {
 		query += `,
-			e.engine_id, e.displacement, e.no_of_cylinders, e.car_range 
-		FROM 
-			cars c 
-		LEFT JOIN 
-			engine e ON c.engine_id = e.engine_id 
-		WHERE 
-			c.brand = $1`
+			` + store.EngineColumns("e") + `
+		FROM
+			cars c
+		LEFT JOIN
+			engines e ON c.engine_id = e.engine_id
+		WHERE
+			c.brand_id IN (SELECT brand_id FROM brand_names WHERE key = lower($1))`
 	} else {
-		query += ` 
-		FROM 
-			cars c 
-		WHERE 
-			c.brand = $1`
+		query += `
+		FROM
+			cars c
+		WHERE
+			c.brand_id IN (SELECT brand_id FROM brand_names WHERE key = lower($1))`
 	}
 
+	where, args := store.CarFilterWhere(filter, "c", []interface{}{brand})
+	query += where
+
 	// Execute the query
-	rows, err := s.db.QueryContext(ctx, query, brand)
+	rows, err := s.db.Reader(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
@@ -113,32 +135,36 @@ func (s Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) (
 	// Iterate through the result set
 	for rows.Next() {
 		var car models.Car
+		var price priceColumns
+		var specs store.CarSpecsRow
+		var history store.CarHistoryRow
+
+		dest := append([]interface{}{
+			&car.ID,
+			&car.Name,
+			&car.Year,
+			&car.Brand,
+			&car.Model,
+			&car.FuelType,
+			&price.amount,
+			&price.currency,
+			&car.CreatedAt,
+			&car.UpdatedAt,
+		}, specs.Dest()...)
+		dest = append(dest, history.Dest()...)
+
 		if isEngine {
-			err = rows.Scan(
-				&car.ID,
-				&car.Name,
-				&car.Year,
-				&car.Brand,
-				&car.FuelType,
-				&car.Price,
-				&car.CreatedAt,
-				&car.UpdatedAt,
-				&car.Engine.EngineID,
-				&car.Engine.Displacement,
-				&car.Engine.NoOfCylinders,
-				&car.Engine.CarRange,
-			)
+			var engine store.EngineRow
+			err = rows.Scan(append(dest, engine.Dest()...)...)
+			car.Engine = engine.Engine()
 		} else {
-			err = rows.Scan(
-				&car.ID,
-				&car.Name,
-				&car.Year,
-				&car.Brand,
-				&car.FuelType,
-				&car.Price,
-				&car.CreatedAt,
-				&car.UpdatedAt,
-			)
+			err = rows.Scan(dest...)
+		}
+
+		if err == nil {
+			car.CarSpecs = specs.Specs()
+			car.CarHistory = history.History()
+			car.Price, err = price.money()
 		}
 
 		if err != nil {
@@ -157,83 +183,173 @@ func (s Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) (
 	return cars, nil
 }
 
-func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error) {
+func (s Store) GetCarsByEngine(ctx context.Context, engineID uuid.UUID) ([]models.Car, error) {
 	tracer := otel.Tracer("CarStore")
 
-	ctx, span := tracer.Start(ctx, "CreateCar-Store")
+	ctx, span := tracer.Start(ctx, "GetCarsByEngine-Store")
 
 	defer span.End()
 
-	var car models.Car
-	var engineID uuid.UUID
-
-	err := s.db.QueryRowContext(ctx, "SELECT id from engine where id=$1", carReq.Engine.EngineID).Scan(&engineID)
-
+	query := `
+		SELECT
+			c.id, c.name, c.year, c.brand, COALESCE(c.model, ''), c.fuel_type,
+			c.price, c.currency, c.created_at, c.updated_at,
+			` + store.CarSpecColumns("c") + `,
+			` + store.CarHistoryColumns("c") + `,
+			` + store.EngineColumns("e") + `
+		FROM
+			cars c
+		JOIN
+			engines e ON c.engine_id = e.engine_id
+		WHERE
+			c.engine_id = $1
+		ORDER BY
+			c.created_at, c.id`
+
+	rows, err := s.db.Reader(ctx).QueryContext(ctx, query, engineID)
 	if err != nil {
 		span.RecordError(err)
-		if errors.Is(err, sql.ErrNoRows) {
-			return car, errors.New("engine_id does not exist in the engine table")
+		return nil, err
+	}
+	defer rows.Close()
+
+	cars := []models.Car{}
+
+	for rows.Next() {
+		var car models.Car
+		var price priceColumns
+		var specs store.CarSpecsRow
+		var history store.CarHistoryRow
+		var engine store.EngineRow
+
+		dest := append([]interface{}{
+			&car.ID,
+			&car.Name,
+			&car.Year,
+			&car.Brand,
+			&car.Model,
+			&car.FuelType,
+			&price.amount,
+			&price.currency,
+			&car.CreatedAt,
+			&car.UpdatedAt,
+		}, specs.Dest()...)
+		dest = append(dest, history.Dest()...)
+
+		err := rows.Scan(append(dest, engine.Dest()...)...)
+
+		if err == nil {
+			car.CarSpecs = specs.Specs()
+			car.CarHistory = history.History()
+			car.Engine = engine.Engine()
+			car.Price, err = price.money()
 		}
 
-		return car, err
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		cars = append(cars, car)
 	}
 
-	//Begin Transaction
-	tx, err := s.db.BeginTx(ctx, nil)
-
-	if err != nil {
+	if err := rows.Err(); err != nil {
 		span.RecordError(err)
-		return car, err
+		return nil, err
 	}
 
-	defer func() {
+	return cars, nil
+}
+
+func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error) {
+	tracer := otel.Tracer("CarStore")
+
+	ctx, span := tracer.Start(ctx, "CreateCar-Store")
+
+	defer span.End()
+
+	var car models.Car
+
+	err := s.db.WithinTx(ctx, func(ctx context.Context) error {
+		conn := s.db.Conn(ctx)
+
+		// Make sure the engine exists, and load its specs for the response
+		var engine store.EngineRow
+		err := conn.QueryRowContext(ctx,
+			"SELECT "+store.EngineColumns("")+" FROM engines WHERE engine_id = $1",
+			carReq.Engine.EngineID,
+		).Scan(engine.Dest()...)
+
 		if err != nil {
-			tx.Rollback()
-			span.RecordError(err)
-			return
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("engine_id does not exist in the engines table")
+			}
+			return err
+		}
+		car.Engine = engine.Engine()
+
+		query := `
+		INSERT INTO cars (id, name, year, brand, fuel_type, engine_id, price, currency, created_at, updated_at, ` + store.CarSpecColumns("") + `,
+			` + store.CarCatalogColumns + `, ` + store.CarHistoryColumns("") + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
+			` + store.CarCatalogValues("$4", "$18") + `, $19, $20, $21, $22, $23, $24)`
+
+		// Get the current time for created_at and updated_at
+		now := time.Now()
+		carID := uuid.New()
+
+		// Execute the insert query
+		args := append([]interface{}{carID, carReq.Name, carReq.Year, carReq.Brand, carReq.FuelType, carReq.Engine.EngineID,
+			carReq.Price.Amount(), carReq.Price.Currency, now, now}, store.CarSpecValues(carReq.CarSpecs)...)
+		args = append(args, store.CarModelValue(carReq.Model))
+		args = append(args, store.CarHistoryValues(carReq.CarHistory)...)
+		_, err = conn.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
 		}
-		err = tx.Commit()
-	}()
-
-	query := `
-	INSERT INTO cars (id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at) 
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
 
-	// Get the current time for created_at and updated_at
-	now := time.Now()
+		// Set the car fields
+		car.ID = carID
+		car.Name = carReq.Name
+		car.Year = carReq.Year
+		car.Brand = carReq.Brand
+		car.Model = carReq.Model
+		car.FuelType = carReq.FuelType
+		car.Price = carReq.Price
+		car.CarSpecs = carReq.CarSpecs
+		car.CarHistory = carReq.CarHistory
+		car.CreatedAt = now
+		car.UpdatedAt = now
+
+		return nil
+	})
 
-	// Execute the insert query
-	result, err := tx.ExecContext(ctx, query, uuid.New(), carReq.Name, carReq.Year, carReq.Brand, carReq.FuelType, carReq.Engine.EngineID, carReq.Price, now, now)
 	if err != nil {
-		tx.Rollback()
 		span.RecordError(err)
-		return car, err
+		return models.Car{}, err
 	}
 
-	// Get the last inserted ID
-	carID, err := result.LastInsertId()
-	if err != nil {
-		tx.Rollback()
-		span.RecordError(err)
-		return car, err
-	}
+	return car, nil
+}
 
-	// Set the car fields
-	car.ID = uuid.New() // Assuming you want to generate a new UUID for the car
-	car.Name = carReq.Name
-	car.Year = carReq.Year
-	car.Brand = carReq.Brand
-	car.FuelType = carReq.FuelType
-	car.Engine = carReq.Engine
-	car.Price = carReq.Price
-	car.CreatedAt = now
-	car.UpdatedAt = now
+// LockCar locks the car's row for the rest of the transaction carried by
+// ctx, so a car is read, checked and updated by one request at a time.
+func (s Store) LockCar(ctx context.Context, id uuid.UUID) error {
+	tracer := otel.Tracer("CarStore")
 
-	// Set the ID of the car
-	car.ID = uuid.Must(uuid.Parse(fmt.Sprintf("%d", carID))) // Convert the last inserted ID to UUID if necessary
+	ctx, span := tracer.Start(ctx, "LockCar-Store")
 
-	return car, nil
+	defer span.End()
 
+	var locked uuid.UUID
+	err := s.db.Conn(ctx).QueryRowContext(ctx, "SELECT id FROM cars WHERE id = $1 FOR NO KEY UPDATE", id).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrCarNotFound
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return err
 }
 
 func (s Store) UpdateCar(ctx context.Context, id uuid.UUID, carReq *models.CarRequest) (models.Car, error) {
@@ -245,47 +361,49 @@ func (s Store) UpdateCar(ctx context.Context, id uuid.UUID, carReq *models.CarRe
 
 	var updatedCar models.Car
 
-	// Begin Transaction
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		span.RecordError(err)
-		return updatedCar, err
-	}
-
-	defer func() {
-		if err != nil {
-			tx.Rollback() // Rollback on error
-			span.RecordError(err)
-			return
-		}
-		err = tx.Commit() // Commit if no error
-	}()
-
 	// Prepare the SQL query to update the car
 	query := `
-    UPDATE cars 
-    SET name = $1, year = $2, brand = $3, fuel_type = $4, price = $5, updated_at = $6 
-    WHERE id = $7`
+    UPDATE cars
+    SET name = $1, year = $2, brand = $3, fuel_type = $4, price = $5, currency = $6, updated_at = $7,
+        (` + store.CarSpecColumns("") + `) = ($9, $10, $11, $12, $13, $14, $15),
+        (` + store.CarCatalogColumns + `) = (` + store.CarCatalogValues("$3", "$16") + `),
+        (` + store.CarHistoryColumns("") + `) = ($17, $18, $19, $20, $21, $22)
+    WHERE id = $8`
 
 	// Get the current time for updated_at
 	now := time.Now()
 
 	// Execute the update query
-	_, err = tx.ExecContext(ctx, query, carReq.Name, carReq.Year, carReq.Brand, carReq.FuelType, carReq.Price, now, id)
+	args := append([]interface{}{carReq.Name, carReq.Year, carReq.Brand, carReq.FuelType,
+		carReq.Price.Amount(), carReq.Price.Currency, now, id}, store.CarSpecValues(carReq.CarSpecs)...)
+	args = append(args, store.CarModelValue(carReq.Model))
+	args = append(args, store.CarHistoryValues(carReq.CarHistory)...)
+	err := s.db.WithinTx(ctx, func(ctx context.Context) error {
+		result, err := s.db.Conn(ctx).ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return models.ErrCarNotFound
+		}
+
+		// Read the car back with its engine and creation time, as the
+		// request holds only what was changed.
+		updatedCar, err = s.GetCarById(ctx, id.String())
+		return err
+	})
+
 	if err != nil {
 		span.RecordError(err)
-		return updatedCar, err // Return error if the update fails
+		return models.Car{}, err
 	}
 
-	// Set the updated car fields
-	updatedCar.ID = id // Assuming id is a string that matches the car's ID
-	updatedCar.Name = carReq.Name
-	updatedCar.Year = carReq.Year
-	updatedCar.Brand = carReq.Brand
-	updatedCar.FuelType = carReq.FuelType
-	updatedCar.Price = carReq.Price
-	updatedCar.UpdatedAt = now
-
 	return updatedCar, nil
 }
 
@@ -298,61 +416,127 @@ func (s Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
 
 	var deletedCar models.Car
 
-	// Begin Transaction
-	tx, err := s.db.BeginTx(ctx, nil)
+	err := s.db.WithinTx(ctx, func(ctx context.Context) error {
+		conn := s.db.Conn(ctx)
+
+		// Select the car before deletion, locking the row until we are done
+		selectQuery := `SELECT id, name, year, brand, COALESCE(model, ''), fuel_type, price, currency, created_at, updated_at, ` + store.CarSpecColumns("") + `,
+			` + store.CarHistoryColumns("") + `
+			FROM cars WHERE id = $1 FOR UPDATE`
+		var price priceColumns
+		var specs store.CarSpecsRow
+		var history store.CarHistoryRow
+		err := conn.QueryRowContext(ctx, selectQuery, id).Scan(append(append([]interface{}{
+			&deletedCar.ID,
+			&deletedCar.Name,
+			&deletedCar.Year,
+			&deletedCar.Brand,
+			&deletedCar.Model,
+			&deletedCar.FuelType,
+			&price.amount,
+			&price.currency,
+			&deletedCar.CreatedAt,
+			&deletedCar.UpdatedAt,
+		}, specs.Dest()...), history.Dest()...)...)
+
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrCarNotFound
+			}
+			return err
+		}
+
+		deletedCar.CarSpecs = specs.Specs()
+		deletedCar.CarHistory = history.History()
+		deletedCar.Price, err = price.money()
+		if err != nil {
+			return err
+		}
+
+		// Prepare the SQL query to delete the car
+		deleteQuery := `DELETE FROM cars WHERE id = $1`
+		result, err := conn.ExecContext(ctx, deleteQuery, id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return errors.New("no rows were deleted")
+		}
+
+		return nil
+	})
+
 	if err != nil {
 		span.RecordError(err)
-		return deletedCar, err
+		return models.Car{}, err
 	}
 
-	defer func() {
-		if err != nil {
-			tx.Rollback() // Rollback on error
-			span.RecordError(err)
-			return
-		}
-		err = tx.Commit() // Commit if no error
-	}()
-
-	// Prepare the SQL query to select the car before deletion
-	selectQuery := `SELECT id, name, year, brand, fuel_type, price, created_at, updated_at FROM cars WHERE id = $1`
-	err = tx.QueryRowContext(ctx, selectQuery, id).Scan(
-		&deletedCar.ID,
-		&deletedCar.Name,
-		&deletedCar.Year,
-		&deletedCar.Brand,
-		&deletedCar.FuelType,
-		&deletedCar.Price,
-		&deletedCar.CreatedAt,
-		&deletedCar.UpdatedAt,
-	)
+	return deletedCar, nil
+}
+
+func (s Store) RenameBrand(ctx context.Context, brandID uuid.UUID, name string) ([]uuid.UUID, error) {
+	tracer := otel.Tracer("CarStore")
 
+	ctx, span := tracer.Start(ctx, "RenameBrand-Store")
+
+	defer span.End()
+
+	ids, err := s.rename(ctx, "UPDATE cars SET brand = $1, updated_at = CURRENT_TIMESTAMP WHERE brand_id = $2 AND brand <> $1 RETURNING id", name, brandID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			span.RecordError(err)
-			return deletedCar, errors.New("car not found")
-		}
-		return deletedCar, err
+		span.RecordError(err)
 	}
 
-	// Prepare the SQL query to delete the car
-	deleteQuery := `DELETE FROM cars WHERE id = $1`
-	result, err := tx.ExecContext(ctx, deleteQuery, id)
+	return ids, err
+}
+
+func (s Store) RenameModel(ctx context.Context, modelID uuid.UUID, name string) ([]uuid.UUID, error) {
+	tracer := otel.Tracer("CarStore")
+
+	ctx, span := tracer.Start(ctx, "RenameModel-Store")
+
+	defer span.End()
+
+	ids, err := s.rename(ctx, "UPDATE cars SET model = $1, updated_at = CURRENT_TIMESTAMP WHERE model_id = $2 AND model IS DISTINCT FROM $1 RETURNING id", name, modelID)
 	if err != nil {
 		span.RecordError(err)
-		return deletedCar, err // Return error if the deletion fails
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	return ids, err
+}
 
+// rename runs an UPDATE returning the IDs of the cars it changed.
+func (s Store) rename(ctx context.Context, query string, name string, id uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := s.db.Conn(ctx).QueryContext(ctx, query, name, id)
 	if err != nil {
-		return deletedCar, err
+		return nil, err
 	}
+	defer rows.Close()
 
-	if rowsAffected == 0 {
-		return models.Car{}, errors.New("no rows were deleted")
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
 	}
 
-	return deletedCar, nil
+	return ids, rows.Err()
+}
+
+// priceColumns receives the price and currency columns of a car, which
+// together make up its money.Money.
+type priceColumns struct {
+	amount   string
+	currency string
+}
 
+func (p priceColumns) money() (money.Money, error) {
+	return money.Parse(p.amount, strings.TrimSpace(p.currency))
 }
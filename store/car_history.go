@@ -0,0 +1,122 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/michgboxy2/carzone/models"
+)
+
+// carHistoryColumns are the columns of a car's models.CarHistory.
+var carHistoryColumns = []string{"mileage", "mileage_unit", "condition", "previous_owners", "accidents", "service_history"}
+
+// CarHistoryColumns lists the columns CarHistoryRow scans and
+// CarHistoryValues fills, each prefixed with alias and a dot unless alias
+// is empty.
+func CarHistoryColumns(alias string) string {
+	if alias == "" {
+		return strings.Join(carHistoryColumns, ", ")
+	}
+	return alias + "." + strings.Join(carHistoryColumns, ", "+alias+".")
+}
+
+// CarHistoryValues returns the values of the history columns. What is not
+// known is NULL.
+func CarHistoryValues(history models.CarHistory) []interface{} {
+	text := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: s != ""}
+	}
+	count := func(n *int) sql.NullInt64 {
+		if n == nil {
+			return sql.NullInt64{}
+		}
+		return sql.NullInt64{Int64: int64(*n), Valid: true}
+	}
+
+	var mileage sql.NullInt64
+	var unit sql.NullString
+	if m := history.Mileage; m != nil {
+		mileage = sql.NullInt64{Int64: m.Value, Valid: true}
+		unit = text(m.Unit)
+	}
+
+	return []interface{}{
+		mileage, unit, text(history.Condition), count(history.PreviousOwners), count(history.Accidents), text(history.ServiceHistory),
+	}
+}
+
+// CarHistoryRow receives the columns listed by CarHistoryColumns.
+type CarHistoryRow struct {
+	mileage, owners, accidents      sql.NullInt64
+	unit, condition, serviceHistory sql.NullString
+}
+
+// Dest returns the scan destinations for the columns, in order.
+func (r *CarHistoryRow) Dest() []interface{} {
+	return []interface{}{&r.mileage, &r.unit, &r.condition, &r.owners, &r.accidents, &r.serviceHistory}
+}
+
+func (r *CarHistoryRow) History() models.CarHistory {
+	count := func(n sql.NullInt64) *int {
+		if !n.Valid {
+			return nil
+		}
+		v := int(n.Int64)
+		return &v
+	}
+
+	history := models.CarHistory{
+		Condition:      r.condition.String,
+		PreviousOwners: count(r.owners),
+		Accidents:      count(r.accidents),
+		ServiceHistory: r.serviceHistory.String,
+	}
+
+	if r.mileage.Valid {
+		history.Mileage = &models.Mileage{Value: r.mileage.Int64, Unit: r.unit.String}
+	}
+
+	return history
+}
+
+// CarFilterWhere returns the conditions of filter on the cars aliased as
+// alias, each starting with AND, along with args extended by their values.
+func CarFilterWhere(filter models.CarFilter, alias string, args []interface{}) (string, []interface{}) {
+	var where strings.Builder
+
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		fmt.Fprintf(&where, " AND "+condition, alias, len(args))
+	}
+
+	// mileage_km is stored to two decimal places, so bounds are rounded the
+	// same way; otherwise a car recorded at exactly the bound in miles would
+	// fall either side of it.
+	if filter.MinMileage > 0 {
+		add("%s.mileage_km >= $%d", models.Mileage{Value: filter.MinMileage, Unit: filter.MileageUnit}.RoundedKilometres())
+	}
+
+	if filter.MaxMileage > 0 {
+		add("%s.mileage_km <= $%d", models.Mileage{Value: filter.MaxMileage, Unit: filter.MileageUnit}.RoundedKilometres())
+	}
+
+	if filter.MinCondition != "" {
+		add("%s.condition = ANY($%d)", pq.Array(models.ConditionsAtLeast(filter.MinCondition)))
+	}
+
+	if filter.MaxOwners != nil {
+		add("%s.previous_owners <= $%d", *filter.MaxOwners)
+	}
+
+	if filter.AccidentFree {
+		add("%s.accidents = $%d", 0)
+	}
+
+	if filter.ServiceHistory != "" {
+		add("%s.service_history = $%d", filter.ServiceHistory)
+	}
+
+	return where.String(), args
+}
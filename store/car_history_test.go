@@ -0,0 +1,64 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/michgboxy2/carzone/models"
+)
+
+func TestCarFilterWhere(t *testing.T) {
+	owners := 2
+
+	tests := []struct {
+		name      string
+		filter    models.CarFilter
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "no filters",
+			filter:    models.CarFilter{MileageUnit: models.MileageKilometres},
+			wantWhere: "",
+			wantArgs:  []interface{}{"Toyota"},
+		},
+		{
+			name:      "mileage in kilometres",
+			filter:    models.CarFilter{MinMileage: 100, MaxMileage: 5000, MileageUnit: models.MileageKilometres},
+			wantWhere: " AND c.mileage_km >= $2 AND c.mileage_km <= $3",
+			wantArgs:  []interface{}{"Toyota", 100.0, 5000.0},
+		},
+		{
+			name:      "mileage in miles is rounded as stored",
+			filter:    models.CarFilter{MinMileage: 100, MaxMileage: 3, MileageUnit: models.MileageMiles},
+			wantWhere: " AND c.mileage_km >= $2 AND c.mileage_km <= $3",
+			wantArgs:  []interface{}{"Toyota", 160.93, 4.83},
+		},
+		{
+			name: "history",
+			filter: models.CarFilter{
+				MileageUnit:    models.MileageKilometres,
+				MinCondition:   "good",
+				MaxOwners:      &owners,
+				AccidentFree:   true,
+				ServiceHistory: "full",
+			},
+			wantWhere: " AND c.condition = ANY($2) AND c.previous_owners <= $3 AND c.accidents = $4 AND c.service_history = $5",
+			wantArgs:  []interface{}{"Toyota", pq.Array([]string{"new", "excellent", "good"}), 2, 0, "full"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := CarFilterWhere(tt.filter, "c", []interface{}{"Toyota"})
+
+			if where != tt.wantWhere {
+				t.Fatalf("where = %q, want %q", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Fatalf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/michgboxy2/carzone/models"
+)
+
+// carSpecColumns are the columns of a car's models.CarSpecs.
+var carSpecColumns = []string{"trim_level", "transmission", "drivetrain", "body_style", "colour", "doors", "seats"}
+
+// CarSpecColumns lists the columns CarSpecsRow scans and CarSpecValues
+// fills, each prefixed with alias and a dot unless alias is empty.
+func CarSpecColumns(alias string) string {
+	if alias == "" {
+		return strings.Join(carSpecColumns, ", ")
+	}
+	return alias + "." + strings.Join(carSpecColumns, ", "+alias+".")
+}
+
+// CarSpecValues returns the values of the spec columns. Specs that are not
+// set are NULL.
+func CarSpecValues(specs models.CarSpecs) []interface{} {
+	text := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: s != ""}
+	}
+	number := func(n int) sql.NullInt64 {
+		return sql.NullInt64{Int64: int64(n), Valid: n != 0}
+	}
+
+	return []interface{}{
+		text(specs.Trim), text(specs.Transmission), text(specs.Drivetrain), text(specs.BodyStyle), text(specs.Colour),
+		number(specs.Doors), number(specs.Seats),
+	}
+}
+
+// CarSpecsRow receives the columns listed by CarSpecColumns.
+type CarSpecsRow struct {
+	trim, transmission, drivetrain, bodyStyle, colour sql.NullString
+	doors, seats                                      sql.NullInt64
+}
+
+// Dest returns the scan destinations for the columns, in order.
+func (r *CarSpecsRow) Dest() []interface{} {
+	return []interface{}{&r.trim, &r.transmission, &r.drivetrain, &r.bodyStyle, &r.colour, &r.doors, &r.seats}
+}
+
+func (r *CarSpecsRow) Specs() models.CarSpecs {
+	return models.CarSpecs{
+		Trim:         r.trim.String,
+		Transmission: r.transmission.String,
+		Drivetrain:   r.drivetrain.String,
+		BodyStyle:    r.bodyStyle.String,
+		Colour:       r.colour.String,
+		Doors:        int(r.doors.Int64),
+		Seats:        int(r.seats.Int64),
+	}
+}
+
+// IsViolation reports whether err is the Postgres error with the given
+// SQLSTATE, such as 23505 for a unique violation.
+func IsViolation(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
@@ -0,0 +1,24 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// CarCatalogColumns are the columns that file a car under the brand and
+// model catalogue, after its model name.
+const CarCatalogColumns = "model, brand_id, model_id"
+
+// CarCatalogValues returns the SQL for the values of CarCatalogColumns. The
+// brand and model placeholders, such as "$4", are bound to the canonical
+// names, and the model to CarModelValue.
+func CarCatalogValues(brand, model string) string {
+	return fmt.Sprintf(`%[2]s,
+		(SELECT id FROM brands WHERE name = %[1]s),
+		(SELECT m.id FROM models m JOIN brands b ON b.id = m.brand_id WHERE b.name = %[1]s AND m.name = %[2]s)`, brand, model)
+}
+
+// CarModelValue is the value of a car's model column, NULL for none.
+func CarModelValue(model string) sql.NullString {
+	return sql.NullString{String: model, Valid: model != ""}
+}
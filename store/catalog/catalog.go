@@ -0,0 +1,464 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+const brandColumns = `b.id, b.name,
+	ARRAY(SELECT alias FROM brand_aliases a WHERE a.brand_id = b.id ORDER BY lower(alias)),
+	b.created_at, b.updated_at`
+
+const modelColumns = `m.id, m.brand_id, m.name,
+	ARRAY(SELECT alias FROM model_aliases a WHERE a.model_id = m.id ORDER BY lower(alias)),
+	m.created_at, m.updated_at`
+
+// Store keeps the brand and model catalogue.
+type Store struct {
+	db *driver.DB
+}
+
+func New(db *driver.DB) *Store {
+	return &Store{db: db}
+}
+
+// LockCatalog serialises changes to the catalogue until the surrounding
+// transaction ends.
+func (s *Store) LockCatalog(ctx context.Context) error {
+	tracer := otel.Tracer("CatalogStore")
+
+	ctx, span := tracer.Start(ctx, "LockCatalog-Store")
+
+	defer span.End()
+
+	_, err := s.db.Conn(ctx).ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "catalog")
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return err
+}
+
+func (s *Store) ListBrands(ctx context.Context) ([]models.Brand, error) {
+	tracer := otel.Tracer("CatalogStore")
+
+	ctx, span := tracer.Start(ctx, "ListBrands-Store")
+
+	defer span.End()
+
+	rows, err := s.db.Reader(ctx).QueryContext(ctx, "SELECT "+brandColumns+" FROM brands b ORDER BY lower(b.name)")
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	brands := []models.Brand{}
+
+	for rows.Next() {
+		brand, err := scanBrand(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		brands = append(brands, brand)
+	}
+
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return brands, nil
+}
+
+func (s *Store) GetBrand(ctx context.Context, id uuid.UUID) (models.Brand, error) {
+	tracer := otel.Tracer("CatalogStore")
+
+	ctx, span := tracer.Start(ctx, "GetBrand-Store")
+
+	defer span.End()
+
+	brand, err := scanBrand(s.db.Reader(ctx).QueryRowContext(ctx, "SELECT "+brandColumns+" FROM brands b WHERE b.id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Brand{}, models.ErrBrandNotFound
+		}
+		span.RecordError(err)
+		return models.Brand{}, err
+	}
+
+	return brand, nil
+}
+
+// FindBrand returns the brand name is the name or an alias of, whatever its
+// case, or models.ErrBrandNotFound.
+func (s *Store) FindBrand(ctx context.Context, name string) (models.Brand, error) {
+	tracer := otel.Tracer("CatalogStore")
+
+	ctx, span := tracer.Start(ctx, "FindBrand-Store")
+
+	defer span.End()
+
+	query := `
+		SELECT ` + brandColumns + `
+		FROM brands b
+		WHERE b.id IN (SELECT brand_id FROM brand_names WHERE key = lower($1))
+		ORDER BY b.created_at
+		LIMIT 1`
+
+	brand, err := scanBrand(s.db.Reader(ctx).QueryRowContext(ctx, query, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Brand{}, models.ErrBrandNotFound
+		}
+		span.RecordError(err)
+		return models.Brand{}, err
+	}
+
+	return brand, nil
+}
+
+func (s *Store) CreateBrand(ctx context.Context, req models.CatalogRequest) (models.Brand, error) {
+	tracer := otel.Tracer("CatalogStore")
+
+	ctx, span := tracer.Start(ctx, "CreateBrand-Store")
+
+	defer span.End()
+
+	now := time.Now()
+	brand := models.Brand{ID: uuid.New(), Name: req.Name, CreatedAt: now, UpdatedAt: now}
+
+	err := s.db.WithinTx(ctx, func(ctx context.Context) error {
+		_, err := s.db.Conn(ctx).ExecContext(ctx,
+			"INSERT INTO brands (id, name, created_at, updated_at) VALUES ($1, $2, $3, $4)",
+			brand.ID, brand.Name, now, now)
+		if err != nil {
+			return err
+		}
+
+		brand.Aliases, err = s.setBrandAliases(ctx, brand.ID, req.Aliases)
+		return err
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		if store.IsViolation(err, "23505") {
+			return models.Brand{}, models.ErrBrandExists
+		}
+		return models.Brand{}, err
+	}
+
+	return brand, nil
+}
+
+// UpdateBrand renames the brand and replaces its aliases. Its cars are left
+// to the caller.
+func (s *Store) UpdateBrand(ctx context.Context, id uuid.UUID, req models.CatalogRequest) (models.Brand, error) {
+	tracer := otel.Tracer("CatalogStore")
+
+	ctx, span := tracer.Start(ctx, "UpdateBrand-Store")
+
+	defer span.End()
+
+	var brand models.Brand
+
+	err := s.db.WithinTx(ctx, func(ctx context.Context) error {
+		conn := s.db.Conn(ctx)
+
+		err := conn.QueryRowContext(ctx,
+			"UPDATE brands SET name = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 RETURNING id, name, created_at, updated_at",
+			req.Name, id,
+		).Scan(&brand.ID, &brand.Name, &brand.CreatedAt, &brand.UpdatedAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.ErrBrandNotFound
+			}
+			return err
+		}
+
+		brand.Aliases, err = s.setBrandAliases(ctx, id, req.Aliases)
+		return err
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		if store.IsViolation(err, "23505") {
+			return models.Brand{}, models.ErrBrandExists
+		}
+		return models.Brand{}, err
+	}
+
+	return brand, nil
+}
+
+// DeleteBrand removes the brand with its models. It reports
+// models.ErrBrandInUse while cars are filed under it or saved searches
+// look for it.
+func (s *Store) DeleteBrand(ctx context.Context, id uuid.UUID) error {
+	tracer := otel.Tracer("CatalogStore")
+
+	ctx, span := tracer.Start(ctx, "DeleteBrand-Store")
+
+	defer span.End()
+
+	result, err := s.db.Conn(ctx).ExecContext(ctx, "DELETE FROM brands WHERE id = $1", id)
+	if err != nil {
+		span.RecordError(err)
+		if store.IsViolation(err, "23503") {
+			return models.ErrBrandInUse
+		}
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if n == 0 {
+		return models.ErrBrandNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) setBrandAliases(ctx context.Context, brandID uuid.UUID, aliases []string) ([]string, error) {
+	conn := s.db.Conn(ctx)
+
+	if _, err := conn.ExecContext(ctx, "DELETE FROM brand_aliases WHERE brand_id = $1", brandID); err != nil {
+		return nil, err
+	}
+
+	for _, alias := range aliases {
+		if _, err := conn.ExecContext(ctx, "INSERT INTO brand_aliases (brand_id, alias) VALUES ($1, $2)", brandID, alias); err != nil {
+			return nil, err
+		}
+	}
+
+	return append([]string{}, aliases...), nil
+}
+
+func (s *Store) ListModels(ctx context.Context, brandID uuid.UUID) ([]models.CarModel, error) {
+	tracer := otel.Tracer("CatalogStore")
+
+	ctx, span := tracer.Start(ctx, "ListModels-Store")
+
+	defer span.End()
+
+	rows, err := s.db.Reader(ctx).QueryContext(ctx,
+		"SELECT "+modelColumns+" FROM models m WHERE m.brand_id = $1 ORDER BY lower(m.name)", brandID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	carModels := []models.CarModel{}
+
+	for rows.Next() {
+		model, err := scanModel(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		carModels = append(carModels, model)
+	}
+
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return carModels, nil
+}
+
+// FindModel returns the model of the brand that name is the name or an
+// alias of, whatever its case, or models.ErrModelNotFound.
+func (s *Store) FindModel(ctx context.Context, brandID uuid.UUID, name string) (models.CarModel, error) {
+	tracer := otel.Tracer("CatalogStore")
+
+	ctx, span := tracer.Start(ctx, "FindModel-Store")
+
+	defer span.End()
+
+	query := `
+		SELECT ` + modelColumns + `
+		FROM models m
+		WHERE m.id IN (SELECT model_id FROM model_names WHERE brand_id = $1 AND key = lower($2))
+		ORDER BY m.created_at
+		LIMIT 1`
+
+	model, err := scanModel(s.db.Reader(ctx).QueryRowContext(ctx, query, brandID, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CarModel{}, models.ErrModelNotFound
+		}
+		span.RecordError(err)
+		return models.CarModel{}, err
+	}
+
+	return model, nil
+}
+
+func (s *Store) CreateModel(ctx context.Context, brandID uuid.UUID, req models.CatalogRequest) (models.CarModel, error) {
+	tracer := otel.Tracer("CatalogStore")
+
+	ctx, span := tracer.Start(ctx, "CreateModel-Store")
+
+	defer span.End()
+
+	now := time.Now()
+	model := models.CarModel{ID: uuid.New(), BrandID: brandID, Name: req.Name, CreatedAt: now, UpdatedAt: now}
+
+	err := s.db.WithinTx(ctx, func(ctx context.Context) error {
+		_, err := s.db.Conn(ctx).ExecContext(ctx,
+			"INSERT INTO models (id, brand_id, name, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)",
+			model.ID, brandID, model.Name, now, now)
+		if err != nil {
+			return err
+		}
+
+		model.Aliases, err = s.setModelAliases(ctx, brandID, model.ID, req.Aliases)
+		return err
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		switch {
+		case store.IsViolation(err, "23505"):
+			return models.CarModel{}, models.ErrModelExists
+		case store.IsViolation(err, "23503"):
+			return models.CarModel{}, models.ErrBrandNotFound
+		}
+		return models.CarModel{}, err
+	}
+
+	return model, nil
+}
+
+// UpdateModel renames the model and replaces its aliases. Its cars are left
+// to the caller.
+func (s *Store) UpdateModel(ctx context.Context, brandID, id uuid.UUID, req models.CatalogRequest) (models.CarModel, error) {
+	tracer := otel.Tracer("CatalogStore")
+
+	ctx, span := tracer.Start(ctx, "UpdateModel-Store")
+
+	defer span.End()
+
+	var model models.CarModel
+
+	err := s.db.WithinTx(ctx, func(ctx context.Context) error {
+		conn := s.db.Conn(ctx)
+
+		err := conn.QueryRowContext(ctx, `
+			UPDATE models SET name = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $2 AND brand_id = $3
+			RETURNING id, brand_id, name, created_at, updated_at`,
+			req.Name, id, brandID,
+		).Scan(&model.ID, &model.BrandID, &model.Name, &model.CreatedAt, &model.UpdatedAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.ErrModelNotFound
+			}
+			return err
+		}
+
+		model.Aliases, err = s.setModelAliases(ctx, brandID, id, req.Aliases)
+		return err
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		if store.IsViolation(err, "23505") {
+			return models.CarModel{}, models.ErrModelExists
+		}
+		return models.CarModel{}, err
+	}
+
+	return model, nil
+}
+
+// DeleteModel reports models.ErrModelInUse while cars are of that model.
+func (s *Store) DeleteModel(ctx context.Context, brandID, id uuid.UUID) error {
+	tracer := otel.Tracer("CatalogStore")
+
+	ctx, span := tracer.Start(ctx, "DeleteModel-Store")
+
+	defer span.End()
+
+	result, err := s.db.Conn(ctx).ExecContext(ctx, "DELETE FROM models WHERE id = $1 AND brand_id = $2", id, brandID)
+	if err != nil {
+		span.RecordError(err)
+		if store.IsViolation(err, "23503") {
+			return models.ErrModelInUse
+		}
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if n == 0 {
+		return models.ErrModelNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) setModelAliases(ctx context.Context, brandID, modelID uuid.UUID, aliases []string) ([]string, error) {
+	conn := s.db.Conn(ctx)
+
+	if _, err := conn.ExecContext(ctx, "DELETE FROM model_aliases WHERE model_id = $1", modelID); err != nil {
+		return nil, err
+	}
+
+	for _, alias := range aliases {
+		_, err := conn.ExecContext(ctx,
+			"INSERT INTO model_aliases (model_id, brand_id, alias) VALUES ($1, $2, $3)", modelID, brandID, alias)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return append([]string{}, aliases...), nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBrand(row scanner) (models.Brand, error) {
+	var brand models.Brand
+
+	err := row.Scan(&brand.ID, &brand.Name, pq.Array(&brand.Aliases), &brand.CreatedAt, &brand.UpdatedAt)
+	if brand.Aliases == nil {
+		brand.Aliases = []string{}
+	}
+
+	return brand, err
+}
+
+func scanModel(row scanner) (models.CarModel, error) {
+	var model models.CarModel
+
+	err := row.Scan(&model.ID, &model.BrandID, &model.Name, pq.Array(&model.Aliases), &model.CreatedAt, &model.UpdatedAt)
+	if model.Aliases == nil {
+		model.Aliases = []string{}
+	}
+
+	return model, err
+}
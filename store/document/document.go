@@ -0,0 +1,204 @@
+package document
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"go.opentelemetry.io/otel"
+)
+
+const documentColumns = `
+	id, car_id, category, file_name, content_type, size_bytes, checksum, blob_key,
+	uploaded_by, created_at`
+
+type Store struct {
+	db *driver.DB
+}
+
+func New(db *driver.DB) *Store {
+	return &Store{db: db}
+}
+
+// LockCar locks the car's row for the rest of the transaction carried by
+// ctx, so changes to one car's documents are made one at a time.
+func (s *Store) LockCar(ctx context.Context, carID uuid.UUID) error {
+	var id uuid.UUID
+	err := s.db.Conn(ctx).QueryRowContext(ctx, "SELECT id FROM cars WHERE id = $1 FOR NO KEY UPDATE", carID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrCarNotFound
+	}
+	return err
+}
+
+// LockChecksum takes a transaction-scoped advisory lock on the checksum.
+// There is no row to lock while a blob has no documents yet.
+func (s *Store) LockChecksum(ctx context.Context, checksum string) error {
+	_, err := s.db.Conn(ctx).ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('car_documents:' || $1))", checksum)
+	return err
+}
+
+func (s *Store) CreateDocument(ctx context.Context, doc models.CarDocument) (models.CarDocument, error) {
+	tracer := otel.Tracer("DocumentStore")
+
+	ctx, span := tracer.Start(ctx, "CreateDocument-Store")
+
+	defer span.End()
+
+	query := `
+		INSERT INTO car_documents (id, car_id, category, file_name, content_type, size_bytes, checksum, blob_key, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at`
+
+	err := s.db.Conn(ctx).QueryRowContext(ctx, query, doc.ID, doc.CarID, doc.Category, doc.FileName, doc.ContentType,
+		doc.Size, doc.Checksum, doc.BlobKey, doc.UploadedBy).Scan(&doc.CreatedAt)
+	if err != nil {
+		span.RecordError(err)
+		return models.CarDocument{}, err
+	}
+
+	return doc, nil
+}
+
+func (s *Store) GetDocument(ctx context.Context, carID, id uuid.UUID) (models.CarDocument, error) {
+	tracer := otel.Tracer("DocumentStore")
+
+	ctx, span := tracer.Start(ctx, "GetDocument-Store")
+
+	defer span.End()
+
+	doc, err := scanDocument(s.db.Reader(ctx).QueryRowContext(ctx,
+		`SELECT `+documentColumns+` FROM car_documents WHERE id = $1 AND car_id = $2`, id, carID))
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return doc, models.ErrDocumentNotFound
+		}
+		return doc, err
+	}
+
+	return doc, nil
+}
+
+func (s *Store) FindByChecksum(ctx context.Context, carID uuid.UUID, checksum string) (models.CarDocument, error) {
+	tracer := otel.Tracer("DocumentStore")
+
+	ctx, span := tracer.Start(ctx, "FindByChecksum-Store")
+
+	defer span.End()
+
+	doc, err := scanDocument(s.db.Conn(ctx).QueryRowContext(ctx,
+		`SELECT `+documentColumns+` FROM car_documents WHERE car_id = $1 AND checksum = $2`, carID, checksum))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return doc, models.ErrDocumentNotFound
+		}
+		span.RecordError(err)
+		return doc, err
+	}
+
+	return doc, nil
+}
+
+func (s *Store) ListDocuments(ctx context.Context, carID uuid.UUID, category string) ([]models.CarDocument, error) {
+	tracer := otel.Tracer("DocumentStore")
+
+	ctx, span := tracer.Start(ctx, "ListDocuments-Store")
+
+	defer span.End()
+
+	docs, err := s.query(ctx, s.db.Reader(ctx), `
+		SELECT `+documentColumns+` FROM car_documents
+		WHERE car_id = $1 AND ($2 = '' OR category = $2)
+		ORDER BY category, created_at`, carID, category)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return docs, err
+}
+
+func (s *Store) CountDocuments(ctx context.Context, carID uuid.UUID) (int, error) {
+	var count int
+	err := s.db.Conn(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM car_documents WHERE car_id = $1", carID).Scan(&count)
+	return count, err
+}
+
+func (s *Store) CountByChecksum(ctx context.Context, checksum string) (int, error) {
+	var count int
+	err := s.db.Conn(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM car_documents WHERE checksum = $1", checksum).Scan(&count)
+	return count, err
+}
+
+func (s *Store) DeleteDocument(ctx context.Context, carID, id uuid.UUID) (models.CarDocument, error) {
+	tracer := otel.Tracer("DocumentStore")
+
+	ctx, span := tracer.Start(ctx, "DeleteDocument-Store")
+
+	defer span.End()
+
+	doc, err := scanDocument(s.db.Conn(ctx).QueryRowContext(ctx,
+		`DELETE FROM car_documents WHERE id = $1 AND car_id = $2 RETURNING `+documentColumns, id, carID))
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return doc, models.ErrDocumentNotFound
+		}
+		return doc, err
+	}
+
+	return doc, nil
+}
+
+// DeleteCarDocuments removes all of a car's documents and returns them, so
+// their blobs can be released too.
+func (s *Store) DeleteCarDocuments(ctx context.Context, carID uuid.UUID) ([]models.CarDocument, error) {
+	tracer := otel.Tracer("DocumentStore")
+
+	ctx, span := tracer.Start(ctx, "DeleteCarDocuments-Store")
+
+	defer span.End()
+
+	docs, err := s.query(ctx, s.db.Conn(ctx),
+		`DELETE FROM car_documents WHERE car_id = $1 RETURNING `+documentColumns, carID)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return docs, err
+}
+
+func (s *Store) query(ctx context.Context, q driver.Querier, query string, args ...interface{}) ([]models.CarDocument, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := []models.CarDocument{}
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDocument(row scanner) (models.CarDocument, error) {
+	var doc models.CarDocument
+
+	err := row.Scan(&doc.ID, &doc.CarID, &doc.Category, &doc.FileName, &doc.ContentType, &doc.Size,
+		&doc.Checksum, &doc.BlobKey, &doc.UploadedBy, &doc.CreatedAt)
+
+	return doc, err
+}
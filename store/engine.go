@@ -0,0 +1,122 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/michgboxy2/carzone/models"
+)
+
+// engineColumns are the columns of a models.Engine, engine_id first and the
+// specs after it.
+var engineColumns = []string{
+	"engine_id", "powertrain", "displacement", "no_of_cylinders", "car_range",
+	"motor_power_kw", "motor_torque_nm",
+	"battery_kwh", "charging_standards", "max_ac_charge_kw", "max_dc_charge_kw",
+	"combined_power_kw", "combined_torque_nm",
+}
+
+// EngineColumns lists the columns EngineRow scans, each prefixed with alias
+// and a dot unless alias is empty.
+func EngineColumns(alias string) string {
+	if alias == "" {
+		return strings.Join(engineColumns, ", ")
+	}
+	return alias + "." + strings.Join(engineColumns, ", "+alias+".")
+}
+
+// EngineSpecColumns lists the columns EngineSpecValues fills, in order.
+func EngineSpecColumns() string {
+	return strings.Join(engineColumns[1:], ", ")
+}
+
+// EngineSpecValues returns the values of the spec columns for engineReq.
+// The columns of a missing motor, battery or combined rating are NULL.
+func EngineSpecValues(engineReq *models.EngineRequest) []interface{} {
+	var (
+		motorPower, motorTorque       sql.NullInt64
+		battery, acCharge, dcCharge   sql.NullFloat64
+		standards                     []string
+		combinedPower, combinedTorque sql.NullInt64
+	)
+
+	if m := engineReq.Motor; m != nil {
+		motorPower = sql.NullInt64{Int64: m.PowerKW, Valid: true}
+		motorTorque = sql.NullInt64{Int64: m.TorqueNm, Valid: true}
+	}
+
+	if b := engineReq.Battery; b != nil {
+		battery = sql.NullFloat64{Float64: b.CapacityKWh, Valid: true}
+		acCharge = sql.NullFloat64{Float64: b.MaxACChargeKW, Valid: b.MaxACChargeKW > 0}
+		dcCharge = sql.NullFloat64{Float64: b.MaxDCChargeKW, Valid: b.MaxDCChargeKW > 0}
+		if len(b.ChargingStandards) > 0 {
+			standards = b.ChargingStandards
+		}
+	}
+
+	if c := engineReq.Combined; c != nil {
+		combinedPower = sql.NullInt64{Int64: c.PowerKW, Valid: true}
+		combinedTorque = sql.NullInt64{Int64: c.TorqueNm, Valid: true}
+	}
+
+	powertrain := engineReq.Powertrain
+	if powertrain == "" {
+		powertrain = models.PowertrainCombustion
+	}
+
+	return []interface{}{
+		powertrain, engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange,
+		motorPower, motorTorque,
+		battery, pq.Array(standards), acCharge, dcCharge,
+		combinedPower, combinedTorque,
+	}
+}
+
+// EngineRow receives the columns listed by EngineColumns.
+type EngineRow struct {
+	engine                        models.Engine
+	powertrain                    sql.NullString
+	motorPower, motorTorque       sql.NullInt64
+	battery, acCharge, dcCharge   sql.NullFloat64
+	standards                     []string
+	combinedPower, combinedTorque sql.NullInt64
+}
+
+// Dest returns the scan destinations for the columns, in order.
+func (r *EngineRow) Dest() []interface{} {
+	return []interface{}{
+		&r.engine.EngineID, &r.powertrain, &r.engine.Displacement, &r.engine.NoOfCylinders, &r.engine.CarRange,
+		&r.motorPower, &r.motorTorque,
+		&r.battery, pq.Array(&r.standards), &r.acCharge, &r.dcCharge,
+		&r.combinedPower, &r.combinedTorque,
+	}
+}
+
+func (r *EngineRow) Engine() models.Engine {
+	engine := r.engine
+
+	engine.Powertrain = models.PowertrainCombustion
+	if r.powertrain.Valid {
+		engine.Powertrain = r.powertrain.String
+	}
+
+	if r.motorPower.Valid {
+		engine.Motor = &models.Motor{PowerKW: r.motorPower.Int64, TorqueNm: r.motorTorque.Int64}
+	}
+
+	if r.battery.Valid {
+		engine.Battery = &models.Battery{
+			CapacityKWh:       r.battery.Float64,
+			ChargingStandards: r.standards,
+			MaxACChargeKW:     r.acCharge.Float64,
+			MaxDCChargeKW:     r.dcCharge.Float64,
+		}
+	}
+
+	if r.combinedPower.Valid {
+		engine.Combined = &models.CombinedRating{PowerKW: r.combinedPower.Int64, TorqueNm: r.combinedTorque.Int64}
+	}
+
+	return engine
+}
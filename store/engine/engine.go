@@ -5,17 +5,20 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/driver"
 	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
 	"go.opentelemetry.io/otel"
 )
 
 type EngineStore struct {
-	db *sql.DB
+	db *driver.DB
 }
 
-func New(db *sql.DB) *EngineStore {
+func New(db *driver.DB) *EngineStore {
 	return &EngineStore{db: db}
 }
 
@@ -26,61 +29,29 @@ func (s EngineStore) GetEngineById(ctx context.Context, id string) (models.Engin
 
 	defer span.End()
 
-	var engine models.Engine
-
-	// Begin Transaction
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		span.RecordError(err)
-		return models.Engine{}, err
-	}
-
-	defer func() {
-		if err != nil {
-			span.RecordError(err)
-			if rbErr := tx.Rollback(); rbErr != nil {
-				fmt.Printf("Transaction rollback error: %v\n", rbErr)
-			}
-		} else {
-			if cmErr := tx.Commit(); cmErr != nil {
-				span.RecordError(err)
-				fmt.Printf("Transaction commit error: %v\v", cmErr)
-			}
-		}
-	}()
+	var row store.EngineRow
 
 	// Prepare the SQL query to select the engine by ID
 	query := `
-		SELECT 
-			engine_id, displacement, no_of_cylinders, car_range 
-		FROM 
-			engine 
-		WHERE 
+		SELECT
+			` + store.EngineColumns("") + `
+		FROM
+			engines
+		WHERE
 			engine_id = $1`
 
 	// Execute the query
-	err = tx.QueryRowContext(ctx, query, id).Scan(
-		&engine.EngineID,
-		&engine.Displacement,
-		&engine.NoOfCylinders,
-		&engine.CarRange,
-	)
+	err := s.db.Reader(ctx).QueryRowContext(ctx, query, id).Scan(row.Dest()...)
 
 	if err != nil {
 		span.RecordError(err)
 		if err == sql.ErrNoRows {
-			return models.Engine{}, errors.New("engine not found")
+			return models.Engine{}, models.ErrEngineNotFound
 		}
 		return models.Engine{}, err
 	}
 
-	// Commit the transaction (optional for read operations)
-	if err := tx.Commit(); err != nil {
-		span.RecordError(err)
-		return models.Engine{}, err
-	}
-
-	return engine, nil
+	return row.Engine(), nil
 }
 
 func (s EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
@@ -91,45 +62,23 @@ func (s EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineR
 	defer span.End()
 	var engine models.Engine
 
-	// Begin Transaction
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		span.RecordError(err)
-		return engine, err
-	}
-
-	defer func() {
-		if err != nil {
-			if rbErr := tx.Rollback(); rbErr != nil {
-				span.RecordError(err)
-				fmt.Printf("Transaction rollback error: %v\n", rbErr)
-			}
-		} else {
-			if cmErr := tx.Commit(); cmErr != nil {
-				span.RecordError(err)
-				fmt.Printf("Transaction commit error: %v\v", cmErr)
-			}
-		}
-	}()
-
 	engineId := uuid.New()
 
 	// Prepare the SQL query to insert a new engine
 	query := `
-		INSERT INTO engine (id, displacement, no_of_cylinders, car_range) 
-		VALUES ($1, $2, $3, $4)`
+		INSERT INTO engines (engine_id, ` + store.EngineSpecColumns() + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
 
 	// Execute the insert query
-	_, err = tx.ExecContext(ctx, query, engineId, engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange)
+	args := append([]interface{}{engineId}, store.EngineSpecValues(engineReq)...)
+	_, err := s.db.Conn(ctx).ExecContext(ctx, query, args...)
 	if err != nil {
 		span.RecordError(err)
 		return engine, err // Return error if the insertion fails
 	}
 
 	// Set the engine fields
-	engine.Displacement = engineReq.Displacement
-	engine.NoOfCylinders = engineReq.NoOfCylinders
-	engine.CarRange = engineReq.CarRange
+	engine = engineReq.Engine(engineId)
 
 	return engine, nil // Return the created engine
 }
@@ -143,40 +92,33 @@ func (s EngineStore) EngineUpdate(ctx context.Context, id uuid.UUID, engineReq *
 
 	var updatedEngine models.Engine
 
-	// Begin Transaction
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		span.RecordError(err)
-		return updatedEngine, err
-	}
-
-	defer func() {
-		if err != nil {
-			tx.Rollback() // Rollback on error
-			span.RecordError(err)
-			return
-		}
-		err = tx.Commit() // Commit if no error
-	}()
-
 	// Prepare the SQL query to update the engine
 	query := `
-		UPDATE engine 
-		SET displacement = $1, no_of_cylinders = $2, car_range = $3 
-		WHERE engine_id = $4`
+		UPDATE engines
+		SET (` + store.EngineSpecColumns() + `, updated_at) =
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, CURRENT_TIMESTAMP)
+		WHERE engine_id = $13`
 
 	// Execute the update query
-	_, err = tx.ExecContext(ctx, query, engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange, id)
+	args := append(store.EngineSpecValues(engineReq), id)
+	result, err := s.db.Conn(ctx).ExecContext(ctx, query, args...)
 	if err != nil {
 		span.RecordError(err)
 		return updatedEngine, err // Return error if the update fails
 	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		return updatedEngine, err
+	}
+
+	if rowsAffected == 0 {
+		return updatedEngine, models.ErrEngineNotFound
+	}
+
 	// Set the updated engine fields
-	updatedEngine.EngineID = id // Assuming id is the engine's ID
-	updatedEngine.Displacement = engineReq.Displacement
-	updatedEngine.NoOfCylinders = engineReq.NoOfCylinders
-	updatedEngine.CarRange = engineReq.CarRange
+	updatedEngine = engineReq.Engine(id)
 
 	return updatedEngine, nil
 }
@@ -190,45 +132,185 @@ func (s EngineStore) EngineDelete(ctx context.Context, id string) (models.Engine
 
 	var deletedEngine models.Engine
 
-	// Begin Transaction
-	tx, err := s.db.BeginTx(ctx, nil)
+	err := s.db.WithinTx(ctx, func(ctx context.Context) error {
+		conn := s.db.Conn(ctx)
+
+		// Select the engine before deletion, locking the row until we are done
+		var row store.EngineRow
+		selectQuery := `SELECT ` + store.EngineColumns("") + ` FROM engines WHERE engine_id = $1 FOR UPDATE`
+		err := conn.QueryRowContext(ctx, selectQuery, id).Scan(row.Dest()...)
+
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrEngineNotFound
+			}
+			return err
+		}
+		deletedEngine = row.Engine()
+
+		// Prepare the SQL query to delete the engine
+		deleteQuery := `DELETE FROM engines WHERE engine_id = $1`
+		_, err = conn.ExecContext(ctx, deleteQuery, id)
+		return err
+	})
+
 	if err != nil {
 		span.RecordError(err)
-		return deletedEngine, err
+		return models.Engine{}, err
 	}
 
-	defer func() {
-		if err != nil {
-			tx.Rollback() // Rollback on error
-			span.RecordError(err)
-			return
-		}
-		err = tx.Commit() // Commit if no error
-	}()
-
-	// Prepare the SQL query to select the engine before deletion
-	selectQuery := `SELECT engine_id, displacement, no_of_cylinders, car_range FROM engine WHERE engine_id = $1`
-	err = tx.QueryRowContext(ctx, selectQuery, id).Scan(
-		&deletedEngine.EngineID,
-		&deletedEngine.Displacement,
-		&deletedEngine.NoOfCylinders,
-		&deletedEngine.CarRange,
+	return deletedEngine, nil
+}
+
+// engineSortColumns maps the catalogue's sort keys to columns.
+var engineSortColumns = map[string]string{
+	models.EngineSortCreated:      "created_at",
+	models.EngineSortDisplacement: "displacement",
+	models.EngineSortCylinders:    "no_of_cylinders",
+	models.EngineSortRange:        "car_range",
+}
+
+func (s EngineStore) ListEngines(ctx context.Context, filter models.EngineFilter) ([]models.Engine, int, error) {
+	tracer := otel.Tracer("EngineStore")
+
+	ctx, span := tracer.Start(ctx, "ListEngines-Store")
+
+	defer span.End()
+
+	var (
+		conditions []string
+		args       []interface{}
 	)
 
+	where := func(condition string, value int64) {
+		if value > 0 {
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+		}
+	}
+
+	if filter.Powertrain != "" {
+		args = append(args, filter.Powertrain)
+		conditions = append(conditions, fmt.Sprintf("powertrain = $%d", len(args)))
+	}
+
+	where("displacement >= $%d", filter.MinDisplacement)
+	where("displacement <= $%d", filter.MaxDisplacement)
+	where("no_of_cylinders = $%d", filter.Cylinders)
+	where("car_range >= $%d", filter.MinRange)
+	where("car_range <= $%d", filter.MaxRange)
+
+	clause := ""
+	if len(conditions) > 0 {
+		clause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	reader := s.db.Reader(ctx)
+
+	var total int
+	if err := reader.QueryRowContext(ctx, "SELECT COUNT(*) FROM engines"+clause, args...).Scan(&total); err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+
+	key, desc := filter.SortKey()
+	order := engineSortColumns[key]
+	if desc {
+		order += " DESC"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			%s
+		FROM
+			engines%s
+		ORDER BY
+			%s, engine_id
+		LIMIT $%d OFFSET $%d`, store.EngineColumns(""), clause, order, len(args)+1, len(args)+2)
+
+	rows, err := reader.QueryContext(ctx, query, append(args, filter.Limit, filter.Offset)...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return deletedEngine, errors.New("engine not found")
+		span.RecordError(err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	engines := []models.Engine{}
+
+	for rows.Next() {
+		var row store.EngineRow
+		if err := rows.Scan(row.Dest()...); err != nil {
+			span.RecordError(err)
+			return nil, 0, err
 		}
-		return deletedEngine, err
+		engines = append(engines, row.Engine())
+	}
+
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, 0, err
 	}
 
-	// Prepare the SQL query to delete the engine
-	deleteQuery := `DELETE FROM engine WHERE engine_id = $1`
-	_, err = tx.ExecContext(ctx, deleteQuery, id)
+	return engines, total, nil
+}
+
+// LockSpecs serialises the creation of engines with the given specs until
+// the surrounding transaction ends.
+func (s EngineStore) LockSpecs(ctx context.Context, engineReq *models.EngineRequest) error {
+	tracer := otel.Tracer("EngineStore")
+
+	ctx, span := tracer.Start(ctx, "LockSpecs-Store")
+
+	defer span.End()
+
+	key := fmt.Sprintf("engines:%s:%d:%d:%d", engineReq.Powertrain, engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange)
+
+	_, err := s.db.Conn(ctx).ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", key)
 	if err != nil {
 		span.RecordError(err)
-		return deletedEngine, err // Return error if the deletion fails
 	}
 
-	return deletedEngine, nil
+	return err
+}
+
+// FindEngineBySpecs returns the oldest engine with exactly the given specs,
+// or models.ErrEngineNotFound.
+func (s EngineStore) FindEngineBySpecs(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+	tracer := otel.Tracer("EngineStore")
+
+	ctx, span := tracer.Start(ctx, "FindEngineBySpecs-Store")
+
+	defer span.End()
+
+	// Specs a powertrain does not have are NULL, so compare NULLs as equal.
+	columns := strings.Split(store.EngineSpecColumns(), ", ")
+	conditions := make([]string, len(columns))
+	for i, column := range columns {
+		conditions[i] = fmt.Sprintf("%s IS NOT DISTINCT FROM $%d", column, i+1)
+	}
+
+	query := `
+		SELECT
+			` + store.EngineColumns("") + `
+		FROM
+			engines
+		WHERE
+			` + strings.Join(conditions, " AND ") + `
+		ORDER BY
+			created_at, engine_id
+		LIMIT 1`
+
+	var row store.EngineRow
+
+	err := s.db.Conn(ctx).QueryRowContext(ctx, query, store.EngineSpecValues(engineReq)...).Scan(row.Dest()...)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Engine{}, models.ErrEngineNotFound
+		}
+		span.RecordError(err)
+		return models.Engine{}, err
+	}
+
+	return row.Engine(), nil
 }
@@ -0,0 +1,146 @@
+package favorite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+// Store keeps the cars users have favorited.
+type Store struct {
+	db *driver.DB
+}
+
+func New(db *driver.DB) *Store {
+	return &Store{db: db}
+}
+
+// LockOwner takes a transaction-scoped advisory lock on the owner, so their
+// favorites are counted and added one at a time.
+func (s *Store) LockOwner(ctx context.Context, owner string) error {
+	_, err := s.db.Conn(ctx).ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('favorites:' || $1))", owner)
+	return err
+}
+
+// AddFavorite favorites the car for owner, if it is not already. It
+// reports models.ErrCarNotFound for an unknown car.
+func (s *Store) AddFavorite(ctx context.Context, owner string, carID uuid.UUID) error {
+	tracer := otel.Tracer("FavoriteStore")
+
+	ctx, span := tracer.Start(ctx, "AddFavorite-Store")
+
+	defer span.End()
+
+	_, err := s.db.Conn(ctx).ExecContext(ctx,
+		"INSERT INTO favorites (owner, car_id) VALUES ($1, $2) ON CONFLICT (owner, car_id) DO NOTHING", owner, carID)
+	if err != nil {
+		span.RecordError(err)
+		if store.IsViolation(err, "23503") {
+			return models.ErrCarNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// RemoveFavorite reports models.ErrCarNotFound if owner has not favorited
+// the car.
+func (s *Store) RemoveFavorite(ctx context.Context, owner string, carID uuid.UUID) error {
+	tracer := otel.Tracer("FavoriteStore")
+
+	ctx, span := tracer.Start(ctx, "RemoveFavorite-Store")
+
+	defer span.End()
+
+	result, err := s.db.Conn(ctx).ExecContext(ctx, "DELETE FROM favorites WHERE owner = $1 AND car_id = $2", owner, carID)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if n == 0 {
+		return models.ErrCarNotFound
+	}
+
+	return nil
+}
+
+// ListFavorites returns owner's favorites, latest first, with only their
+// cars' IDs set.
+func (s *Store) ListFavorites(ctx context.Context, owner string) ([]models.Favorite, error) {
+	tracer := otel.Tracer("FavoriteStore")
+
+	ctx, span := tracer.Start(ctx, "ListFavorites-Store")
+
+	defer span.End()
+
+	rows, err := s.db.Reader(ctx).QueryContext(ctx,
+		"SELECT car_id, created_at FROM favorites WHERE owner = $1 ORDER BY created_at DESC, car_id", owner)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	favorites := []models.Favorite{}
+	for rows.Next() {
+		var favorite models.Favorite
+		if err := rows.Scan(&favorite.Car.ID, &favorite.FavoritedAt); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		favorites = append(favorites, favorite)
+	}
+
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return favorites, nil
+}
+
+// IsFavorite reports whether owner has favorited the car.
+func (s *Store) IsFavorite(ctx context.Context, owner string, carID uuid.UUID) (bool, error) {
+	tracer := otel.Tracer("FavoriteStore")
+
+	ctx, span := tracer.Start(ctx, "IsFavorite-Store")
+
+	defer span.End()
+
+	var exists bool
+	err := s.db.Conn(ctx).QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM favorites WHERE owner = $1 AND car_id = $2)", owner, carID).Scan(&exists)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return exists, err
+}
+
+func (s *Store) CountFavorites(ctx context.Context, owner string) (int, error) {
+	tracer := otel.Tracer("FavoriteStore")
+
+	ctx, span := tracer.Start(ctx, "CountFavorites-Store")
+
+	defer span.End()
+
+	var n int
+	err := s.db.Conn(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM favorites WHERE owner = $1", owner).Scan(&n)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return n, err
+}
@@ -0,0 +1,26 @@
+-- Cars from before the brand catalogue, or loaded around the API, only name
+-- their brand in free text. Spellings that differ in case and spacing alone
+-- are one brand, named after the most common of them, and those that are
+-- neither a brand nor an alias yet are added to the catalogue.
+INSERT INTO brands (id, name)
+SELECT uuid_generate_v4(), spelling
+FROM (
+    SELECT DISTINCT ON (lower(spelling)) spelling
+    FROM (
+        SELECT regexp_replace(btrim(brand), '\s+', ' ', 'g') AS spelling, COUNT(*) AS uses
+        FROM cars
+        WHERE brand_id IS NULL
+        GROUP BY 1
+    ) spellings
+    WHERE spelling <> ''
+    ORDER BY lower(spelling), uses DESC, spelling
+) folded
+WHERE NOT EXISTS (SELECT 1 FROM brand_names n WHERE n.key = lower(folded.spelling));
+
+UPDATE cars c
+SET brand_id = b.id,
+    brand = b.name
+FROM brand_names n
+JOIN brands b ON b.id = n.brand_id
+WHERE c.brand_id IS NULL
+    AND n.key = lower(regexp_replace(btrim(c.brand), '\s+', ' ', 'g'));
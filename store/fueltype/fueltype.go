@@ -0,0 +1,205 @@
+package fueltype
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+const columns = "code, name, synonyms, powertrains, deprecated, emission_factor, emission_unit, created_at, updated_at"
+
+// Store keeps the fuel type registry.
+type Store struct {
+	db *driver.DB
+}
+
+func New(db *driver.DB) *Store {
+	return &Store{db: db}
+}
+
+// LockRegistry serialises changes to the registry until the surrounding
+// transaction ends.
+func (s *Store) LockRegistry(ctx context.Context) error {
+	tracer := otel.Tracer("FuelTypeStore")
+
+	ctx, span := tracer.Start(ctx, "LockRegistry-Store")
+
+	defer span.End()
+
+	_, err := s.db.Conn(ctx).ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "fuel_types")
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return err
+}
+
+func (s *Store) ListFuelTypes(ctx context.Context) ([]models.FuelType, error) {
+	tracer := otel.Tracer("FuelTypeStore")
+
+	ctx, span := tracer.Start(ctx, "ListFuelTypes-Store")
+
+	defer span.End()
+
+	rows, err := s.db.Reader(ctx).QueryContext(ctx, "SELECT "+columns+" FROM fuel_types ORDER BY deprecated, name, code")
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	fuelTypes := []models.FuelType{}
+
+	for rows.Next() {
+		fuelType, err := scanFuelType(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		fuelTypes = append(fuelTypes, fuelType)
+	}
+
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return fuelTypes, nil
+}
+
+func (s *Store) GetFuelType(ctx context.Context, code string) (models.FuelType, error) {
+	tracer := otel.Tracer("FuelTypeStore")
+
+	ctx, span := tracer.Start(ctx, "GetFuelType-Store")
+
+	defer span.End()
+
+	fuelType, err := scanFuelType(s.db.Reader(ctx).QueryRowContext(ctx, "SELECT "+columns+" FROM fuel_types WHERE code = $1", code))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.FuelType{}, models.ErrFuelTypeNotFound
+		}
+		span.RecordError(err)
+		return models.FuelType{}, err
+	}
+
+	return fuelType, nil
+}
+
+func (s *Store) CreateFuelType(ctx context.Context, req models.FuelTypeRequest) (models.FuelType, error) {
+	tracer := otel.Tracer("FuelTypeStore")
+
+	ctx, span := tracer.Start(ctx, "CreateFuelType-Store")
+
+	defer span.End()
+
+	now := time.Now()
+	fuelType := req.FuelType()
+	fuelType.CreatedAt, fuelType.UpdatedAt = now, now
+
+	_, err := s.db.Conn(ctx).ExecContext(ctx, `
+		INSERT INTO fuel_types (`+columns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		fuelType.Code, fuelType.Name, pq.Array(fuelType.Synonyms), pq.Array(fuelType.Powertrains),
+		fuelType.Deprecated, fuelType.EmissionFactor, fuelType.EmissionUnit, now, now)
+
+	if err != nil {
+		span.RecordError(err)
+		if store.IsViolation(err, "23505") {
+			return models.FuelType{}, models.ErrFuelTypeExists
+		}
+		return models.FuelType{}, err
+	}
+
+	return fuelType, nil
+}
+
+// UpdateFuelType replaces everything but the code of a fuel type.
+func (s *Store) UpdateFuelType(ctx context.Context, code string, req models.FuelTypeRequest) (models.FuelType, error) {
+	tracer := otel.Tracer("FuelTypeStore")
+
+	ctx, span := tracer.Start(ctx, "UpdateFuelType-Store")
+
+	defer span.End()
+
+	fuelType, err := scanFuelType(s.db.Conn(ctx).QueryRowContext(ctx, `
+		UPDATE fuel_types
+		SET (name, synonyms, powertrains, deprecated, emission_factor, emission_unit, updated_at) =
+			($2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)
+		WHERE code = $1
+		RETURNING `+columns,
+		code, req.Name, pq.Array(req.Synonyms), pq.Array(req.Powertrains), req.Deprecated, req.EmissionFactor, req.EmissionUnit))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.FuelType{}, models.ErrFuelTypeNotFound
+		}
+		span.RecordError(err)
+		return models.FuelType{}, err
+	}
+
+	return fuelType, nil
+}
+
+// DeleteFuelType reports models.ErrFuelTypeInUse while any car has the
+// fuel type.
+func (s *Store) DeleteFuelType(ctx context.Context, code string) error {
+	tracer := otel.Tracer("FuelTypeStore")
+
+	ctx, span := tracer.Start(ctx, "DeleteFuelType-Store")
+
+	defer span.End()
+
+	conn := s.db.Conn(ctx)
+
+	var inUse bool
+	if err := conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM cars WHERE fuel_type = $1)", code).Scan(&inUse); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if inUse {
+		return models.ErrFuelTypeInUse
+	}
+
+	result, err := conn.ExecContext(ctx, "DELETE FROM fuel_types WHERE code = $1", code)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if n == 0 {
+		return models.ErrFuelTypeNotFound
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanFuelType(row scanner) (models.FuelType, error) {
+	var f models.FuelType
+
+	err := row.Scan(&f.Code, &f.Name, pq.Array(&f.Synonyms), pq.Array(&f.Powertrains),
+		&f.Deprecated, &f.EmissionFactor, &f.EmissionUnit, &f.CreatedAt, &f.UpdatedAt)
+	if f.Synonyms == nil {
+		f.Synonyms = []string{}
+	}
+
+	return f, err
+}
@@ -0,0 +1,253 @@
+package image
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"go.opentelemetry.io/otel"
+)
+
+const imageColumns = `
+	id, car_id, position, is_cover, content_type, width, height, size_bytes,
+	original_key, thumbnail_key, created_at`
+
+type Store struct {
+	db *driver.DB
+}
+
+func New(db *driver.DB) *Store {
+	return &Store{db: db}
+}
+
+// LockCar locks the car's row for the rest of the transaction carried by
+// ctx, so changes to one car's images are made one at a time.
+func (s *Store) LockCar(ctx context.Context, carID uuid.UUID) error {
+	var id uuid.UUID
+	err := s.db.Conn(ctx).QueryRowContext(ctx, "SELECT id FROM cars WHERE id = $1 FOR NO KEY UPDATE", carID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrCarNotFound
+	}
+	return err
+}
+
+// CreateImage adds the image after the car's existing ones. The first image
+// of a car becomes its cover.
+func (s *Store) CreateImage(ctx context.Context, img models.CarImage) (models.CarImage, error) {
+	tracer := otel.Tracer("ImageStore")
+
+	ctx, span := tracer.Start(ctx, "CreateImage-Store")
+
+	defer span.End()
+
+	query := `
+		INSERT INTO car_images (id, car_id, position, is_cover, content_type, width, height, size_bytes, original_key, thumbnail_key)
+		SELECT $1, $2, COALESCE(MAX(position) + 1, 0), COUNT(*) = 0, $3, $4, $5, $6, $7, $8
+		FROM car_images WHERE car_id = $2
+		RETURNING position, is_cover, created_at`
+
+	err := s.db.Conn(ctx).QueryRowContext(ctx, query, img.ID, img.CarID, img.ContentType, img.Width, img.Height,
+		img.Size, img.OriginalKey, img.ThumbnailKey).Scan(&img.Position, &img.IsCover, &img.CreatedAt)
+	if err != nil {
+		span.RecordError(err)
+		return models.CarImage{}, err
+	}
+
+	return img, nil
+}
+
+func (s *Store) GetImage(ctx context.Context, id uuid.UUID) (models.CarImage, error) {
+	tracer := otel.Tracer("ImageStore")
+
+	ctx, span := tracer.Start(ctx, "GetImage-Store")
+
+	defer span.End()
+
+	img, err := scanImage(s.db.Reader(ctx).QueryRowContext(ctx, `SELECT `+imageColumns+` FROM car_images WHERE id = $1`, id))
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return img, models.ErrImageNotFound
+		}
+		return img, err
+	}
+
+	return img, nil
+}
+
+func (s *Store) ListImages(ctx context.Context, carID uuid.UUID) ([]models.CarImage, error) {
+	tracer := otel.Tracer("ImageStore")
+
+	ctx, span := tracer.Start(ctx, "ListImages-Store")
+
+	defer span.End()
+
+	images, err := s.query(ctx, s.db.Reader(ctx),
+		`SELECT `+imageColumns+` FROM car_images WHERE car_id = $1 ORDER BY position, created_at`, carID)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return images, err
+}
+
+// DeleteImage removes one image of a car and closes the gap it leaves in
+// the order. If it was the cover, the new first image takes over.
+func (s *Store) DeleteImage(ctx context.Context, carID, id uuid.UUID) (models.CarImage, error) {
+	tracer := otel.Tracer("ImageStore")
+
+	ctx, span := tracer.Start(ctx, "DeleteImage-Store")
+
+	defer span.End()
+
+	var deleted models.CarImage
+
+	err := s.db.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		deleted, err = scanImage(s.db.Conn(ctx).QueryRowContext(ctx,
+			`DELETE FROM car_images WHERE id = $1 AND car_id = $2 RETURNING `+imageColumns, id, carID))
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrImageNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = s.db.Conn(ctx).ExecContext(ctx,
+			"UPDATE car_images SET position = position - 1 WHERE car_id = $1 AND position > $2", carID, deleted.Position)
+		if err != nil {
+			return err
+		}
+
+		if !deleted.IsCover {
+			return nil
+		}
+
+		_, err = s.db.Conn(ctx).ExecContext(ctx, `
+			UPDATE car_images SET is_cover = TRUE
+			WHERE id = (SELECT id FROM car_images WHERE car_id = $1 ORDER BY position LIMIT 1)`, carID)
+		return err
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return models.CarImage{}, err
+	}
+
+	return deleted, nil
+}
+
+// DeleteCarImages removes all of a car's images and returns them, so their
+// blobs can be removed too.
+func (s *Store) DeleteCarImages(ctx context.Context, carID uuid.UUID) ([]models.CarImage, error) {
+	tracer := otel.Tracer("ImageStore")
+
+	ctx, span := tracer.Start(ctx, "DeleteCarImages-Store")
+
+	defer span.End()
+
+	images, err := s.query(ctx, s.db.Conn(ctx),
+		`DELETE FROM car_images WHERE car_id = $1 RETURNING `+imageColumns, carID)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return images, err
+}
+
+// SetPositions orders the car's images as listed in ids, which must hold
+// each of them exactly once.
+func (s *Store) SetPositions(ctx context.Context, carID uuid.UUID, ids []uuid.UUID) error {
+	tracer := otel.Tracer("ImageStore")
+
+	ctx, span := tracer.Start(ctx, "SetPositions-Store")
+
+	defer span.End()
+
+	for position, id := range ids {
+		result, err := s.db.Conn(ctx).ExecContext(ctx,
+			"UPDATE car_images SET position = $1 WHERE id = $2 AND car_id = $3", position, id, carID)
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return models.ErrImageOrderMismatch
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) SetCover(ctx context.Context, carID, id uuid.UUID) error {
+	tracer := otel.Tracer("ImageStore")
+
+	ctx, span := tracer.Start(ctx, "SetCover-Store")
+
+	defer span.End()
+
+	// Both rows change in one statement, so the one-cover-per-car index
+	// never sees two covers.
+	result, err := s.db.Conn(ctx).ExecContext(ctx, `
+		UPDATE car_images SET is_cover = (id = $2)
+		WHERE car_id = $1 AND (is_cover OR id = $2)
+			AND EXISTS (SELECT 1 FROM car_images WHERE id = $2 AND car_id = $1)`, carID, id)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrImageNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) query(ctx context.Context, q driver.Querier, query string, args ...interface{}) ([]models.CarImage, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []models.CarImage{}
+	for rows.Next() {
+		img, err := scanImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+
+	return images, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanImage(row scanner) (models.CarImage, error) {
+	var img models.CarImage
+
+	err := row.Scan(&img.ID, &img.CarID, &img.Position, &img.IsCover, &img.ContentType, &img.Width, &img.Height,
+		&img.Size, &img.OriginalKey, &img.ThumbnailKey, &img.CreatedAt)
+
+	return img, err
+}
@@ -2,17 +2,28 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/money"
 )
 
 type CarStoreInterface interface {
+	// GetCarById reports models.ErrCarNotFound if there is no such car.
 	GetCarById(ctx context.Context, id string) (models.Car, error)
-	GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error)
+	GetCarByBrand(ctx context.Context, brand string, isEngine bool, filter models.CarFilter) ([]models.Car, error)
+	GetCarsByEngine(ctx context.Context, engineID uuid.UUID) ([]models.Car, error)
 	CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error)
 	UpdateCar(ctx context.Context, id uuid.UUID, carReq *models.CarRequest) (models.Car, error)
 	DeleteCar(ctx context.Context, id string) (models.Car, error)
+	// LockCar serialises changes to a car within a transaction. It reports
+	// models.ErrCarNotFound if there is no such car.
+	LockCar(ctx context.Context, id uuid.UUID) error
+	// RenameBrand and RenameModel write a renamed brand or model onto its
+	// cars and return the IDs of the cars changed.
+	RenameBrand(ctx context.Context, brandID uuid.UUID, name string) ([]uuid.UUID, error)
+	RenameModel(ctx context.Context, modelID uuid.UUID, name string) ([]uuid.UUID, error)
 }
 
 type EngineStoreInterface interface {
@@ -20,4 +31,212 @@ type EngineStoreInterface interface {
 	CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error)
 	EngineUpdate(ctx context.Context, id uuid.UUID, engineReq *models.EngineRequest) (models.Engine, error)
 	EngineDelete(ctx context.Context, id string) (models.Engine, error)
+	// ListEngines returns a page of engines and the number matching filter.
+	ListEngines(ctx context.Context, filter models.EngineFilter) ([]models.Engine, int, error)
+	// LockSpecs serialises the creation of engines with the same specs
+	// within a transaction.
+	LockSpecs(ctx context.Context, engineReq *models.EngineRequest) error
+	FindEngineBySpecs(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error)
+}
+
+// OutboxStoreInterface is the transactional outbox. Append is called inside
+// the transaction that makes the change; the rest is used by the relay.
+type OutboxStoreInterface interface {
+	Append(ctx context.Context, events ...models.Event) error
+	TryLock(ctx context.Context) (bool, error)
+	Pending(ctx context.Context, limit int) ([]models.Event, error)
+	// Claim holds events back from Pending until a deadline while they are
+	// published outside a transaction; Release lets go of those that were
+	// not.
+	Claim(ctx context.Context, sequences []int64, until time.Time) error
+	Release(ctx context.Context, sequences []int64) error
+	MarkPublished(ctx context.Context, sequences []int64) error
+	// MarkFailed records a failed publish. A nil retryAt parks the event.
+	MarkFailed(ctx context.Context, sequence int64, reason string, retryAt *time.Time) error
+	DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error)
+
+	// Since, BySequence and LastSequence read events regardless of whether
+	// they have been published, for live streams and their replay.
+	Since(ctx context.Context, after int64, limit int) ([]models.Event, error)
+	BySequence(ctx context.Context, sequences []int64) ([]models.Event, error)
+	LastSequence(ctx context.Context) (int64, error)
+}
+
+type WebhookStoreInterface interface {
+	CreateSubscription(ctx context.Context, sub models.WebhookSubscription) (models.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id uuid.UUID) (models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+
+	// EnqueueDeliveries creates a pending delivery of event for every active
+	// subscription to its type. Enqueueing the same event twice is a no-op.
+	EnqueueDeliveries(ctx context.Context, event models.Event) (int64, error)
+	// ClaimDue returns up to limit due deliveries and pushes their next
+	// attempt back by lease, so no other dispatcher picks them up meanwhile.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.DueDelivery, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID, statusCode int) error
+	// MarkFailed records a failed attempt. A nil next moves the delivery to
+	// the dead-letter state.
+	MarkFailed(ctx context.Context, id uuid.UUID, statusCode *int, reason string, next *time.Time) error
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, subscriptionID, deliveryID uuid.UUID) (models.WebhookDelivery, error)
+}
+
+type ImageStoreInterface interface {
+	// LockCar serialises changes to a car's images within a transaction and
+	// reports models.ErrCarNotFound for an unknown car.
+	LockCar(ctx context.Context, carID uuid.UUID) error
+	CreateImage(ctx context.Context, img models.CarImage) (models.CarImage, error)
+	GetImage(ctx context.Context, id uuid.UUID) (models.CarImage, error)
+	ListImages(ctx context.Context, carID uuid.UUID) ([]models.CarImage, error)
+	DeleteImage(ctx context.Context, carID, id uuid.UUID) (models.CarImage, error)
+	DeleteCarImages(ctx context.Context, carID uuid.UUID) ([]models.CarImage, error)
+	SetPositions(ctx context.Context, carID uuid.UUID, ids []uuid.UUID) error
+	SetCover(ctx context.Context, carID, id uuid.UUID) error
+}
+
+type DocumentStoreInterface interface {
+	// LockCar serialises changes to a car's documents within a transaction
+	// and reports models.ErrCarNotFound for an unknown car.
+	LockCar(ctx context.Context, carID uuid.UUID) error
+	// LockChecksum serialises, within a transaction, the writing and removal
+	// of the blob shared by every document with that checksum.
+	LockChecksum(ctx context.Context, checksum string) error
+	CreateDocument(ctx context.Context, doc models.CarDocument) (models.CarDocument, error)
+	GetDocument(ctx context.Context, carID, id uuid.UUID) (models.CarDocument, error)
+	// FindByChecksum returns the car's document with that checksum, or
+	// models.ErrDocumentNotFound.
+	FindByChecksum(ctx context.Context, carID uuid.UUID, checksum string) (models.CarDocument, error)
+	// ListDocuments returns the car's documents, only those of category
+	// unless it is empty.
+	ListDocuments(ctx context.Context, carID uuid.UUID, category string) ([]models.CarDocument, error)
+	CountDocuments(ctx context.Context, carID uuid.UUID) (int, error)
+	// CountByChecksum counts the documents of any car sharing the checksum.
+	CountByChecksum(ctx context.Context, checksum string) (int, error)
+	DeleteDocument(ctx context.Context, carID, id uuid.UUID) (models.CarDocument, error)
+	DeleteCarDocuments(ctx context.Context, carID uuid.UUID) ([]models.CarDocument, error)
+}
+
+type ExchangeRateStoreInterface interface {
+	// GetRates returns the rates against base; they are empty if none have
+	// been loaded.
+	GetRates(ctx context.Context, base string) (money.Rates, error)
+	// ReplaceRates swaps all rates against rates.Base for the given ones.
+	ReplaceRates(ctx context.Context, rates money.Rates) (money.Rates, error)
+}
+
+// Transactor runs fn in a database transaction. Store calls made with the
+// context passed to fn share that transaction.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+	// Savepoint runs fn within the current transaction such that an error
+	// from fn rolls back only what fn did.
+	Savepoint(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// ReferenceStoreInterface keeps the values of each of models.ReferenceKinds.
+type ReferenceStoreInterface interface {
+	ListValues(ctx context.Context, kind string) ([]models.ReferenceValue, error)
+	GetValue(ctx context.Context, kind, code string) (models.ReferenceValue, error)
+	CreateValue(ctx context.Context, kind string, req models.ReferenceValueRequest) (models.ReferenceValue, error)
+	UpdateValue(ctx context.Context, kind, code, name string) (models.ReferenceValue, error)
+	// DeleteValue reports models.ErrReferenceInUse for a value cars use.
+	DeleteValue(ctx context.Context, kind, code string) error
+}
+
+// CatalogStoreInterface keeps the brand and model catalogue. The Find
+// methods match a name or alias whatever its case.
+type CatalogStoreInterface interface {
+	// LockCatalog serialises changes to the catalogue within a transaction.
+	LockCatalog(ctx context.Context) error
+	ListBrands(ctx context.Context) ([]models.Brand, error)
+	GetBrand(ctx context.Context, id uuid.UUID) (models.Brand, error)
+	FindBrand(ctx context.Context, name string) (models.Brand, error)
+	CreateBrand(ctx context.Context, req models.CatalogRequest) (models.Brand, error)
+	UpdateBrand(ctx context.Context, id uuid.UUID, req models.CatalogRequest) (models.Brand, error)
+	// DeleteBrand reports models.ErrBrandInUse for a brand cars or saved
+	// searches use.
+	DeleteBrand(ctx context.Context, id uuid.UUID) error
+	ListModels(ctx context.Context, brandID uuid.UUID) ([]models.CarModel, error)
+	FindModel(ctx context.Context, brandID uuid.UUID, name string) (models.CarModel, error)
+	CreateModel(ctx context.Context, brandID uuid.UUID, req models.CatalogRequest) (models.CarModel, error)
+	UpdateModel(ctx context.Context, brandID, id uuid.UUID, req models.CatalogRequest) (models.CarModel, error)
+	// DeleteModel reports models.ErrModelInUse for a model cars use.
+	DeleteModel(ctx context.Context, brandID, id uuid.UUID) error
+}
+
+// PriceStoreInterface keeps the price history of cars.
+type PriceStoreInterface interface {
+	RecordPrice(ctx context.Context, carID uuid.UUID, price money.Money, at time.Time) error
+	// ListPrices returns the car's history, oldest first.
+	ListPrices(ctx context.Context, carID uuid.UUID) ([]models.PricePoint, error)
+}
+
+// FuelTypeStoreInterface keeps the fuel type registry.
+type FuelTypeStoreInterface interface {
+	// LockRegistry serialises changes to the registry within a transaction.
+	LockRegistry(ctx context.Context) error
+	ListFuelTypes(ctx context.Context) ([]models.FuelType, error)
+	GetFuelType(ctx context.Context, code string) (models.FuelType, error)
+	CreateFuelType(ctx context.Context, req models.FuelTypeRequest) (models.FuelType, error)
+	UpdateFuelType(ctx context.Context, code string, req models.FuelTypeRequest) (models.FuelType, error)
+	// DeleteFuelType reports models.ErrFuelTypeInUse for a fuel type cars
+	// have.
+	DeleteFuelType(ctx context.Context, code string) error
+}
+
+// SearchStoreInterface keeps saved searches, the cars they match and the
+// alerts waiting to be sent. Users only reach their own searches.
+type SearchStoreInterface interface {
+	CreateSearch(ctx context.Context, search models.SavedSearch) (models.SavedSearch, error)
+	GetSearch(ctx context.Context, owner string, id uuid.UUID) (models.SavedSearch, error)
+	ListSearches(ctx context.Context, owner string) ([]models.SavedSearch, error)
+	CountSearches(ctx context.Context, owner string) (int, error)
+	// LockOwner serialises, within a transaction, the creation of an
+	// owner's searches so their number stays within the limit.
+	LockOwner(ctx context.Context, owner string) error
+	UpdateSearch(ctx context.Context, search models.SavedSearch) (models.SavedSearch, error)
+	DeleteSearch(ctx context.Context, owner string, id uuid.UUID) error
+	ListAlerts(ctx context.Context, searchID uuid.UUID, limit int) ([]models.SearchAlert, error)
+
+	// MatchingSearches returns the searches car's brand and fuel type
+	// match; their years and prices are left to the caller.
+	MatchingSearches(ctx context.Context, car models.Car) ([]models.SavedSearch, error)
+	// DeleteStaleMatches forgets the car's matches with searches its brand
+	// and fuel type no longer match.
+	DeleteStaleMatches(ctx context.Context, car models.Car) error
+	// GetMatch returns the price a search last saw a car at, or nil if the
+	// car does not match it.
+	GetMatch(ctx context.Context, searchID, carID uuid.UUID) (*money.Money, error)
+	SaveMatch(ctx context.Context, searchID, carID uuid.UUID, price money.Money) error
+	DeleteMatch(ctx context.Context, searchID, carID uuid.UUID) error
+	AddAlert(ctx context.Context, alert models.SearchAlert) error
+	// ClaimDueDigests returns up to limit searches whose digest is due with
+	// their waiting alerts, and pushes their next digest back by lease so no
+	// other digester picks them up meanwhile.
+	ClaimDueDigests(ctx context.Context, limit int, lease time.Duration) ([]models.SearchDigest, error)
+	MarkDigestSent(ctx context.Context, searchID uuid.UUID, lastAlertID int64, next time.Time) error
+	PostponeDigest(ctx context.Context, searchID uuid.UUID, next time.Time) error
+}
+
+// FavoriteStoreInterface keeps the cars users have favorited.
+type FavoriteStoreInterface interface {
+	// AddFavorite is a no-op for a car owner has already favorited.
+	AddFavorite(ctx context.Context, owner string, carID uuid.UUID) error
+	RemoveFavorite(ctx context.Context, owner string, carID uuid.UUID) error
+	// ListFavorites returns owner's favorites, latest first, with only
+	// their cars' IDs set.
+	ListFavorites(ctx context.Context, owner string) ([]models.Favorite, error)
+	IsFavorite(ctx context.Context, owner string, carID uuid.UUID) (bool, error)
+	CountFavorites(ctx context.Context, owner string) (int, error)
+	// LockOwner serialises, within a transaction, the adding of an owner's
+	// favorites so their number stays within the limit.
+	LockOwner(ctx context.Context, owner string) error
+}
+
+// SimilarStoreInterface ranks cars by how alike they are.
+type SimilarStoreInterface interface {
+	// RankSimilar returns up to limit cars closest to carID, nearest first,
+	// leaving carID itself out. It returns nothing for an unknown car.
+	RankSimilar(ctx context.Context, carID uuid.UUID, similarity models.Similarity, limit int) ([]models.RankedCar, error)
 }
@@ -0,0 +1,223 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"go.opentelemetry.io/otel"
+)
+
+// Channel is the Postgres NOTIFY channel announcing new events. The payload
+// is the event's sequence. Notifications are sent when the transaction that
+// wrote the event commits, and never if it rolls back.
+const Channel = "carzone_events"
+
+// relayLockKey is the advisory lock held by whichever instance is relaying,
+// so events are published by one relay at a time and stay in order.
+const relayLockKey = 0x6361727a6f6e65 // "carzone"
+
+const eventColumns = `id, event_id, event_type, aggregate_type, aggregate_id, payload, occurred_at, attempts`
+
+type Store struct {
+	db *driver.DB
+}
+
+func New(db *driver.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) Append(ctx context.Context, events ...models.Event) error {
+	tracer := otel.Tracer("OutboxStore")
+
+	ctx, span := tracer.Start(ctx, "Append-Store")
+
+	defer span.End()
+
+	query := `
+		WITH inserted AS (
+			INSERT INTO outbox (event_id, event_type, aggregate_type, aggregate_id, payload, occurred_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		)
+		SELECT pg_notify($7, id::text) FROM inserted`
+
+	for _, event := range events {
+		_, err := s.db.Conn(ctx).ExecContext(ctx, query,
+			event.ID, event.Type, event.AggregateType, event.AggregateID, []byte(event.Payload), event.OccurredAt, Channel)
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TryLock takes the relay lock for the rest of the transaction carried by
+// ctx. It reports false if another instance holds it.
+func (s *Store) TryLock(ctx context.Context) (bool, error) {
+	var locked bool
+	err := s.db.Conn(ctx).QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", relayLockKey).Scan(&locked)
+	return locked, err
+}
+
+// Pending returns the oldest unpublished events in sequence order that can
+// be published now. Events claimed, waiting to be retried or parked are left
+// out, and so are later events for their aggregates, which must not overtake
+// them; a stuck aggregate never takes up room in the batch.
+func (s *Store) Pending(ctx context.Context, limit int) ([]models.Event, error) {
+	tracer := otel.Tracer("OutboxStore")
+
+	ctx, span := tracer.Start(ctx, "Pending-Store")
+
+	defer span.End()
+
+	query := `SELECT ` + eventColumns + `
+		FROM outbox e
+		WHERE e.published_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox held
+				WHERE held.published_at IS NULL
+					AND held.aggregate_type = e.aggregate_type
+					AND held.aggregate_id = e.aggregate_id
+					AND held.id <= e.id
+					AND (held.parked_at IS NOT NULL OR held.retry_at > CURRENT_TIMESTAMP)
+			)
+		ORDER BY e.id
+		LIMIT $1`
+
+	events, err := queryEvents(ctx, s.db.Conn(ctx), query, limit)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return events, err
+}
+
+// Claim holds the events back from Pending until until, while they are
+// published outside the transaction that read them. An event that is
+// neither marked nor released by then is published again.
+func (s *Store) Claim(ctx context.Context, sequences []int64, until time.Time) error {
+	if len(sequences) == 0 {
+		return nil
+	}
+
+	_, err := s.db.Conn(ctx).ExecContext(ctx,
+		"UPDATE outbox SET retry_at = $2 WHERE id = ANY($1)", pq.Array(sequences), until)
+	return err
+}
+
+// Release returns claimed events that were not published to Pending.
+func (s *Store) Release(ctx context.Context, sequences []int64) error {
+	if len(sequences) == 0 {
+		return nil
+	}
+
+	_, err := s.db.Conn(ctx).ExecContext(ctx,
+		"UPDATE outbox SET retry_at = NULL WHERE id = ANY($1) AND published_at IS NULL", pq.Array(sequences))
+	return err
+}
+
+func (s *Store) MarkPublished(ctx context.Context, sequences []int64) error {
+	if len(sequences) == 0 {
+		return nil
+	}
+
+	_, err := s.db.Conn(ctx).ExecContext(ctx,
+		"UPDATE outbox SET published_at = CURRENT_TIMESTAMP, last_error = NULL WHERE id = ANY($1)",
+		pq.Array(sequences))
+	return err
+}
+
+// MarkFailed records a failed publish. The event is retried at retryAt, or
+// parked when retryAt is nil.
+func (s *Store) MarkFailed(ctx context.Context, sequence int64, reason string, retryAt *time.Time) error {
+	_, err := s.db.Conn(ctx).ExecContext(ctx, `
+		UPDATE outbox
+		SET attempts = attempts + 1, last_error = $1, retry_at = $2,
+			parked_at = CASE WHEN $2::timestamp IS NULL THEN CURRENT_TIMESTAMP END
+		WHERE id = $3`,
+		reason, retryAt, sequence)
+	return err
+}
+
+// DeletePublishedBefore removes published events older than before and
+// returns how many were removed.
+func (s *Store) DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.Conn(ctx).ExecContext(ctx,
+		"DELETE FROM outbox WHERE published_at IS NOT NULL AND published_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Since returns up to limit events with a sequence above after, oldest
+// first. It reads from the primary, which is where notified events are
+// guaranteed to be visible.
+func (s *Store) Since(ctx context.Context, after int64, limit int) ([]models.Event, error) {
+	tracer := otel.Tracer("OutboxStore")
+
+	ctx, span := tracer.Start(ctx, "Since-Store")
+
+	defer span.End()
+
+	query := `SELECT ` + eventColumns + ` FROM outbox WHERE id > $1 ORDER BY id LIMIT $2`
+
+	events, err := queryEvents(ctx, s.db.Reader(driver.WithPrimaryReads(ctx)), query, after, limit)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return events, err
+}
+
+func (s *Store) BySequence(ctx context.Context, sequences []int64) ([]models.Event, error) {
+	tracer := otel.Tracer("OutboxStore")
+
+	ctx, span := tracer.Start(ctx, "BySequence-Store")
+
+	defer span.End()
+
+	query := `SELECT ` + eventColumns + ` FROM outbox WHERE id = ANY($1) ORDER BY id`
+
+	events, err := queryEvents(ctx, s.db.Reader(driver.WithPrimaryReads(ctx)), query, pq.Array(sequences))
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return events, err
+}
+
+func (s *Store) LastSequence(ctx context.Context) (int64, error) {
+	var last int64
+	err := s.db.Reader(driver.WithPrimaryReads(ctx)).QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM outbox").Scan(&last)
+	return last, err
+}
+
+func queryEvents(ctx context.Context, q driver.Querier, query string, args ...interface{}) ([]models.Event, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var event models.Event
+		var payload []byte
+
+		if err := rows.Scan(&event.Sequence, &event.ID, &event.Type, &event.AggregateType,
+			&event.AggregateID, &payload, &event.OccurredAt, &event.Attempts); err != nil {
+			return nil, err
+		}
+
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
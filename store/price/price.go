@@ -0,0 +1,81 @@
+package price
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/money"
+	"go.opentelemetry.io/otel"
+)
+
+// Store keeps the price history of cars.
+type Store struct {
+	db *driver.DB
+}
+
+func New(db *driver.DB) *Store {
+	return &Store{db: db}
+}
+
+// RecordPrice adds price to the car's history as of at.
+func (s *Store) RecordPrice(ctx context.Context, carID uuid.UUID, price money.Money, at time.Time) error {
+	tracer := otel.Tracer("PriceStore")
+
+	ctx, span := tracer.Start(ctx, "RecordPrice-Store")
+
+	defer span.End()
+
+	_, err := s.db.Conn(ctx).ExecContext(ctx,
+		"INSERT INTO car_prices (car_id, price, currency, changed_at) VALUES ($1, $2, $3, $4)",
+		carID, price.Amount(), price.Currency, at)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return err
+}
+
+// ListPrices returns the car's history, oldest first.
+func (s *Store) ListPrices(ctx context.Context, carID uuid.UUID) ([]models.PricePoint, error) {
+	tracer := otel.Tracer("PriceStore")
+
+	ctx, span := tracer.Start(ctx, "ListPrices-Store")
+
+	defer span.End()
+
+	rows, err := s.db.Reader(ctx).QueryContext(ctx,
+		"SELECT price, currency, changed_at FROM car_prices WHERE car_id = $1 ORDER BY changed_at, id", carID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []models.PricePoint{}
+
+	for rows.Next() {
+		var point models.PricePoint
+		var amount, currency string
+		if err := rows.Scan(&amount, &currency, &point.ChangedAt); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		if point.Price, err = money.Parse(amount, strings.TrimSpace(currency)); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		points = append(points, point)
+	}
+
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return points, nil
+}
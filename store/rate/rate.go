@@ -0,0 +1,114 @@
+package rate
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/money"
+	"go.opentelemetry.io/otel"
+)
+
+type Store struct {
+	db *driver.DB
+}
+
+func New(db *driver.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetRates(ctx context.Context, base string) (money.Rates, error) {
+	tracer := otel.Tracer("ExchangeRateStore")
+
+	ctx, span := tracer.Start(ctx, "GetRates-Store")
+
+	defer span.End()
+
+	rates, err := s.query(ctx, s.db.Reader(ctx), base)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return rates, err
+}
+
+func (s *Store) ReplaceRates(ctx context.Context, rates money.Rates) (money.Rates, error) {
+	tracer := otel.Tracer("ExchangeRateStore")
+
+	ctx, span := tracer.Start(ctx, "ReplaceRates-Store")
+
+	defer span.End()
+
+	var replaced money.Rates
+
+	err := s.db.WithinTx(ctx, func(ctx context.Context) error {
+		conn := s.db.Conn(ctx)
+
+		if _, err := conn.ExecContext(ctx, "DELETE FROM exchange_rates WHERE base = $1", rates.Base); err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		for currency, rate := range rates.Rates {
+			if currency == rates.Base {
+				continue
+			}
+
+			_, err := conn.ExecContext(ctx,
+				"INSERT INTO exchange_rates (base, currency, rate, updated_at) VALUES ($1, $2, $3, $4)",
+				rates.Base, currency, money.FormatRate(rate), now)
+			if err != nil {
+				return fmt.Errorf("rate for %s: %w", currency, err)
+			}
+		}
+
+		var err error
+		replaced, err = s.query(ctx, conn, rates.Base)
+		return err
+	})
+
+	if err != nil {
+		span.RecordError(err)
+		return money.Rates{}, err
+	}
+
+	return replaced, nil
+}
+
+func (s *Store) query(ctx context.Context, q driver.Querier, base string) (money.Rates, error) {
+	rows, err := q.QueryContext(ctx, "SELECT currency, rate, updated_at FROM exchange_rates WHERE base = $1", base)
+	if err != nil {
+		return money.Rates{}, err
+	}
+	defer rows.Close()
+
+	rates := money.Rates{Base: base, Rates: map[string]*big.Rat{}}
+
+	for rows.Next() {
+		var (
+			currency, value string
+			updatedAt       time.Time
+		)
+
+		if err := rows.Scan(&currency, &value, &updatedAt); err != nil {
+			return money.Rates{}, err
+		}
+
+		rate, err := money.ParseRate(value)
+		if err != nil {
+			return money.Rates{}, err
+		}
+
+		rates.Rates[strings.TrimSpace(currency)] = rate
+
+		if updatedAt.After(rates.UpdatedAt) {
+			rates.UpdatedAt = updatedAt
+		}
+	}
+
+	return rates, rows.Err()
+}
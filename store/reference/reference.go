@@ -0,0 +1,179 @@
+package reference
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+// Store keeps the values of every reference kind, each in the table named
+// after it.
+type Store struct {
+	db *driver.DB
+}
+
+func New(db *driver.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) ListValues(ctx context.Context, kind string) ([]models.ReferenceValue, error) {
+	tracer := otel.Tracer("ReferenceStore")
+
+	ctx, span := tracer.Start(ctx, "ListValues-Store")
+
+	defer span.End()
+
+	if err := models.ValidateReferenceKind(kind); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Reader(ctx).QueryContext(ctx, "SELECT code, name, created_at, updated_at FROM "+kind+" ORDER BY name, code")
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []models.ReferenceValue{}
+
+	for rows.Next() {
+		var value models.ReferenceValue
+		if err := rows.Scan(&value.Code, &value.Name, &value.CreatedAt, &value.UpdatedAt); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func (s *Store) GetValue(ctx context.Context, kind, code string) (models.ReferenceValue, error) {
+	tracer := otel.Tracer("ReferenceStore")
+
+	ctx, span := tracer.Start(ctx, "GetValue-Store")
+
+	defer span.End()
+
+	if err := models.ValidateReferenceKind(kind); err != nil {
+		return models.ReferenceValue{}, err
+	}
+
+	var value models.ReferenceValue
+
+	err := s.db.Reader(ctx).QueryRowContext(ctx,
+		"SELECT code, name, created_at, updated_at FROM "+kind+" WHERE code = $1", code,
+	).Scan(&value.Code, &value.Name, &value.CreatedAt, &value.UpdatedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ReferenceValue{}, models.ErrReferenceNotFound
+		}
+		span.RecordError(err)
+		return models.ReferenceValue{}, err
+	}
+
+	return value, nil
+}
+
+func (s *Store) CreateValue(ctx context.Context, kind string, req models.ReferenceValueRequest) (models.ReferenceValue, error) {
+	tracer := otel.Tracer("ReferenceStore")
+
+	ctx, span := tracer.Start(ctx, "CreateValue-Store")
+
+	defer span.End()
+
+	if err := models.ValidateReferenceKind(kind); err != nil {
+		return models.ReferenceValue{}, err
+	}
+
+	now := time.Now()
+	value := models.ReferenceValue{Code: req.Code, Name: req.Name, CreatedAt: now, UpdatedAt: now}
+
+	_, err := s.db.Conn(ctx).ExecContext(ctx,
+		"INSERT INTO "+kind+" (code, name, created_at, updated_at) VALUES ($1, $2, $3, $4)",
+		value.Code, value.Name, now, now)
+
+	if err != nil {
+		span.RecordError(err)
+		if store.IsViolation(err, "23505") {
+			return models.ReferenceValue{}, models.ErrReferenceExists
+		}
+		return models.ReferenceValue{}, err
+	}
+
+	return value, nil
+}
+
+func (s *Store) UpdateValue(ctx context.Context, kind, code, name string) (models.ReferenceValue, error) {
+	tracer := otel.Tracer("ReferenceStore")
+
+	ctx, span := tracer.Start(ctx, "UpdateValue-Store")
+
+	defer span.End()
+
+	if err := models.ValidateReferenceKind(kind); err != nil {
+		return models.ReferenceValue{}, err
+	}
+
+	var value models.ReferenceValue
+
+	err := s.db.Conn(ctx).QueryRowContext(ctx,
+		"UPDATE "+kind+" SET name = $1, updated_at = CURRENT_TIMESTAMP WHERE code = $2 RETURNING code, name, created_at, updated_at",
+		name, code,
+	).Scan(&value.Code, &value.Name, &value.CreatedAt, &value.UpdatedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ReferenceValue{}, models.ErrReferenceNotFound
+		}
+		span.RecordError(err)
+		return models.ReferenceValue{}, err
+	}
+
+	return value, nil
+}
+
+func (s *Store) DeleteValue(ctx context.Context, kind, code string) error {
+	tracer := otel.Tracer("ReferenceStore")
+
+	ctx, span := tracer.Start(ctx, "DeleteValue-Store")
+
+	defer span.End()
+
+	if err := models.ValidateReferenceKind(kind); err != nil {
+		return err
+	}
+
+	result, err := s.db.Conn(ctx).ExecContext(ctx, "DELETE FROM "+kind+" WHERE code = $1", code)
+	if err != nil {
+		span.RecordError(err)
+		if store.IsViolation(err, "23503") {
+			return models.ErrReferenceInUse
+		}
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if n == 0 {
+		return models.ErrReferenceNotFound
+	}
+
+	return nil
+}
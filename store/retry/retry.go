@@ -0,0 +1,182 @@
+// Package retry wraps the SQL stores so calls that fail with a transient
+// database error are run again with backoff.
+//
+// Calls made inside a transaction are never retried here: the statement
+// that failed has aborted the transaction, so only whoever started it can
+// run the unit of work again (see driver.TxManager).
+package retry
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+)
+
+func run(ctx context.Context, retry func(context.Context, func() error) error, op func() error) error {
+	if driver.InTx(ctx) {
+		return op()
+	}
+	return retry(ctx, op)
+}
+
+type CarStore struct {
+	next    store.CarStoreInterface
+	retrier *driver.Retrier
+}
+
+func NewCarStore(next store.CarStoreInterface, retrier *driver.Retrier) *CarStore {
+	return &CarStore{next: next, retrier: retrier}
+}
+
+func (s *CarStore) GetCarById(ctx context.Context, id string) (models.Car, error) {
+	var car models.Car
+	err := run(ctx, s.retrier.Read, func() (err error) {
+		car, err = s.next.GetCarById(ctx, id)
+		return err
+	})
+	return car, err
+}
+
+func (s *CarStore) GetCarByBrand(ctx context.Context, brand string, isEngine bool, filter models.CarFilter) ([]models.Car, error) {
+	var cars []models.Car
+	err := run(ctx, s.retrier.Read, func() (err error) {
+		cars, err = s.next.GetCarByBrand(ctx, brand, isEngine, filter)
+		return err
+	})
+	return cars, err
+}
+
+func (s *CarStore) GetCarsByEngine(ctx context.Context, engineID uuid.UUID) ([]models.Car, error) {
+	var cars []models.Car
+	err := run(ctx, s.retrier.Read, func() (err error) {
+		cars, err = s.next.GetCarsByEngine(ctx, engineID)
+		return err
+	})
+	return cars, err
+}
+
+func (s *CarStore) CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error) {
+	var car models.Car
+	err := run(ctx, s.retrier.Write, func() (err error) {
+		car, err = s.next.CreateCar(ctx, carReq)
+		return err
+	})
+	return car, err
+}
+
+func (s *CarStore) LockCar(ctx context.Context, id uuid.UUID) error {
+	return run(ctx, s.retrier.Write, func() error {
+		return s.next.LockCar(ctx, id)
+	})
+}
+
+func (s *CarStore) UpdateCar(ctx context.Context, id uuid.UUID, carReq *models.CarRequest) (models.Car, error) {
+	var car models.Car
+	err := run(ctx, s.retrier.Write, func() (err error) {
+		car, err = s.next.UpdateCar(ctx, id, carReq)
+		return err
+	})
+	return car, err
+}
+
+func (s *CarStore) DeleteCar(ctx context.Context, id string) (models.Car, error) {
+	var car models.Car
+	err := run(ctx, s.retrier.Write, func() (err error) {
+		car, err = s.next.DeleteCar(ctx, id)
+		return err
+	})
+	return car, err
+}
+
+func (s *CarStore) RenameBrand(ctx context.Context, brandID uuid.UUID, name string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := run(ctx, s.retrier.Write, func() (err error) {
+		ids, err = s.next.RenameBrand(ctx, brandID, name)
+		return err
+	})
+	return ids, err
+}
+
+func (s *CarStore) RenameModel(ctx context.Context, modelID uuid.UUID, name string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := run(ctx, s.retrier.Write, func() (err error) {
+		ids, err = s.next.RenameModel(ctx, modelID, name)
+		return err
+	})
+	return ids, err
+}
+
+type EngineStore struct {
+	next    store.EngineStoreInterface
+	retrier *driver.Retrier
+}
+
+func NewEngineStore(next store.EngineStoreInterface, retrier *driver.Retrier) *EngineStore {
+	return &EngineStore{next: next, retrier: retrier}
+}
+
+func (s *EngineStore) GetEngineById(ctx context.Context, id string) (models.Engine, error) {
+	var engine models.Engine
+	err := run(ctx, s.retrier.Read, func() (err error) {
+		engine, err = s.next.GetEngineById(ctx, id)
+		return err
+	})
+	return engine, err
+}
+
+func (s *EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+	var engine models.Engine
+	err := run(ctx, s.retrier.Write, func() (err error) {
+		engine, err = s.next.CreateEngine(ctx, engineReq)
+		return err
+	})
+	return engine, err
+}
+
+func (s *EngineStore) EngineUpdate(ctx context.Context, id uuid.UUID, engineReq *models.EngineRequest) (models.Engine, error) {
+	var engine models.Engine
+	err := run(ctx, s.retrier.Write, func() (err error) {
+		engine, err = s.next.EngineUpdate(ctx, id, engineReq)
+		return err
+	})
+	return engine, err
+}
+
+func (s *EngineStore) EngineDelete(ctx context.Context, id string) (models.Engine, error) {
+	var engine models.Engine
+	err := run(ctx, s.retrier.Write, func() (err error) {
+		engine, err = s.next.EngineDelete(ctx, id)
+		return err
+	})
+	return engine, err
+}
+
+func (s *EngineStore) ListEngines(ctx context.Context, filter models.EngineFilter) ([]models.Engine, int, error) {
+	var (
+		engines []models.Engine
+		total   int
+	)
+	err := run(ctx, s.retrier.Read, func() (err error) {
+		engines, total, err = s.next.ListEngines(ctx, filter)
+		return err
+	})
+	return engines, total, err
+}
+
+func (s *EngineStore) LockSpecs(ctx context.Context, engineReq *models.EngineRequest) error {
+	return run(ctx, s.retrier.Write, func() error {
+		return s.next.LockSpecs(ctx, engineReq)
+	})
+}
+
+func (s *EngineStore) FindEngineBySpecs(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+	var engine models.Engine
+	err := run(ctx, s.retrier.Read, func() (err error) {
+		engine, err = s.next.FindEngineBySpecs(ctx, engineReq)
+		return err
+	})
+	return engine, err
+}
@@ -0,0 +1,30 @@
+package store
+
+import _ "embed"
+
+//go:embed schema.sql
+var schema string
+
+// FoldBrands files every car without a brand_id under the catalogue brand
+// its free-text brand resolves to, adding the brands that are missing. It
+// is part of Schema, and must be run again after loading cars other than
+// through the car store.
+//
+//go:embed fold_brands.sql
+var FoldBrands string
+
+// StartPrices gives every car without a price history one starting at its
+// current price. Like FoldBrands it is part of Schema, and must be run
+// again after loading cars other than through the car store.
+//
+//go:embed start_prices.sql
+var StartPrices string
+
+// Schema creates the tables the stores rely on. Every statement is
+// idempotent so it can run on each startup.
+var Schema = schema + "\n" + FoldBrands + "\n" + StartPrices
+
+// Seed inserts a small set of sample engines and cars.
+//
+//go:embed seed.sql
+var Seed string
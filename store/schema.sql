@@ -1,13 +1,36 @@
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
 CREATE TABLE IF NOT EXISTS engines (
     engine_id UUID PRIMARY KEY,      
+    powertrain VARCHAR(20) NOT NULL DEFAULT 'combustion',
     displacement INT NOT NULL,       
     no_of_cylinders INT NOT NULL,    
     car_range INT NOT NULL,
+    motor_power_kw INT,
+    motor_torque_nm INT,
+    battery_kwh NUMERIC(6, 2),
+    charging_standards TEXT[],
+    max_ac_charge_kw NUMERIC(6, 2),
+    max_dc_charge_kw NUMERIC(6, 2),
+    combined_power_kw INT,
+    combined_torque_nm INT,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Engines used to be combustion engines only. The motor, battery and
+-- combined columns stay NULL for powertrains without them.
+ALTER TABLE engines
+    ADD COLUMN IF NOT EXISTS powertrain VARCHAR(20) NOT NULL DEFAULT 'combustion',
+    ADD COLUMN IF NOT EXISTS motor_power_kw INT,
+    ADD COLUMN IF NOT EXISTS motor_torque_nm INT,
+    ADD COLUMN IF NOT EXISTS battery_kwh NUMERIC(6, 2),
+    ADD COLUMN IF NOT EXISTS charging_standards TEXT[],
+    ADD COLUMN IF NOT EXISTS max_ac_charge_kw NUMERIC(6, 2),
+    ADD COLUMN IF NOT EXISTS max_dc_charge_kw NUMERIC(6, 2),
+    ADD COLUMN IF NOT EXISTS combined_power_kw INT,
+    ADD COLUMN IF NOT EXISTS combined_torque_nm INT;
+
 CREATE TABLE IF NOT EXISTS cars (
     id UUID PRIMARY KEY,             
     name VARCHAR(100) NOT NULL,   
@@ -15,20 +38,376 @@ CREATE TABLE IF NOT EXISTS cars (
     brand VARCHAR(50) NOT NULL,     
     fuel_type VARCHAR(20) NOT NULL, 
     engine_id UUID NOT NULL,         
-    price DECIMAL(10, 2) NOT NULL,
+    price NUMERIC(19, 4) NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP   
 );
 
-CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+-- The engine catalogue is filtered by its specs, and creating an engine
+-- looks for one with identical specs to reuse.
+CREATE INDEX IF NOT EXISTS engines_specs_idx ON engines (displacement, no_of_cylinders, car_range);
+CREATE INDEX IF NOT EXISTS cars_engine_idx ON cars (engine_id);
+
+-- Prices used to be DECIMAL(10, 2) in US dollars, which capped them below
+-- 100 million and had no room for currencies with three decimal places.
+DO $$
+BEGIN
+    IF (SELECT numeric_precision FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'cars' AND column_name = 'price') <> 19 THEN
+        ALTER TABLE cars ALTER COLUMN price TYPE NUMERIC(19, 4);
+    END IF;
+END
+$$;
+
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+-- Reference values a car's specs are chosen from. Cars refer to them by
+-- code, so a value cannot be deleted while a car uses it.
+CREATE TABLE IF NOT EXISTS transmissions (
+    code VARCHAR(30) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS drivetrains (
+    code VARCHAR(30) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS body_styles (
+    code VARCHAR(30) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS colours (
+    code VARCHAR(30) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Start each reference table off with the common values. Tables that
+-- already have values are left alone.
+INSERT INTO transmissions (code, name)
+SELECT * FROM (VALUES ('manual', 'Manual'), ('automatic', 'Automatic'), ('cvt', 'CVT'), ('dct', 'Dual-clutch (DCT)')) v
+WHERE NOT EXISTS (SELECT 1 FROM transmissions);
+
+INSERT INTO drivetrains (code, name)
+SELECT * FROM (VALUES ('fwd', 'Front-wheel drive'), ('rwd', 'Rear-wheel drive'), ('awd', 'All-wheel drive'), ('4wd', 'Four-wheel drive')) v
+WHERE NOT EXISTS (SELECT 1 FROM drivetrains);
+
+INSERT INTO body_styles (code, name)
+SELECT * FROM (VALUES ('sedan', 'Sedan'), ('hatchback', 'Hatchback'), ('wagon', 'Wagon'), ('suv', 'SUV'), ('coupe', 'Coupe'),
+    ('convertible', 'Convertible'), ('pickup', 'Pickup'), ('van', 'Van')) v
+WHERE NOT EXISTS (SELECT 1 FROM body_styles);
+
+INSERT INTO colours (code, name)
+SELECT * FROM (VALUES ('black', 'Black'), ('white', 'White'), ('silver', 'Silver'), ('grey', 'Grey'), ('red', 'Red'),
+    ('blue', 'Blue'), ('green', 'Green'), ('brown', 'Brown'), ('yellow', 'Yellow'), ('orange', 'Orange')) v
+WHERE NOT EXISTS (SELECT 1 FROM colours);
+
+ALTER TABLE cars
+    ADD COLUMN IF NOT EXISTS trim_level VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS transmission VARCHAR(30) REFERENCES transmissions (code),
+    ADD COLUMN IF NOT EXISTS drivetrain VARCHAR(30) REFERENCES drivetrains (code),
+    ADD COLUMN IF NOT EXISTS body_style VARCHAR(30) REFERENCES body_styles (code),
+    ADD COLUMN IF NOT EXISTS colour VARCHAR(30) REFERENCES colours (code),
+    ADD COLUMN IF NOT EXISTS doors SMALLINT CHECK (doors BETWEEN 1 AND 7),
+    ADD COLUMN IF NOT EXISTS seats SMALLINT CHECK (seats BETWEEN 1 AND 12);
+
+-- What is known of a used car's past. The mileage is kept in the unit it
+-- was given in; mileage_km is what filters compare.
+ALTER TABLE cars
+    ADD COLUMN IF NOT EXISTS mileage BIGINT CHECK (mileage >= 0),
+    ADD COLUMN IF NOT EXISTS mileage_unit VARCHAR(2) CHECK (mileage_unit IN ('km', 'mi')),
+    ADD COLUMN IF NOT EXISTS condition VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS previous_owners SMALLINT CHECK (previous_owners >= 0),
+    ADD COLUMN IF NOT EXISTS accidents SMALLINT CHECK (accidents >= 0),
+    ADD COLUMN IF NOT EXISTS service_history VARCHAR(20);
+
+ALTER TABLE cars
+    ADD COLUMN IF NOT EXISTS mileage_km NUMERIC(12, 2) GENERATED ALWAYS AS (
+        CASE WHEN mileage_unit = 'mi' THEN mileage * 1.609344 ELSE mileage END) STORED;
+
+CREATE INDEX IF NOT EXISTS cars_mileage_km_idx ON cars (mileage_km);
+
+-- The fuel types cars are given, by code. The code and synonyms match
+-- whatever their case; the service keeps them from being another fuel
+-- type's.
+CREATE TABLE IF NOT EXISTS fuel_types (
+    code VARCHAR(20) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    synonyms TEXT[] NOT NULL DEFAULT '{}',
+    powertrains TEXT[] NOT NULL,
+    deprecated BOOLEAN NOT NULL DEFAULT FALSE,
+    emission_factor NUMERIC(8, 4) NOT NULL DEFAULT 0 CHECK (emission_factor >= 0),
+    emission_unit VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The fuel types cars could have before the registry existed.
+INSERT INTO fuel_types (code, name, synonyms, powertrains, emission_factor, emission_unit)
+SELECT * FROM (VALUES
+    ('Petrol', 'Petrol', '{Gasoline,Gas,Benzine}'::TEXT[], '{combustion}'::TEXT[], 2.3100, 'kg_co2e_per_litre'),
+    ('Diesel', 'Diesel', '{}'::TEXT[], '{combustion}'::TEXT[], 2.6800, 'kg_co2e_per_litre'),
+    ('Electric', 'Electric', '{EV,BEV}'::TEXT[], '{electric}'::TEXT[], 0, 'kg_co2e_per_kwh'),
+    ('Hybrid', 'Hybrid', '{HEV,PHEV,"Plug-in Hybrid"}'::TEXT[], '{hybrid,plug_in_hybrid}'::TEXT[], 2.3100, 'kg_co2e_per_litre')) v
+WHERE NOT EXISTS (SELECT 1 FROM fuel_types);
+
+-- Give every car the code of its fuel type, should it be spelled another
+-- way.
+UPDATE cars c
+SET fuel_type = f.code
+FROM fuel_types f
+WHERE c.fuel_type <> f.code
+    AND (lower(c.fuel_type) = lower(f.code)
+        OR lower(c.fuel_type) IN (SELECT lower(s) FROM unnest(f.synonyms) s));
+
+-- The brand and model catalogue. Names and aliases match whatever their
+-- case; the service keeps a brand's names from being another brand's, and
+-- a model's from being another model's of the same brand.
+CREATE TABLE IF NOT EXISTS brands (
+    id UUID PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS brands_name_idx ON brands (lower(name));
+
+CREATE TABLE IF NOT EXISTS brand_aliases (
+    brand_id UUID NOT NULL REFERENCES brands (id) ON DELETE CASCADE,
+    alias VARCHAR(50) NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS brand_aliases_alias_idx ON brand_aliases (lower(alias));
+CREATE INDEX IF NOT EXISTS brand_aliases_brand_idx ON brand_aliases (brand_id);
+
+CREATE TABLE IF NOT EXISTS models (
+    id UUID PRIMARY KEY,
+    brand_id UUID NOT NULL REFERENCES brands (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS models_name_idx ON models (brand_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS model_aliases (
+    model_id UUID NOT NULL REFERENCES models (id) ON DELETE CASCADE,
+    brand_id UUID NOT NULL,
+    alias VARCHAR(100) NOT NULL
+);
 
-INSERT INTO engines (engine_id, displacement, no_of_cylinders, car_range) VALUES
-    (uuid_generate_v4(), 2000, 4, 500),
-    (uuid_generate_v4(), 1500, 4, 450),
-    (uuid_generate_v4(), 3000, 6, 600);
+CREATE UNIQUE INDEX IF NOT EXISTS model_aliases_alias_idx ON model_aliases (brand_id, lower(alias));
+CREATE INDEX IF NOT EXISTS model_aliases_model_idx ON model_aliases (model_id);
 
+-- Every name and alias, lowercased, with what it resolves to.
+CREATE OR REPLACE VIEW brand_names AS
+    SELECT id AS brand_id, lower(name) AS key FROM brands
+    UNION ALL
+    SELECT brand_id, lower(alias) FROM brand_aliases;
+
+CREATE OR REPLACE VIEW model_names AS
+    SELECT brand_id, id AS model_id, lower(name) AS key FROM models
+    UNION ALL
+    SELECT brand_id, model_id, lower(alias) FROM model_aliases;
+
+-- cars.brand and cars.model hold the canonical names, kept in step with
+-- the catalogue entries the IDs point at. The fold that follows this file
+-- files older cars under their brand.
+ALTER TABLE cars
+    ADD COLUMN IF NOT EXISTS model VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS brand_id UUID REFERENCES brands (id),
+    ADD COLUMN IF NOT EXISTS model_id UUID REFERENCES models (id);
+
+CREATE INDEX IF NOT EXISTS cars_brand_idx ON cars (brand_id);
+CREATE INDEX IF NOT EXISTS cars_model_idx ON cars (model_id);
+
+-- Exchange rates against a base currency: one unit of base buys rate units
+-- of currency.
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    base CHAR(3) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    rate NUMERIC(24, 12) NOT NULL CHECK (rate > 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (base, currency)
+);
+
+-- Domain events written in the same transaction as the change they describe
+-- and published by the outbox relay. id gives the global order.
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL UNIQUE,
+    event_type VARCHAR(100) NOT NULL,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (id) WHERE published_at IS NULL;
+
+-- An event that failed to publish waits until retry_at, holding back later
+-- events for its aggregate. The relay also sets retry_at to claim the events
+-- it is publishing. Once it runs out of attempts it is parked and
+-- holds them back until requeued by clearing parked_at and attempts.
+ALTER TABLE outbox
+    ADD COLUMN IF NOT EXISTS retry_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS parked_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS outbox_aggregate_pending_idx ON outbox (aggregate_type, aggregate_id, id) WHERE published_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id UUID PRIMARY KEY,
+    url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    secret TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    last_status_code INT,
+    last_error TEXT,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_log_idx ON webhook_deliveries (subscription_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS car_images (
+    id UUID PRIMARY KEY,
+    car_id UUID NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    is_cover BOOLEAN NOT NULL DEFAULT FALSE,
+    content_type VARCHAR(50) NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    original_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS car_images_car_idx ON car_images (car_id, position);
+CREATE UNIQUE INDEX IF NOT EXISTS car_images_cover_idx ON car_images (car_id) WHERE is_cover;
+
+-- Files attached to cars. blob_key is derived from the checksum, so a file
+-- attached to several cars is stored once.
+CREATE TABLE IF NOT EXISTS car_documents (
+    id UUID PRIMARY KEY,
+    car_id UUID NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    category VARCHAR(50) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    blob_key TEXT NOT NULL,
+    uploaded_by VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (car_id, checksum)
+);
+
+CREATE INDEX IF NOT EXISTS car_documents_car_idx ON car_documents (car_id, category, created_at);
+CREATE INDEX IF NOT EXISTS car_documents_checksum_idx ON car_documents (checksum);
+
+-- Every price a car has been listed at, oldest first. The car's current
+-- price is the latest; a new row is written whenever it changes.
+CREATE TABLE IF NOT EXISTS car_prices (
+    id BIGSERIAL PRIMARY KEY,
+    car_id UUID NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    price NUMERIC(19, 4) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS car_prices_car_idx ON car_prices (car_id, changed_at, id);
+
+-- Listing queries users are alerted about. Empty criteria match any car;
+-- min_price and max_price are in currency.
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id UUID PRIMARY KEY,
+    owner VARCHAR(100) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    brand_id UUID REFERENCES brands (id),
+    fuel_type VARCHAR(20),
+    min_price NUMERIC(19, 4),
+    max_price NUMERIC(19, 4),
+    currency CHAR(3),
+    min_year SMALLINT,
+    max_year SMALLINT,
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('instant', 'hourly', 'daily', 'weekly')),
+    email VARCHAR(254),
+    last_notified_at TIMESTAMP,
+    -- The earliest time the next digest may be sent.
+    next_digest_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_owner_idx ON saved_searches (owner, created_at);
+CREATE INDEX IF NOT EXISTS saved_searches_brand_idx ON saved_searches (brand_id);
+
+-- The cars each search matches, at the price they were last seen at, so
+-- that only new matches and price drops raise alerts.
+CREATE TABLE IF NOT EXISTS search_matches (
+    search_id UUID NOT NULL REFERENCES saved_searches (id) ON DELETE CASCADE,
+    car_id UUID NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    price NUMERIC(19, 4) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    matched_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (search_id, car_id)
+);
+
+CREATE INDEX IF NOT EXISTS search_matches_car_idx ON search_matches (car_id);
+
+-- Alerts wait here, with the car as it was, until the search's next
+-- digest sends them.
+CREATE TABLE IF NOT EXISTS search_alerts (
+    id BIGSERIAL PRIMARY KEY,
+    search_id UUID NOT NULL REFERENCES saved_searches (id) ON DELETE CASCADE,
+    car_id UUID NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    car JSONB NOT NULL,
+    old_price NUMERIC(19, 4),
+    old_currency CHAR(3),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    notified_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS search_alerts_search_idx ON search_alerts (search_id, id);
+CREATE INDEX IF NOT EXISTS search_alerts_pending_idx ON search_alerts (search_id) WHERE notified_at IS NULL;
+CREATE INDEX IF NOT EXISTS search_alerts_car_idx ON search_alerts (car_id);
+
+-- The cars each user has favorited.
+CREATE TABLE IF NOT EXISTS favorites (
+    owner VARCHAR(100) NOT NULL,
+    car_id UUID NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (owner, car_id)
+);
 
-INSERT INTO cars (id, name, year, brand, fuel_type, engine_id, price) VALUES
-    (uuid_generate_v4(), 'Toyota Camry', '2020', 'Toyota', 'Petrol', (SELECT engine_id FROM engines LIMIT 1), 24000.00),
-    (uuid_generate_v4(), 'Honda Accord', '2019', 'Honda', 'Petrol', (SELECT engine_id FROM engines LIMIT 1 OFFSET 1), 22000.00),
-    (uuid_generate_v4(), 'Ford Mustang', '2021', 'Ford', 'Petrol', (SELECT engine_id FROM engines LIMIT 1 OFFSET 2), 30000.00);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS favorites_car_idx ON favorites (car_id);
@@ -0,0 +1,590 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/money"
+	"github.com/michgboxy2/carzone/store"
+	"go.opentelemetry.io/otel"
+)
+
+const searchColumns = `
+	s.id, s.owner, s.name, s.brand_id, b.name, s.fuel_type, s.min_price, s.max_price, s.currency,
+	s.min_year, s.max_year, s.frequency, s.email, s.last_notified_at, s.created_at, s.updated_at`
+
+const alertColumns = "id, search_id, kind, car, old_price, old_currency, created_at, notified_at"
+
+// Store keeps saved searches, the cars they match and the alerts waiting
+// to be sent.
+type Store struct {
+	db *driver.DB
+}
+
+func New(db *driver.DB) *Store {
+	return &Store{db: db}
+}
+
+// LockOwner takes a transaction-scoped advisory lock on the owner, so their
+// searches are counted and created one at a time.
+func (s *Store) LockOwner(ctx context.Context, owner string) error {
+	_, err := s.db.Conn(ctx).ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('saved_searches:' || $1))", owner)
+	return err
+}
+
+// CreateSearch saves search for its owner. It reports
+// models.ErrBrandNotFound if its brand was deleted meanwhile.
+func (s *Store) CreateSearch(ctx context.Context, search models.SavedSearch) (models.SavedSearch, error) {
+	tracer := otel.Tracer("SearchStore")
+
+	ctx, span := tracer.Start(ctx, "CreateSearch-Store")
+
+	defer span.End()
+
+	search.ID = uuid.New()
+
+	query := `
+		INSERT INTO saved_searches (id, owner, name, brand_id, fuel_type, min_price, max_price, currency,
+			min_year, max_year, frequency, email)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING created_at, updated_at`
+
+	args := append([]interface{}{search.ID, search.Owner}, searchValues(search)...)
+
+	err := s.db.Conn(ctx).QueryRowContext(ctx, query, args...).Scan(&search.CreatedAt, &search.UpdatedAt)
+	if err != nil {
+		span.RecordError(err)
+		if store.IsViolation(err, "23503") {
+			return models.SavedSearch{}, models.ErrBrandNotFound
+		}
+		return models.SavedSearch{}, err
+	}
+
+	return search, nil
+}
+
+// GetSearch returns one of owner's searches.
+func (s *Store) GetSearch(ctx context.Context, owner string, id uuid.UUID) (models.SavedSearch, error) {
+	tracer := otel.Tracer("SearchStore")
+
+	ctx, span := tracer.Start(ctx, "GetSearch-Store")
+
+	defer span.End()
+
+	search, err := scanSearch(s.db.Reader(ctx).QueryRowContext(ctx, `
+		SELECT `+searchColumns+`
+		FROM saved_searches s LEFT JOIN brands b ON b.id = s.brand_id
+		WHERE s.id = $1 AND s.owner = $2`, id, owner))
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.SavedSearch{}, models.ErrSearchNotFound
+		}
+		return models.SavedSearch{}, err
+	}
+
+	return search, nil
+}
+
+// ListSearches returns owner's searches, oldest first.
+func (s *Store) ListSearches(ctx context.Context, owner string) ([]models.SavedSearch, error) {
+	tracer := otel.Tracer("SearchStore")
+
+	ctx, span := tracer.Start(ctx, "ListSearches-Store")
+
+	defer span.End()
+
+	searches, err := s.querySearches(ctx, s.db.Reader(ctx), `
+		SELECT `+searchColumns+`
+		FROM saved_searches s LEFT JOIN brands b ON b.id = s.brand_id
+		WHERE s.owner = $1
+		ORDER BY s.created_at, s.id`, owner)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return searches, nil
+}
+
+func (s *Store) CountSearches(ctx context.Context, owner string) (int, error) {
+	tracer := otel.Tracer("SearchStore")
+
+	ctx, span := tracer.Start(ctx, "CountSearches-Store")
+
+	defer span.End()
+
+	var n int
+	err := s.db.Conn(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM saved_searches WHERE owner = $1", owner).Scan(&n)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return n, err
+}
+
+// UpdateSearch replaces the criteria and settings of one of search.Owner's
+// searches.
+func (s *Store) UpdateSearch(ctx context.Context, search models.SavedSearch) (models.SavedSearch, error) {
+	tracer := otel.Tracer("SearchStore")
+
+	ctx, span := tracer.Start(ctx, "UpdateSearch-Store")
+
+	defer span.End()
+
+	query := `
+		UPDATE saved_searches
+		SET name = $3, brand_id = $4, fuel_type = $5, min_price = $6, max_price = $7, currency = $8,
+			min_year = $9, max_year = $10, frequency = $11, email = $12, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND owner = $2
+		RETURNING last_notified_at, created_at, updated_at`
+
+	args := append([]interface{}{search.ID, search.Owner}, searchValues(search)...)
+
+	err := s.db.Conn(ctx).QueryRowContext(ctx, query, args...).
+		Scan(&search.LastNotifiedAt, &search.CreatedAt, &search.UpdatedAt)
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.SavedSearch{}, models.ErrSearchNotFound
+		}
+		if store.IsViolation(err, "23503") {
+			return models.SavedSearch{}, models.ErrBrandNotFound
+		}
+		return models.SavedSearch{}, err
+	}
+
+	return search, nil
+}
+
+// DeleteSearch removes one of owner's searches and, through the foreign
+// keys, its matches and alerts.
+func (s *Store) DeleteSearch(ctx context.Context, owner string, id uuid.UUID) error {
+	tracer := otel.Tracer("SearchStore")
+
+	ctx, span := tracer.Start(ctx, "DeleteSearch-Store")
+
+	defer span.End()
+
+	result, err := s.db.Conn(ctx).ExecContext(ctx, "DELETE FROM saved_searches WHERE id = $1 AND owner = $2", id, owner)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if n == 0 {
+		return models.ErrSearchNotFound
+	}
+
+	return nil
+}
+
+// ListAlerts returns the newest alerts of a search first, sent or not.
+func (s *Store) ListAlerts(ctx context.Context, searchID uuid.UUID, limit int) ([]models.SearchAlert, error) {
+	tracer := otel.Tracer("SearchStore")
+
+	ctx, span := tracer.Start(ctx, "ListAlerts-Store")
+
+	defer span.End()
+
+	rows, err := s.db.Reader(ctx).QueryContext(ctx, `
+		SELECT `+alertColumns+`
+		FROM search_alerts
+		WHERE search_id = $1
+		ORDER BY id DESC
+		LIMIT $2`, searchID, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []models.SearchAlert{}
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+// matchesBrandAndFuel holds for the searches s whose brand and fuel type
+// are $1 and $2, or that leave them out.
+const matchesBrandAndFuel = `(s.brand_id IS NULL OR s.brand_id IN (SELECT brand_id FROM brand_names WHERE key = lower($1)))
+	AND (s.fuel_type IS NULL OR s.fuel_type = $2)`
+
+// MatchingSearches returns the searches whose brand and fuel type car has,
+// or that leave them out. The caller checks the years and prices.
+func (s *Store) MatchingSearches(ctx context.Context, car models.Car) ([]models.SavedSearch, error) {
+	tracer := otel.Tracer("SearchStore")
+
+	ctx, span := tracer.Start(ctx, "MatchingSearches-Store")
+
+	defer span.End()
+
+	searches, err := s.querySearches(ctx, s.db.Conn(ctx), `
+		SELECT `+searchColumns+`
+		FROM saved_searches s LEFT JOIN brands b ON b.id = s.brand_id
+		WHERE `+matchesBrandAndFuel, car.Brand, car.FuelType)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return searches, nil
+}
+
+// DeleteStaleMatches forgets the car's matches with searches whose brand or
+// fuel type it no longer has, which MatchingSearches does not return, so
+// that matching one of them again is news again.
+func (s *Store) DeleteStaleMatches(ctx context.Context, car models.Car) error {
+	tracer := otel.Tracer("SearchStore")
+
+	ctx, span := tracer.Start(ctx, "DeleteStaleMatches-Store")
+
+	defer span.End()
+
+	_, err := s.db.Conn(ctx).ExecContext(ctx, `
+		DELETE FROM search_matches m
+		USING saved_searches s
+		WHERE m.search_id = s.id AND m.car_id = $3 AND NOT (`+matchesBrandAndFuel+`)`,
+		car.Brand, car.FuelType, car.ID)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return err
+}
+
+// GetMatch returns the price a search last saw a car at, or nil if the car
+// does not match it.
+func (s *Store) GetMatch(ctx context.Context, searchID, carID uuid.UUID) (*money.Money, error) {
+	tracer := otel.Tracer("SearchStore")
+
+	ctx, span := tracer.Start(ctx, "GetMatch-Store")
+
+	defer span.End()
+
+	var amount, currency string
+	err := s.db.Conn(ctx).QueryRowContext(ctx,
+		"SELECT price, currency FROM search_matches WHERE search_id = $1 AND car_id = $2", searchID, carID).
+		Scan(&amount, &currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	price, err := money.Parse(amount, strings.TrimSpace(currency))
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &price, nil
+}
+
+// SaveMatch records that a search matches a car at price. Cars deleted
+// since are skipped.
+func (s *Store) SaveMatch(ctx context.Context, searchID, carID uuid.UUID, price money.Money) error {
+	tracer := otel.Tracer("SearchStore")
+
+	ctx, span := tracer.Start(ctx, "SaveMatch-Store")
+
+	defer span.End()
+
+	_, err := s.db.Conn(ctx).ExecContext(ctx, `
+		INSERT INTO search_matches (search_id, car_id, price, currency)
+		SELECT $1, $2, $3, $4
+		WHERE EXISTS (SELECT 1 FROM cars WHERE id = $2)
+		ON CONFLICT (search_id, car_id) DO UPDATE SET price = EXCLUDED.price, currency = EXCLUDED.currency`,
+		searchID, carID, price.Amount(), price.Currency)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return err
+}
+
+func (s *Store) DeleteMatch(ctx context.Context, searchID, carID uuid.UUID) error {
+	tracer := otel.Tracer("SearchStore")
+
+	ctx, span := tracer.Start(ctx, "DeleteMatch-Store")
+
+	defer span.End()
+
+	_, err := s.db.Conn(ctx).ExecContext(ctx,
+		"DELETE FROM search_matches WHERE search_id = $1 AND car_id = $2", searchID, carID)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return err
+}
+
+// AddAlert queues an alert for the search's next digest. Alerts for cars
+// deleted since are skipped.
+func (s *Store) AddAlert(ctx context.Context, alert models.SearchAlert) error {
+	tracer := otel.Tracer("SearchStore")
+
+	ctx, span := tracer.Start(ctx, "AddAlert-Store")
+
+	defer span.End()
+
+	car, err := json.Marshal(alert.Car)
+	if err != nil {
+		return err
+	}
+
+	var oldPrice, oldCurrency sql.NullString
+	if p := alert.OldPrice; p != nil {
+		oldPrice = sql.NullString{String: p.Amount(), Valid: true}
+		oldCurrency = sql.NullString{String: p.Currency, Valid: true}
+	}
+
+	_, err = s.db.Conn(ctx).ExecContext(ctx, `
+		INSERT INTO search_alerts (search_id, car_id, kind, car, old_price, old_currency)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE EXISTS (SELECT 1 FROM cars WHERE id = $2)`,
+		alert.SearchID, alert.Car.ID, alert.Kind, car, oldPrice, oldCurrency)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return err
+}
+
+// ClaimDueDigests claims up to limit searches with alerts waiting whose
+// next digest is due, and returns them with those alerts. A claimed search
+// is invisible to other digesters for the lease.
+func (s *Store) ClaimDueDigests(ctx context.Context, limit int, lease time.Duration) ([]models.SearchDigest, error) {
+	tracer := otel.Tracer("SearchStore")
+
+	ctx, span := tracer.Start(ctx, "ClaimDueDigests-Store")
+
+	defer span.End()
+
+	query := `
+		WITH due AS (
+			SELECT id FROM saved_searches s
+			WHERE next_digest_at <= CURRENT_TIMESTAMP
+				AND EXISTS (SELECT 1 FROM search_alerts a WHERE a.search_id = s.id AND a.notified_at IS NULL)
+			ORDER BY next_digest_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE saved_searches s
+			SET next_digest_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+			FROM due
+			WHERE s.id = due.id
+			RETURNING s.id
+		)
+		SELECT ` + searchColumns + `
+		FROM saved_searches s LEFT JOIN brands b ON b.id = s.brand_id
+		WHERE s.id IN (SELECT id FROM claimed)`
+
+	searches, err := s.querySearches(ctx, s.db.Conn(ctx), query, limit, lease.Seconds())
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if len(searches) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, len(searches))
+	digests := make(map[uuid.UUID]*models.SearchDigest, len(searches))
+	for i, search := range searches {
+		ids[i] = search.ID
+		digests[search.ID] = &models.SearchDigest{Search: search}
+	}
+
+	rows, err := s.db.Conn(ctx).QueryContext(ctx, `
+		SELECT `+alertColumns+`
+		FROM search_alerts
+		WHERE search_id = ANY ($1) AND notified_at IS NULL
+		ORDER BY id`, pq.Array(ids))
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		digest := digests[alert.SearchID]
+		digest.Alerts = append(digest.Alerts, alert)
+	}
+
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	due := make([]models.SearchDigest, 0, len(searches))
+	for _, search := range searches {
+		// The search's alerts may have gone with their cars meanwhile.
+		if digest := digests[search.ID]; len(digest.Alerts) > 0 {
+			due = append(due, *digest)
+		}
+	}
+
+	return due, nil
+}
+
+// MarkDigestSent marks the search's alerts up to lastAlertID as sent and
+// holds its next digest back until next.
+func (s *Store) MarkDigestSent(ctx context.Context, searchID uuid.UUID, lastAlertID int64, next time.Time) error {
+	_, err := s.db.Conn(ctx).ExecContext(ctx, `
+		WITH sent AS (
+			UPDATE search_alerts SET notified_at = CURRENT_TIMESTAMP
+			WHERE search_id = $1 AND id <= $2 AND notified_at IS NULL
+		)
+		UPDATE saved_searches
+		SET last_notified_at = CURRENT_TIMESTAMP, next_digest_at = $3
+		WHERE id = $1`, searchID, lastAlertID, next)
+	return err
+}
+
+// PostponeDigest holds the search's next digest back until next, leaving
+// its alerts waiting.
+func (s *Store) PostponeDigest(ctx context.Context, searchID uuid.UUID, next time.Time) error {
+	_, err := s.db.Conn(ctx).ExecContext(ctx,
+		"UPDATE saved_searches SET next_digest_at = $1 WHERE id = $2", next, searchID)
+	return err
+}
+
+func (s *Store) querySearches(ctx context.Context, db driver.Querier, query string, args ...interface{}) ([]models.SavedSearch, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []models.SavedSearch{}
+	for rows.Next() {
+		search, err := scanSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+
+	return searches, rows.Err()
+}
+
+// searchValues returns the values of the columns from name to email.
+func searchValues(search models.SavedSearch) []interface{} {
+	text := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: s != ""}
+	}
+	amount := func(m *money.Money) sql.NullString {
+		if m == nil {
+			return sql.NullString{}
+		}
+		return sql.NullString{String: m.Amount(), Valid: true}
+	}
+	year := func(y int) sql.NullInt64 {
+		return sql.NullInt64{Int64: int64(y), Valid: y != 0}
+	}
+
+	return []interface{}{
+		search.Name, search.BrandID, text(search.FuelType), amount(search.MinPrice), amount(search.MaxPrice),
+		text(search.Currency()), year(search.MinYear), year(search.MaxYear), search.Frequency, text(search.Email),
+	}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSearch(row scanner) (models.SavedSearch, error) {
+	var search models.SavedSearch
+	var brand, fuelType, minPrice, maxPrice, currency, email sql.NullString
+	var minYear, maxYear sql.NullInt64
+
+	err := row.Scan(&search.ID, &search.Owner, &search.Name, &search.BrandID, &brand, &fuelType, &minPrice, &maxPrice,
+		&currency, &minYear, &maxYear, &search.Frequency, &email, &search.LastNotifiedAt, &search.CreatedAt, &search.UpdatedAt)
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+
+	search.Brand = brand.String
+	search.FuelType = fuelType.String
+	search.Email = email.String
+	search.MinYear = int(minYear.Int64)
+	search.MaxYear = int(maxYear.Int64)
+
+	for _, bound := range []struct {
+		amount sql.NullString
+		price  **money.Money
+	}{{minPrice, &search.MinPrice}, {maxPrice, &search.MaxPrice}} {
+		if !bound.amount.Valid {
+			continue
+		}
+
+		price, err := money.Parse(bound.amount.String, strings.TrimSpace(currency.String))
+		if err != nil {
+			return models.SavedSearch{}, err
+		}
+		*bound.price = &price
+	}
+
+	return search, nil
+}
+
+func scanAlert(row scanner) (models.SearchAlert, error) {
+	var alert models.SearchAlert
+	var car []byte
+	var oldPrice, oldCurrency sql.NullString
+
+	err := row.Scan(&alert.ID, &alert.SearchID, &alert.Kind, &car, &oldPrice, &oldCurrency, &alert.CreatedAt, &alert.NotifiedAt)
+	if err != nil {
+		return models.SearchAlert{}, err
+	}
+
+	if err := json.Unmarshal(car, &alert.Car); err != nil {
+		return models.SearchAlert{}, err
+	}
+
+	if oldPrice.Valid {
+		price, err := money.Parse(oldPrice.String, strings.TrimSpace(oldCurrency.String))
+		if err != nil {
+			return models.SearchAlert{}, err
+		}
+		alert.OldPrice = &price
+	}
+
+	return alert, nil
+}
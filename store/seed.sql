@@ -0,0 +1,15 @@
+INSERT INTO engines (engine_id, displacement, no_of_cylinders, car_range) VALUES
+    (uuid_generate_v4(), 2000, 4, 500),
+    (uuid_generate_v4(), 1500, 4, 450),
+    (uuid_generate_v4(), 3000, 6, 600);
+
+INSERT INTO engines (engine_id, powertrain, displacement, no_of_cylinders, car_range,
+                     motor_power_kw, motor_torque_nm, battery_kwh, charging_standards, max_ac_charge_kw, max_dc_charge_kw) VALUES
+    (uuid_generate_v4(), 'electric', 0, 0, 510, 220, 420, 77.40, '{CCS2,Type2}', 11, 175);
+
+
+INSERT INTO cars (id, name, year, brand, fuel_type, engine_id, price) VALUES
+    (uuid_generate_v4(), 'Toyota Camry', '2020', 'Toyota', 'Petrol', (SELECT engine_id FROM engines LIMIT 1), 24000.00),
+    (uuid_generate_v4(), 'Honda Accord', '2019', 'Honda', 'Petrol', (SELECT engine_id FROM engines LIMIT 1 OFFSET 1), 22000.00),
+    (uuid_generate_v4(), 'Ford Mustang', '2021', 'Ford', 'Petrol', (SELECT engine_id FROM engines LIMIT 1 OFFSET 2), 30000.00),
+    (uuid_generate_v4(), 'Hyundai Ioniq 5', '2023', 'Hyundai', 'Electric', (SELECT engine_id FROM engines WHERE powertrain = 'electric' LIMIT 1), 45000.00);
@@ -0,0 +1,110 @@
+package similar
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"go.opentelemetry.io/otel"
+)
+
+// Store ranks cars against each other in the database.
+type Store struct {
+	db *driver.DB
+}
+
+func New(db *driver.DB) *Store {
+	return &Store{db: db}
+}
+
+// rankQuery scores every other car against the source car. Each part of
+// the distance runs from 0 to 1 before it is weighted:
+//
+//   - brand and fuel type are 0 when they are the same and 1 otherwise;
+//   - year is the difference in years over the year span;
+//   - price is the difference between the prices in the base currency over
+//     the price band of the source car's price, and 1 when either price
+//     has no exchange rate or the source car is free;
+//   - engine is the mean of the powertrain, which is the same or not, and
+//     the relative differences in displacement, cylinders and range.
+//
+// Parts are capped at 1, and the weighted sum is divided by the total
+// weight.
+const rankQuery = `
+	WITH priced AS (
+		SELECT
+			c.id, c.brand_id, lower(c.fuel_type) AS fuel_type, c.year::int AS year,
+			CASE WHEN c.currency = $2 THEN c.price ELSE c.price / r.rate END AS price,
+			e.powertrain, e.displacement, e.no_of_cylinders, e.car_range
+		FROM
+			cars c
+		JOIN
+			engines e ON c.engine_id = e.engine_id
+		LEFT JOIN
+			exchange_rates r ON r.base = $2 AND r.currency = c.currency
+	),
+	source AS (
+		SELECT * FROM priced WHERE id = $1
+	)
+	SELECT
+		c.id,
+		(
+			$3::float8 * CASE WHEN c.brand_id = s.brand_id THEN 0 ELSE 1 END
+			+ $4::float8 * CASE WHEN c.fuel_type = s.fuel_type THEN 0 ELSE 1 END
+			+ $5::float8 * LEAST(ABS(c.year - s.year)::float8 / $6::int, 1)
+			+ $7::float8 * COALESCE(LEAST(ABS(c.price - s.price) / NULLIF(s.price * $8::numeric, 0), 1)::float8, 1)
+			+ $9::float8 * (
+				CASE WHEN c.powertrain = s.powertrain THEN 0 ELSE 1 END
+				+ LEAST(ABS(c.displacement - s.displacement)::float8 / GREATEST(s.displacement, 1), 1)
+				+ LEAST(ABS(c.no_of_cylinders - s.no_of_cylinders)::float8 / GREATEST(s.no_of_cylinders, 1), 1)
+				+ LEAST(ABS(c.car_range - s.car_range)::float8 / GREATEST(s.car_range, 1), 1)
+			) / 4
+		) / $10::float8 AS distance
+	FROM
+		priced c, source s
+	WHERE
+		c.id <> s.id
+	ORDER BY
+		distance, c.id
+	LIMIT $11`
+
+func (s *Store) RankSimilar(ctx context.Context, carID uuid.UUID, similarity models.Similarity, limit int) ([]models.RankedCar, error) {
+	tracer := otel.Tracer("SimilarStore")
+
+	ctx, span := tracer.Start(ctx, "RankSimilar-Store")
+
+	defer span.End()
+
+	weights := similarity.Weights
+
+	rows, err := s.db.Reader(ctx).QueryContext(ctx, rankQuery,
+		carID, similarity.Currency,
+		weights.Brand, weights.FuelType,
+		weights.Year, similarity.YearSpan,
+		weights.Price, similarity.PriceBand,
+		weights.Engine, weights.Total(),
+		limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ranked := []models.RankedCar{}
+	for rows.Next() {
+		var car models.RankedCar
+		if err := rows.Scan(&car.CarID, &car.Distance); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		ranked = append(ranked, car)
+	}
+
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return ranked, nil
+}
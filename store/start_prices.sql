@@ -0,0 +1,7 @@
+-- Cars listed before prices were recorded, or loaded around the API, have
+-- no price history. It starts with their current price, as of their
+-- listing.
+INSERT INTO car_prices (car_id, price, currency, changed_at)
+SELECT c.id, c.price, c.currency, COALESCE(c.created_at, CURRENT_TIMESTAMP)
+FROM cars c
+WHERE NOT EXISTS (SELECT 1 FROM car_prices p WHERE p.car_id = c.id);
@@ -0,0 +1,325 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/michgboxy2/carzone/driver"
+	"github.com/michgboxy2/carzone/models"
+	"go.opentelemetry.io/otel"
+)
+
+const deliveryColumns = `
+	id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+	last_status_code, last_error, delivered_at, created_at, updated_at`
+
+type Store struct {
+	db *driver.DB
+}
+
+func New(db *driver.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateSubscription(ctx context.Context, sub models.WebhookSubscription) (models.WebhookSubscription, error) {
+	tracer := otel.Tracer("WebhookStore")
+
+	ctx, span := tracer.Start(ctx, "CreateSubscription-Store")
+
+	defer span.End()
+
+	query := `
+		INSERT INTO webhook_subscriptions (id, url, event_types, secret, active)
+		VALUES ($1, $2, $3, $4, TRUE)
+		RETURNING active, created_at, updated_at`
+
+	sub.ID = uuid.New()
+
+	err := s.db.Conn(ctx).QueryRowContext(ctx, query, sub.ID, sub.URL, pq.Array(sub.EventTypes), sub.Secret).
+		Scan(&sub.Active, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		span.RecordError(err)
+		return models.WebhookSubscription{}, err
+	}
+
+	return sub, nil
+}
+
+func (s *Store) GetSubscription(ctx context.Context, id uuid.UUID) (models.WebhookSubscription, error) {
+	tracer := otel.Tracer("WebhookStore")
+
+	ctx, span := tracer.Start(ctx, "GetSubscription-Store")
+
+	defer span.End()
+
+	var sub models.WebhookSubscription
+
+	err := s.db.Reader(ctx).QueryRowContext(ctx, `
+		SELECT id, url, event_types, active, created_at, updated_at
+		FROM webhook_subscriptions WHERE id = $1`, id).
+		Scan(&sub.ID, &sub.URL, pq.Array(&sub.EventTypes), &sub.Active, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return sub, models.ErrSubscriptionNotFound
+		}
+		return sub, err
+	}
+
+	return sub, nil
+}
+
+func (s *Store) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	tracer := otel.Tracer("WebhookStore")
+
+	ctx, span := tracer.Start(ctx, "ListSubscriptions-Store")
+
+	defer span.End()
+
+	rows, err := s.db.Reader(ctx).QueryContext(ctx, `
+		SELECT id, url, event_types, active, created_at, updated_at
+		FROM webhook_subscriptions ORDER BY created_at, id`)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []models.WebhookSubscription{}
+	for rows.Next() {
+		var sub models.WebhookSubscription
+		if err := rows.Scan(&sub.ID, &sub.URL, pq.Array(&sub.EventTypes), &sub.Active, &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+// DeleteSubscription removes the subscription and, through the foreign key,
+// its delivery log.
+func (s *Store) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	tracer := otel.Tracer("WebhookStore")
+
+	ctx, span := tracer.Start(ctx, "DeleteSubscription-Store")
+
+	defer span.End()
+
+	result, err := s.db.Conn(ctx).ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrSubscriptionNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) EnqueueDeliveries(ctx context.Context, event models.Event) (int64, error) {
+	tracer := otel.Tracer("WebhookStore")
+
+	ctx, span := tracer.Start(ctx, "EnqueueDeliveries-Store")
+
+	defer span.End()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload, status, next_attempt_at)
+		SELECT uuid_generate_v4(), s.id, $1, $2, $3, 'pending', CURRENT_TIMESTAMP
+		FROM webhook_subscriptions s
+		WHERE s.active AND $2 = ANY (s.event_types)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING`
+
+	result, err := s.db.Conn(ctx).ExecContext(ctx, query, event.ID, event.Type, payload)
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (s *Store) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.DueDelivery, error) {
+	tracer := otel.Tracer("WebhookStore")
+
+	ctx, span := tracer.Start(ctx, "ClaimDue-Store")
+
+	defer span.End()
+
+	query := `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_deliveries d
+			SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+			FROM due
+			WHERE d.id = due.id
+			RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.attempts
+		)
+		SELECT c.id, c.subscription_id, c.event_id, c.event_type, c.payload, c.attempts, s.url, s.secret
+		FROM claimed c
+		JOIN webhook_subscriptions s ON s.id = c.subscription_id`
+
+	rows, err := s.db.Conn(ctx).QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []models.DueDelivery
+	for rows.Next() {
+		var d models.DueDelivery
+		var payload []byte
+
+		if err := rows.Scan(&d.Delivery.ID, &d.Delivery.SubscriptionID, &d.Delivery.EventID, &d.Delivery.EventType,
+			&payload, &d.Delivery.Attempts, &d.URL, &d.Secret); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		d.Delivery.Payload = payload
+		due = append(due, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return due, nil
+}
+
+func (s *Store) MarkDelivered(ctx context.Context, id uuid.UUID, statusCode int) error {
+	_, err := s.db.Conn(ctx).ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, last_status_code = $1, last_error = NULL,
+			next_attempt_at = NULL, delivered_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`, statusCode, id)
+	return err
+}
+
+func (s *Store) MarkFailed(ctx context.Context, id uuid.UUID, statusCode *int, reason string, next *time.Time) error {
+	status := models.DeliveryPending
+	if next == nil {
+		status = models.DeliveryDead
+	}
+
+	_, err := s.db.Conn(ctx).ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = attempts + 1, last_status_code = $2, last_error = $3,
+			next_attempt_at = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5`, status, statusCode, reason, next, id)
+	return err
+}
+
+// ListDeliveries returns the newest deliveries of a subscription first,
+// optionally only those with the given status.
+func (s *Store) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error) {
+	tracer := otel.Tracer("WebhookStore")
+
+	ctx, span := tracer.Start(ctx, "ListDeliveries-Store")
+
+	defer span.End()
+
+	query := `SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3`
+
+	rows, err := s.db.Reader(ctx).QueryContext(ctx, query, subscriptionID, status, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver puts a delivery, typically a dead one, back in the queue for an
+// immediate attempt. Its attempt count starts over.
+func (s *Store) Redeliver(ctx context.Context, subscriptionID, deliveryID uuid.UUID) (models.WebhookDelivery, error) {
+	tracer := otel.Tracer("WebhookStore")
+
+	ctx, span := tracer.Start(ctx, "Redeliver-Store")
+
+	defer span.End()
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND subscription_id = $2
+		RETURNING ` + deliveryColumns
+
+	delivery, err := scanDelivery(s.db.Conn(ctx).QueryRowContext(ctx, query, deliveryID, subscriptionID))
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return delivery, models.ErrDeliveryNotFound
+		}
+		return delivery, err
+	}
+
+	return delivery, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDelivery(row scanner) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload []byte
+
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt, &d.UpdatedAt)
+	d.Payload = payload
+
+	return d, err
+}
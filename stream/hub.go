@@ -0,0 +1,205 @@
+// Package stream fans change events out to live SSE and WebSocket clients.
+//
+// Every instance listens for new outbox events with Postgres LISTEN/NOTIFY
+// and broadcasts them to its own clients, so a client sees every change
+// whichever instance made it. Clients that fall behind are disconnected and
+// resume from the outbox with Last-Event-ID.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+)
+
+var ErrClosed = errors.New("stream is shutting down")
+
+// Filter selects the events a client receives. Brand and FuelType match car
+// events case-insensitively; when either is set, engine events are left
+// out since they belong to no brand. Types, if non-empty, lists the event
+// types wanted.
+type Filter struct {
+	Brand    string
+	FuelType string
+	Types    []string
+}
+
+// carFields are the attributes of a car event's payload filters look at.
+// Every car event payload carries them.
+type carFields struct {
+	Brand    string `json:"brand"`
+	FuelType string `json:"fuel_type"`
+}
+
+func (f Filter) match(event models.Event, car *carFields) bool {
+	if len(f.Types) > 0 {
+		wanted := false
+		for _, t := range f.Types {
+			if t == event.Type {
+				wanted = true
+				break
+			}
+		}
+		if !wanted {
+			return false
+		}
+	}
+
+	if f.Brand == "" && f.FuelType == "" {
+		return true
+	}
+
+	if car == nil {
+		return false
+	}
+
+	return (f.Brand == "" || strings.EqualFold(f.Brand, car.Brand)) &&
+		(f.FuelType == "" || strings.EqualFold(f.FuelType, car.FuelType))
+}
+
+func fieldsOf(event models.Event) *carFields {
+	if event.AggregateType != models.AggregateCar {
+		return nil
+	}
+
+	var car carFields
+	if err := json.Unmarshal(event.Payload, &car); err != nil {
+		return nil
+	}
+	return &car
+}
+
+// Subscription receives the events matching its filter on C. C is closed
+// when the client falls too far behind or the hub shuts down.
+type Subscription struct {
+	C      <-chan models.Event
+	ch     chan models.Event
+	filter Filter
+}
+
+type Hub struct {
+	buffer int
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewHub returns a hub whose subscribers may fall up to buffer events
+// behind before they are disconnected.
+func NewHub(buffer int) *Hub {
+	return &Hub{buffer: buffer, subs: make(map[*Subscription]struct{})}
+}
+
+func (h *Hub) Subscribe(filter Filter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	ch := make(chan models.Event, h.buffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter}
+	h.subs[sub] = struct{}{}
+
+	return sub, nil
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(sub)
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Broadcast hands event to every matching subscriber without blocking.
+func (h *Hub) Broadcast(event models.Event) {
+	car := fieldsOf(event)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		if !sub.filter.match(event, car) {
+			continue
+		}
+
+		select {
+		case sub.ch <- event:
+		default:
+			// Too far behind; the client reconnects and replays.
+			h.remove(sub)
+		}
+	}
+}
+
+// Close ends every subscription and refuses new ones. It is called when the
+// server shuts down so streaming handlers return.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		h.remove(sub)
+	}
+}
+
+// Broker subscribes clients to the hub and replays what they missed from the
+// outbox.
+type Broker struct {
+	hub         *Hub
+	outbox      store.OutboxStoreInterface
+	replayLimit int
+}
+
+func NewBroker(hub *Hub, outbox store.OutboxStoreInterface, replayLimit int) *Broker {
+	return &Broker{hub: hub, outbox: outbox, replayLimit: replayLimit}
+}
+
+// Subscribe starts a subscription and, when lastEventID is set, returns the
+// matching events after it, oldest first, capped at the replay limit. The
+// subscription starts before the replay is read, so nothing falls in
+// between; the caller should skip live events it has already replayed.
+func (b *Broker) Subscribe(ctx context.Context, filter Filter, lastEventID int64) (*Subscription, []models.Event, error) {
+	sub, err := b.hub.Subscribe(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if lastEventID <= 0 {
+		return sub, nil, nil
+	}
+
+	events, err := b.outbox.Since(ctx, lastEventID, b.replayLimit)
+	if err != nil {
+		b.hub.Unsubscribe(sub)
+		return nil, nil, err
+	}
+
+	replay := events[:0]
+	for _, event := range events {
+		if filter.match(event, fieldsOf(event)) {
+			replay = append(replay, event)
+		}
+	}
+
+	return sub, replay, nil
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.hub.Unsubscribe(sub)
+}
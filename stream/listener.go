@@ -0,0 +1,112 @@
+package stream
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/michgboxy2/carzone/models"
+	"github.com/michgboxy2/carzone/store"
+	"github.com/michgboxy2/carzone/store/outbox"
+)
+
+// catchUpLimit bounds how many events are read at once after the listener
+// reconnects.
+const catchUpLimit = 500
+
+// Listener feeds the hub from Postgres notifications. It holds its own
+// connection, outside the pool, since LISTEN is tied to a session.
+type Listener struct {
+	dsn    string
+	outbox store.OutboxStoreInterface
+	hub    *Hub
+	last   int64
+}
+
+func NewListener(dsn string, outbox store.OutboxStoreInterface, hub *Hub) *Listener {
+	return &Listener{dsn: dsn, outbox: outbox, hub: hub}
+}
+
+func (l *Listener) Run(ctx context.Context) {
+	listener := pq.NewListener(l.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("event listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(outbox.Channel); err != nil {
+		log.Printf("event listener: listening on %s: %v", outbox.Channel, err)
+	}
+
+	// Start from the current end of the outbox; older events are only sent
+	// to clients that ask for them with Last-Event-ID.
+	last, err := l.outbox.LastSequence(ctx)
+	if err != nil {
+		log.Printf("event listener: %v", err)
+	}
+	l.last = last
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			if n == nil {
+				// The connection was re-established and notifications sent
+				// meanwhile are lost; read them from the outbox instead.
+				l.catchUp(ctx)
+				continue
+			}
+			l.deliver(ctx, n.Extra)
+		case <-ping.C:
+			go listener.Ping()
+		}
+	}
+}
+
+func (l *Listener) deliver(ctx context.Context, payload string) {
+	sequence, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		log.Printf("event listener: bad notification %q", payload)
+		return
+	}
+
+	events, err := l.outbox.BySequence(ctx, []int64{sequence})
+	if err != nil {
+		log.Printf("event listener: loading event %d: %v", sequence, err)
+		return
+	}
+
+	l.broadcast(events)
+}
+
+func (l *Listener) catchUp(ctx context.Context) {
+	for {
+		events, err := l.outbox.Since(ctx, l.last, catchUpLimit)
+		if err != nil {
+			log.Printf("event listener: catching up: %v", err)
+			return
+		}
+
+		l.broadcast(events)
+
+		if len(events) < catchUpLimit {
+			return
+		}
+	}
+}
+
+func (l *Listener) broadcast(events []models.Event) {
+	for _, event := range events {
+		l.hub.Broadcast(event)
+		if event.Sequence > l.last {
+			l.last = event.Sequence
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package tracing sets up the OpenTelemetry tracer provider.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/michgboxy2/carzone/config"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Exporter wraps the OTLP exporter and remembers the outcome of the last
+// export so health checks can report on it.
+type Exporter struct {
+	trace.SpanExporter
+
+	mu          sync.Mutex
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastErr     error
+}
+
+// Status is a snapshot of the exporter's recent behaviour.
+type Status struct {
+	Enabled     bool
+	LastSuccess time.Time
+	LastFailure time.Time
+	LastError   error
+}
+
+func (e *Exporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err != nil {
+		e.lastFailure = time.Now()
+		e.lastErr = err
+	} else {
+		e.lastSuccess = time.Now()
+	}
+
+	return err
+}
+
+// Status reports the exporter state. A nil Exporter means tracing is
+// disabled.
+func (e *Exporter) Status() Status {
+	if e == nil {
+		return Status{}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return Status{
+		Enabled:     true,
+		LastSuccess: e.lastSuccess,
+		LastFailure: e.lastFailure,
+		LastError:   e.lastErr,
+	}
+}
+
+// Start builds the tracer provider described by cfg. When tracing is
+// disabled the provider records nothing and the returned Exporter is nil.
+func Start(cfg config.Tracing) (*trace.TracerProvider, *Exporter, error) {
+	res := resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String(cfg.ServiceName),
+	)
+
+	if !cfg.Enabled {
+		return trace.NewTracerProvider(trace.WithResource(res)), nil, nil
+	}
+
+	header := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	otlpExporter, err := otlptrace.New(
+		context.Background(),
+		otlptracehttp.NewClient(
+			otlptracehttp.WithEndpoint(cfg.Endpoint()),
+			otlptracehttp.WithHeaders(header),
+			otlptracehttp.WithInsecure(),
+		),
+	)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating new Exporter: %w", err)
+	}
+
+	exporter := &Exporter{SpanExporter: otlpExporter}
+
+	tracerProvider := trace.NewTracerProvider(
+		trace.WithBatcher(
+			exporter,
+			trace.WithMaxExportBatchSize(trace.DefaultMaxExportBatchSize),
+			trace.WithBatchTimeout(trace.DefaultScheduleDelay*time.Millisecond),
+		),
+		trace.WithResource(res),
+	)
+
+	return tracerProvider, exporter, nil
+}
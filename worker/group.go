@@ -0,0 +1,57 @@
+// Package worker runs the service's background goroutines and stops them
+// together on shutdown.
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewGroup() *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Group{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Go starts fn in its own goroutine. fn must return once its context is
+// cancelled.
+func (g *Group) Go(name string, fn func(ctx context.Context)) {
+	g.wg.Add(1)
+
+	go func() {
+		defer g.wg.Done()
+
+		log.Printf("worker %s started", name)
+		fn(g.ctx)
+		log.Printf("worker %s stopped", name)
+	}()
+}
+
+// Shutdown cancels every worker and waits for them to return, or for ctx to
+// expire.
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}